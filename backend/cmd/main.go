@@ -1,14 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"real-time-forum/backend/internal/api"
 	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/digest"
+	"real-time-forum/backend/internal/mail"
+	"real-time-forum/backend/internal/uploads"
 	"real-time-forum/backend/internal/websocket"
 )
 
+const (
+	// Time allowed for in-flight requests and socket draining on shutdown
+	shutdownTimeout = 15 * time.Second
+
+	// Seconds clients are asked to wait before reconnecting after a shutdown
+	reconnectAfter = 5
+
+	// How often orphaned uploads are removed, and how long an upload may
+	// wait to be attached before it counts as orphaned
+	uploadCleanupInterval = time.Hour
+	uploadMaxAge          = 24 * time.Hour
+
+	// How often users due an email digest are looked for, unless
+	// DIGEST_INTERVAL says otherwise
+	defaultDigestInterval = time.Hour
+)
+
 func main() {
 	// Initialize database
 	if err := database.InitDB(); err != nil {
@@ -18,46 +45,268 @@ func main() {
 
 	// Create WebSocket hub
 	hub := websocket.NewHub()
+	if value := os.Getenv("MESSAGE_EDIT_WINDOW"); value != "" {
+		window, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid MESSAGE_EDIT_WINDOW:", err)
+		}
+		hub.SetMessageEditWindow(window)
+	}
+	hub.SetRateLimits(rateLimits())
 	go hub.Run()
 
+	// Set up file uploads
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "uploads"
+	}
+	store, err := uploads.NewLocalStorage(uploadDir)
+	if err != nil {
+		log.Fatal("Failed to create upload directory:", err)
+	}
+	uploader := uploads.NewService(store)
+	if value := os.Getenv("UPLOAD_MAX_SIZE"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			log.Fatal("Invalid UPLOAD_MAX_SIZE:", value)
+		}
+		uploader.Limits.MaxFileSize = size
+	}
+
 	// Create handlers
-	handlers := api.NewHandlers(hub)
+	handlers := api.NewHandlers(hub, uploader)
 
 	// Setup routes
-	setupRoutes(handlers)
+	mux := http.NewServeMux()
+	setupRoutes(mux, handlers)
 
 	port := ":8080"
-	log.Printf("Server started on port %s", port)
-	log.Printf("Open http://localhost%s in your browser to access the server", port)
-	log.Fatal(http.ListenAndServe(port, nil))
-													
+	server := &http.Server{
+		Addr:    port,
+		Handler: mux,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go uploader.RunCleanup(ctx, uploadCleanupInterval, uploadMaxAge)
+	startDigests(ctx)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server started on port %s", port)
+		log.Printf("Open http://localhost%s in your browser to access the server", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			log.Printf("Server error: %v", err)
+		}
+	case <-ctx.Done():
+		log.Println("Shutdown signal received")
+	}
+
+	shutdown(server, hub)
+}
+
+// rateLimits reads the WebSocket rate limits from the environment, keeping
+// the defaults for anything not set: RATE_LIMIT_MESSAGES, RATE_LIMIT_TYPING
+// and RATE_LIMIT_CONNECTION as "<per second>:<burst>", RATE_LIMIT_MAX_VIOLATIONS
+// and RATE_LIMIT_VIOLATION_WINDOW for disconnecting abusers, and
+// TYPING_INTERVAL for coalescing typing indicators
+func rateLimits() websocket.RateLimitConfig {
+	limits := websocket.DefaultRateLimitConfig()
+	rates := []struct {
+		name   string
+		events []websocket.EventType
+	}{
+		{"RATE_LIMIT_MESSAGES", []websocket.EventType{websocket.EventTypePrivateMessage}},
+		{"RATE_LIMIT_TYPING", []websocket.EventType{websocket.EventTypeTyping, websocket.EventTypeStopTyping,
+			websocket.EventTypeRoomTyping, websocket.EventTypeRoomStopTyping}},
+		{"RATE_LIMIT_CONNECTION", nil},
+	}
+	for _, r := range rates {
+		value := os.Getenv(r.name)
+		if value == "" {
+			continue
+		}
+		rate, err := websocket.ParseRate(value)
+		if err != nil {
+			log.Fatalf("Invalid %s: %v", r.name, err)
+		}
+		if r.events == nil {
+			limits.Connection = rate
+		}
+		for _, event := range r.events {
+			limits.Events[event] = rate
+		}
+	}
+
+	if value := os.Getenv("RATE_LIMIT_MAX_VIOLATIONS"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			log.Fatal("Invalid RATE_LIMIT_MAX_VIOLATIONS:", value)
+		}
+		limits.MaxViolations = count
+	}
+	durations := map[string]*time.Duration{
+		"RATE_LIMIT_VIOLATION_WINDOW": &limits.ViolationWindow,
+		"TYPING_INTERVAL":             &limits.TypingInterval,
+	}
+	for name, duration := range durations {
+		if value := os.Getenv(name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				log.Fatalf("Invalid %s: %s", name, value)
+			}
+			*duration = d
+		}
+	}
+	return limits
 }
 
-func setupRoutes(handlers *api.Handlers) {
+// startDigests starts the email digest job when a mail transport is
+// configured: SMTP_ADDR (with optional SMTP_USERNAME and SMTP_PASSWORD) or,
+// for development, MAIL_DROP_DIR to write emails to files instead
+func startDigests(ctx context.Context) {
+	var transport mail.Transport
+	if dir := os.Getenv("MAIL_DROP_DIR"); dir != "" {
+		fileTransport, err := mail.NewFileTransport(dir)
+		if err != nil {
+			log.Fatal("Failed to create mail drop directory:", err)
+		}
+		transport = fileTransport
+	} else if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		transport = &mail.SMTPTransport{
+			Addr:     addr,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	} else {
+		log.Println("Email digests disabled: set SMTP_ADDR or MAIL_DROP_DIR to enable them")
+		return
+	}
+
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Real Time Forum <forum@localhost>"
+	}
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	interval := defaultDigestInterval
+	if value := os.Getenv("DIGEST_INTERVAL"); value != "" {
+		var err error
+		interval, err = time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			log.Fatal("Invalid DIGEST_INTERVAL:", value)
+		}
+	}
+
+	go digest.NewService(transport, from, baseURL).Run(ctx, interval)
+}
+
+// shutdown stops accepting connections, drains WebSocket clients, waits for
+// in-flight requests and marks everyone offline before the database closes
+func shutdown(server *http.Server, hub *websocket.Hub) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	hubDone := make(chan struct{})
+	go func() {
+		if err := hub.Shutdown(ctx, reconnectAfter); err != nil {
+			log.Printf("Error draining WebSocket clients: %v", err)
+		}
+		close(hubDone)
+	}()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Error waiting for in-flight requests: %v", err)
+		server.Close()
+	}
+	<-hubDone
+
+	if err := database.SetAllUsersOffline(); err != nil {
+		log.Printf("Error marking users offline: %v", err)
+	}
+
+	log.Println("Server stopped")
+}
+
+func setupRoutes(mux *http.ServeMux, handlers *api.Handlers) {
 	// Static files
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("frontend/static/"))))
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("frontend/static/"))))
 
 	// Main page
-	http.HandleFunc("/", handlers.ServeHome)
+	mux.HandleFunc("/", handlers.ServeHome)
 
 	// Favicon handler to prevent 404 errors
-	http.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "image/x-icon")
 		w.WriteHeader(http.StatusNoContent) // 204 No Content
 	})
 
 	// API routes
-	http.HandleFunc("/api/register", handlers.HandleRegister)
-	http.HandleFunc("/api/login", handlers.HandleLogin)
-	http.HandleFunc("/api/logout", handlers.HandleLogout)
-	http.HandleFunc("/api/posts", handlers.HandlePosts)
-	http.HandleFunc("/api/comments", handlers.HandleComments)
-	http.HandleFunc("/api/messages", handlers.HandleMessages)
-	http.HandleFunc("/api/users", handlers.HandleUsers)
-	http.HandleFunc("/api/users/me", handlers.HandleUsersMe)
-	http.HandleFunc("/api/profile", handlers.HandleProfile)
-	http.HandleFunc("/api/categories", handlers.HandleCategories)
+	mux.HandleFunc("/api/register", handlers.HandleRegister)
+	mux.HandleFunc("/api/login", handlers.HandleLogin)
+	mux.HandleFunc("/api/logout", handlers.HandleLogout)
+	mux.HandleFunc("/api/posts", handlers.HandlePosts)
+	mux.HandleFunc("/api/posts/state", handlers.HandlePostState)
+	mux.HandleFunc("/api/reports", handlers.HandleReports)
+	mux.HandleFunc("/api/bans", handlers.HandleBans)
+	mux.HandleFunc("/api/comments", handlers.HandleComments)
+	mux.HandleFunc("/api/reactions", handlers.HandleReactions)
+	mux.HandleFunc("/api/votes", handlers.HandleVotes)
+	mux.HandleFunc("/api/attachments", handlers.HandleAttachments)
+	mux.HandleFunc("/api/attachments/download", handlers.HandleAttachmentDownload)
+	mux.HandleFunc("/api/messages", handlers.HandleMessages)
+	mux.HandleFunc("/api/messages/revisions", handlers.HandleMessageRevisions)
+	mux.HandleFunc("/api/conversations", handlers.HandleConversations)
+	mux.HandleFunc("/api/conversations/members", handlers.HandleConversationMembers)
+	mux.HandleFunc("/api/conversations/messages", handlers.HandleConversationMessages)
+	mux.HandleFunc("/api/conversations/read", handlers.HandleConversationRead)
+	mux.HandleFunc("/api/conversations/requests", handlers.HandleMessageRequests)
+	mux.HandleFunc("/api/users", handlers.HandleUsers)
+	mux.HandleFunc("/api/users/me", handlers.HandleUsersMe)
+	mux.HandleFunc("/api/users/search", handlers.HandleUserSearch)
+	mux.HandleFunc("/api/mentions", handlers.HandleMentions)
+	mux.HandleFunc("/api/notifications", handlers.HandleNotifications)
+	mux.HandleFunc("/api/notifications/read", handlers.HandleMarkNotificationsRead)
+	mux.HandleFunc("/api/notifications/read-all", handlers.HandleMarkAllNotificationsRead)
+	mux.HandleFunc("/api/notifications/preferences", handlers.HandleNotificationPreferences)
+	mux.HandleFunc("/api/digest/settings", handlers.HandleDigestSettings)
+	mux.HandleFunc("/api/digest/unsubscribe", handlers.HandleDigestUnsubscribe)
+	mux.HandleFunc("/api/watches", handlers.HandleWatches)
+	mux.HandleFunc("/api/watches/settings", handlers.HandleWatchSettings)
+	mux.HandleFunc("/api/bookmarks", handlers.HandleBookmarks)
+	mux.HandleFunc("/api/bookmarks/folders", handlers.HandleBookmarkFolders)
+	mux.HandleFunc("/api/tags", handlers.HandleTags)
+	mux.HandleFunc("/api/tags/synonyms", handlers.HandleTagSynonyms)
+	mux.HandleFunc("/api/profile", handlers.HandleProfile)
+	mux.HandleFunc("/api/profile/avatar", handlers.HandleProfileAvatar)
+	mux.HandleFunc("/api/avatars", handlers.HandleAvatars)
+	mux.HandleFunc("/api/blocks", handlers.HandleBlocks)
+	mux.HandleFunc("/api/mutes", handlers.HandleMutes)
+	mux.HandleFunc("/api/follows", handlers.HandleFollows)
+	mux.HandleFunc("/api/followers", handlers.HandleFollowers)
+	mux.HandleFunc("/api/categories", handlers.HandleCategories)
+	mux.HandleFunc("/api/categories/order", handlers.HandleCategoryOrder)
+	mux.HandleFunc("/api/categories/permissions", handlers.HandleCategoryPermissions)
+	mux.HandleFunc("/api/groups", handlers.HandleUserGroups)
+	mux.HandleFunc("/api/groups/members", handlers.HandleUserGroupMembers)
 
 	// WebSocket endpoint
-	http.HandleFunc("/ws", handlers.HandleWebSocket)
+	mux.HandleFunc("/ws", handlers.HandleWebSocket)
+	mux.HandleFunc("/api/ws/metrics", handlers.HandleWebSocketMetrics)
+
+	// Fallback transports for clients that cannot open a WebSocket
+	mux.HandleFunc("/api/events", handlers.HandleEvents)
+	mux.HandleFunc("/api/poll", handlers.HandlePoll)
+	mux.HandleFunc("/api/realtime/actions", handlers.HandleRealtimeActions)
 }
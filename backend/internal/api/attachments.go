@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/uploads"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+	"strings"
+)
+
+// writeAttachmentError maps upload and attachment errors to HTTP responses
+func writeAttachmentError(w http.ResponseWriter, err error, fallback string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == uploads.ErrFileTooLarge || errors.As(err, &tooLarge):
+		http.Error(w, uploads.ErrFileTooLarge.Error(), http.StatusRequestEntityTooLarge)
+	case err == uploads.ErrUnsupportedType:
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case err == uploads.ErrEmptyFile, err == uploads.ErrInvalidImage, err == uploads.ErrImageTooLarge,
+		err == uploads.ErrInvalidCrop, err == database.ErrAttachmentUnavailable:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err == database.ErrAttachmentNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// HandleAttachments uploads a file (POST, multipart field "file") and removes
+// an upload that was never attached (DELETE ?id=)
+func (h *Handlers) HandleAttachments(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		// Leave room for the multipart framing around the file
+		r.Body = http.MaxBytesReader(w, r.Body, h.Uploads.Limits.MaxFileSize+1<<20)
+		part, filename, err := filePart(r)
+		if err != nil {
+			writeAttachmentError(w, err, "Invalid upload")
+			return
+		}
+		defer part.Close()
+
+		file, err := h.Uploads.Save(part)
+		if err != nil {
+			writeAttachmentError(w, err, "Error saving upload")
+			return
+		}
+
+		attachment := models.Attachment{
+			UploaderID:   userID,
+			Filename:     uploads.CleanFilename(filename),
+			ContentType:  file.ContentType,
+			Size:         file.Size,
+			Width:        file.Width,
+			Height:       file.Height,
+			StorageKey:   file.StorageKey,
+			ThumbnailKey: file.ThumbnailKey,
+		}
+		if err := database.CreateAttachment(&attachment); err != nil {
+			h.Uploads.Remove(file.StorageKey, file.ThumbnailKey)
+			http.Error(w, "Error saving upload", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(attachment)
+
+	case "DELETE":
+		attachmentID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+			return
+		}
+
+		attachment, err := database.DeleteUnusedAttachment(attachmentID, userID)
+		if err != nil {
+			writeAttachmentError(w, err, "Error deleting upload")
+			return
+		}
+		h.Uploads.Remove(attachment.StorageKey, attachment.ThumbnailKey)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// filePart finds the "file" field of a multipart upload
+func filePart(r *http.Request) (io.ReadCloser, string, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", uploads.ErrEmptyFile
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, "", uploads.ErrEmptyFile
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == "file" {
+			return part, part.FileName(), nil
+		}
+		part.Close()
+	}
+}
+
+// HandleAttachmentDownload serves an attachment, or its thumbnail with
+// thumb=1, to users allowed to see it
+func (h *Handlers) HandleAttachmentDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := database.GetAttachment(attachmentID)
+	if err != nil {
+		writeAttachmentError(w, err, "Error retrieving attachment")
+		return
+	}
+
+	allowed, err := database.CanViewAttachment(attachment, userID)
+	if err != nil {
+		http.Error(w, "Error retrieving attachment", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		// Answer as if the file did not exist
+		writeAttachmentError(w, database.ErrAttachmentNotFound, "")
+		return
+	}
+
+	key, contentType := attachment.StorageKey, attachment.ContentType
+	if r.URL.Query().Get("thumb") == "1" && attachment.ThumbnailKey != "" {
+		key, contentType = attachment.ThumbnailKey, "image/png"
+		if strings.HasSuffix(key, ".jpg") {
+			contentType = "image/jpeg"
+		}
+	}
+
+	file, err := h.Uploads.Store.Open(key)
+	if err != nil {
+		http.Error(w, "Error retrieving attachment", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", uploads.ContentDisposition(contentType, attachment.Filename))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, "", attachment.CreatedAt, file)
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"encoding/json"
+	"image"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/uploads"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+	"time"
+)
+
+// defaultAvatarSize is served when no size is requested
+const defaultAvatarSize = 64
+
+// HandleProfileAvatar sets the current user's avatar (POST, multipart field
+// "file", optional crop square ?x=&y=&size= in image pixels) or removes it
+// to fall back to a generated one (DELETE)
+func (h *Handlers) HandleProfileAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var avatarKey string
+	switch r.Method {
+	case "POST":
+		crop, err := parseCrop(r)
+		if err != nil {
+			http.Error(w, "Invalid crop", http.StatusBadRequest)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, h.Uploads.Limits.MaxFileSize+1<<20)
+		part, _, err := filePart(r)
+		if err != nil {
+			writeAttachmentError(w, err, "Invalid upload")
+			return
+		}
+		defer part.Close()
+
+		avatarKey, err = h.Uploads.SaveAvatar(part, crop)
+		if err != nil {
+			writeAttachmentError(w, err, "Error saving avatar")
+			return
+		}
+
+	case "DELETE":
+		// An empty key falls back to the generated avatar
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	previous, err := database.SetUserAvatar(userID, avatarKey)
+	if err != nil {
+		if avatarKey != "" {
+			h.Uploads.RemoveAvatar(avatarKey)
+		}
+		http.Error(w, "Error updating avatar", http.StatusInternalServerError)
+		return
+	}
+	if previous != "" {
+		h.Uploads.RemoveAvatar(previous)
+	}
+
+	user, err := database.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
+		return
+	}
+	h.Hub.NotifyProfileUpdated(userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// parseCrop reads the optional crop square of an avatar upload
+func parseCrop(r *http.Request) (image.Rectangle, error) {
+	query := r.URL.Query()
+	if query.Get("size") == "" {
+		return image.Rectangle{}, nil
+	}
+
+	var values [3]int
+	for i, name := range []string{"x", "y", "size"} {
+		value, err := strconv.Atoi(query.Get(name))
+		if err != nil || value < 0 {
+			return image.Rectangle{}, uploads.ErrInvalidCrop
+		}
+		values[i] = value
+	}
+	x, y, size := values[0], values[1], values[2]
+	if size == 0 {
+		return image.Rectangle{}, uploads.ErrInvalidCrop
+	}
+	return image.Rect(x, y, x+size, y+size), nil
+}
+
+// HandleAvatars serves a user's avatar (GET ?user_id=&size=) at the closest
+// stored size. Users without an uploaded avatar get their initials, or an
+// identicon with style=identicon.
+func (h *Handlers) HandleAvatars(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := utils.GetUserIDFromSession(r); err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	userID, err := strconv.Atoi(query.Get("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	size := defaultAvatarSize
+	if value := query.Get("size"); value != "" {
+		if size, err = strconv.Atoi(value); err != nil || size <= 0 {
+			http.Error(w, "Invalid size", http.StatusBadRequest)
+			return
+		}
+	}
+	size = uploads.AvatarSize(size)
+
+	user, err := database.GetUserByID(userID)
+	if err != nil {
+		if err == database.ErrUserNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error retrieving avatar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+
+	if user.AvatarKey != "" {
+		file, err := h.Uploads.Store.Open(uploads.AvatarKey(user.AvatarKey, size))
+		if err == nil {
+			defer file.Close()
+			// Versioned URLs change with every upload, so they never go stale
+			if query.Get("v") == user.AvatarKey {
+				w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+			} else {
+				w.Header().Set("Cache-Control", "private, no-cache")
+			}
+			w.Header().Set("Content-Type", "image/png")
+			http.ServeContent(w, r, "", time.Time{}, file)
+			return
+		}
+	}
+
+	// Generated avatars follow nickname and color changes
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if query.Get("style") == "identicon" {
+		data, err := uploads.Identicon(strconv.Itoa(user.ID), size)
+		if err != nil {
+			http.Error(w, "Error generating avatar", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+		return
+	}
+
+	background, ok := models.AvatarColors[user.AvatarColor]
+	if !ok {
+		background = models.AvatarColors[models.DefaultAvatarColor]
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write(uploads.InitialsAvatar(user.Nickname, background, size))
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"strconv"
+)
+
+// HandleBans lets moderators read the ban history (GET ?user_id=&active=
+// &offset=), ban a user (POST {userId, reason, hours, hideContent}, where
+// an hours of 0 bans permanently) and lift a user's ban (DELETE ?user_id=)
+func (h *Handlers) HandleBans(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireRole(w, r, models.RoleModerator)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	switch r.Method {
+	case "GET":
+		var opts models.BanListOptions
+		opts.Offset, _ = strconv.Atoi(query.Get("offset"))
+		opts.Offset = max(opts.Offset, 0)
+		opts.ActiveOnly, _ = strconv.ParseBool(query.Get("active"))
+		if user := query.Get("user_id"); user != "" {
+			var err error
+			if opts.UserID, err = strconv.Atoi(user); err != nil || opts.UserID <= 0 {
+				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		bans, err := database.GetBans(opts)
+		if err != nil {
+			http.Error(w, "Error retrieving bans", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bans)
+
+	case "POST":
+		var req models.BanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ban, err := h.Hub.BanUser(userID, req)
+		if err != nil {
+			writeBanError(w, err, "Error banning user")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(ban)
+
+	case "DELETE":
+		bannedID, err := strconv.Atoi(query.Get("user_id"))
+		if err != nil || bannedID <= 0 {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		ban, err := h.Hub.LiftBan(userID, bannedID)
+		if err != nil {
+			writeBanError(w, err, "Error lifting ban")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ban)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeBanError responds to a failed ban or unban
+func writeBanError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case database.ErrUserNotFound, database.ErrUserNotBanned:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case database.ErrCannotBanUser:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
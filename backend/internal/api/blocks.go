@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+)
+
+// HandleBlocks lists (GET), adds (POST) and removes (DELETE) blocked users
+func (h *Handlers) HandleBlocks(w http.ResponseWriter, r *http.Request) {
+	h.handleRestrictions(w, r, database.GetBlockedUsers, h.Hub.SetBlocked)
+}
+
+// HandleMutes lists (GET), adds (POST) and removes (DELETE) muted users
+func (h *Handlers) HandleMutes(w http.ResponseWriter, r *http.Request) {
+	h.handleRestrictions(w, r, database.GetMutedUsers, h.Hub.SetMuted)
+}
+
+// handleRestrictions serves a block or mute list
+func (h *Handlers) handleRestrictions(w http.ResponseWriter, r *http.Request,
+	list func(userID int) ([]models.RestrictedUser, error),
+	set func(userID, targetID int, restricted bool) error) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.RestrictUserRequest
+	switch r.Method {
+	case "GET":
+		users, err := list(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving users", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(users)
+		return
+
+	case "POST":
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+	case "DELETE":
+		req.UserID, _ = strconv.Atoi(r.URL.Query().Get("user_id"))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := req.Validate(userID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := set(userID, req.UserID, r.Method == "POST"); err != nil {
+		if err == database.ErrUserNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error updating user list", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+)
+
+// HandleBookmarks lists the current user's bookmarks (GET ?offset=&folder_id=
+// &target_type=), saves or updates one (POST {targetType, targetId, folderId,
+// note}) and removes one (DELETE ?target_type=&target_id=)
+func (h *Handlers) HandleBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	switch r.Method {
+	case "GET":
+		var opts models.BookmarkListOptions
+		opts.Offset, _ = strconv.Atoi(query.Get("offset"))
+		opts.Offset = max(opts.Offset, 0)
+		if folder := query.Get("folder_id"); folder != "" {
+			if opts.FolderID, err = strconv.Atoi(folder); err != nil || opts.FolderID <= 0 {
+				http.Error(w, models.ErrInvalidBookmarkFolder.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if opts.TargetType = query.Get("target_type"); opts.TargetType != "" && !models.IsValidBookmarkTarget(opts.TargetType) {
+			http.Error(w, models.ErrInvalidBookmarkTarget.Error(), http.StatusBadRequest)
+			return
+		}
+
+		bookmarks, err := database.GetBookmarks(userID, opts)
+		if err != nil {
+			http.Error(w, "Error retrieving bookmarks", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bookmarks)
+
+	case "POST":
+		var req models.BookmarkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		bookmark, err := database.SaveBookmark(userID, req)
+		if err != nil {
+			switch err {
+			case database.ErrPostNotFound, database.ErrCommentNotFound, database.ErrBookmarkFolderNotFound:
+				http.Error(w, err.Error(), http.StatusNotFound)
+			default:
+				http.Error(w, "Error saving bookmark", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bookmark)
+
+	case "DELETE":
+		targetType := query.Get("target_type")
+		targetID, _ := strconv.Atoi(query.Get("target_id"))
+		if !models.IsValidBookmarkTarget(targetType) || targetID <= 0 {
+			http.Error(w, models.ErrInvalidBookmarkTarget.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := database.DeleteBookmark(userID, targetType, targetID); err != nil {
+			http.Error(w, "Error removing bookmark", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleBookmarkFolders lists the current user's bookmark folders (GET),
+// creates one (POST {name}), renames one (PUT ?id= {name}) and deletes one
+// (DELETE ?id=), keeping its bookmarks outside any folder
+func (h *Handlers) HandleBookmarkFolders(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var folderID int
+	if r.Method == "PUT" || r.Method == "DELETE" {
+		folderID, err = strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || folderID <= 0 {
+			http.Error(w, models.ErrInvalidBookmarkFolder.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch r.Method {
+	case "GET":
+		folders, err := database.GetBookmarkFolders(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving folders", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(folders)
+
+	case "POST", "PUT":
+		var req models.BookmarkFolderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var folder *models.BookmarkFolder
+		status := http.StatusOK
+		if r.Method == "POST" {
+			folder, err = database.CreateBookmarkFolder(userID, req.Name)
+			status = http.StatusCreated
+		} else {
+			folder, err = database.RenameBookmarkFolder(userID, folderID, req.Name)
+		}
+		if err != nil {
+			switch err {
+			case database.ErrBookmarkFolderExists:
+				http.Error(w, err.Error(), http.StatusConflict)
+			case database.ErrBookmarkFolderNotFound:
+				http.Error(w, err.Error(), http.StatusNotFound)
+			default:
+				http.Error(w, "Error saving folder", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(folder)
+
+	case "DELETE":
+		if err := database.DeleteBookmarkFolder(userID, folderID); err != nil {
+			if err == database.ErrBookmarkFolderNotFound {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Error deleting folder", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
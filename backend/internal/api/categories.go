@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+)
+
+// HandleCategories lists the categories in display order (GET, with
+// ?include_archived=true to include archived ones), and lets admins create
+// one (POST {name, description, parentId}) or change one (PUT ?id= {name,
+// description, parentId, archived}, where fields left out are unchanged)
+func (h *Handlers) HandleCategories(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		// Anyone may list categories; those signed in also see the ones
+		// restricted to them
+		userID, _ := utils.GetUserIDFromSession(r)
+		includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("include_archived"))
+		categories, err := database.GetCategories(userID, includeArchived)
+		if err != nil {
+			http.Error(w, "Error retrieving categories", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(categories)
+
+	case "POST":
+		if _, ok := requireRole(w, r, models.RoleAdmin); !ok {
+			return
+		}
+		var req models.CreateCategoryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		category, err := database.CreateCategory(req)
+		if err != nil {
+			writeCategoryError(w, err)
+			return
+		}
+		h.Hub.NotifyCategoriesUpdated()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(category)
+
+	case "PUT":
+		if _, ok := requireRole(w, r, models.RoleAdmin); !ok {
+			return
+		}
+		categoryID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || categoryID <= 0 {
+			http.Error(w, models.ErrInvalidCategory.Error(), http.StatusBadRequest)
+			return
+		}
+		var req models.UpdateCategoryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		category, err := database.UpdateCategory(categoryID, req)
+		if err != nil {
+			writeCategoryError(w, err)
+			return
+		}
+		h.Hub.NotifyCategoriesUpdated()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(category)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleCategoryOrder lets admins reorder the top-level categories or the
+// subcategories of one category (PUT {parentId, categoryIds})
+func (h *Handlers) HandleCategoryOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PUT" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := requireRole(w, r, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req models.CategoryOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := database.ReorderCategories(req.ParentID, req.CategoryIDs); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	h.Hub.NotifyCategoriesUpdated()
+
+	categories, err := database.GetCategories(userID, true)
+	if err != nil {
+		http.Error(w, "Error retrieving categories", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+// writeCategoryError responds to a failed category change
+func writeCategoryError(w http.ResponseWriter, err error) {
+	switch err {
+	case database.ErrCategoryNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case database.ErrCategoryExists:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case database.ErrInvalidCategoryParent, models.ErrInvalidCategoryOrder:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Error saving category", http.StatusInternalServerError)
+	}
+}
+
+// HandleCategoryPermissions lets admins see (GET ?category_id=) and replace
+// (PUT ?category_id= {permissions: [{action, role | groupId}]}) the
+// permission rules of a category
+func (h *Handlers) HandleCategoryPermissions(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireRole(w, r, models.RoleAdmin); !ok {
+		return
+	}
+	categoryID, err := strconv.Atoi(r.URL.Query().Get("category_id"))
+	if err != nil || categoryID <= 0 {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var req models.CategoryPermissionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := database.SetCategoryPermissions(categoryID, req.Permissions); err != nil {
+			switch err {
+			case database.ErrCategoryNotFound:
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case database.ErrGroupNotFound:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, "Error saving permissions", http.StatusInternalServerError)
+			}
+			return
+		}
+		h.Hub.NotifyCategoriesUpdated()
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	permissions, err := database.GetCategoryPermissions(categoryID)
+	if err != nil {
+		if err == database.ErrCategoryNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error retrieving permissions", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(permissions)
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+)
+
+// writeConversationError maps conversation errors to HTTP responses
+func writeConversationError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case database.ErrConversationNotFound, database.ErrUserNotFound, database.ErrMessageNotFound, database.ErrNoMessageRequest:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case database.ErrNotParticipant, database.ErrNotMessageSender, database.ErrEditWindowExpired, database.ErrBlocked,
+		database.ErrMessagesNotAccepted:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case database.ErrMessageDeleted:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case database.ErrNotGroupConversation, models.ErrInvalidParticipants, database.ErrAttachmentUnavailable,
+		database.ErrRecipientMismatch:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// HandleConversations lists, creates and renames conversations
+func (h *Handlers) HandleConversations(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		conversations, err := database.GetConversationsForUser(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving conversations", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(conversations)
+
+	case "POST":
+		var req models.CreateConversationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conversation, err := database.CreateConversation(userID, req.Title, req.ParticipantIDs)
+		if err != nil {
+			writeConversationError(w, err, "Error creating conversation")
+			return
+		}
+
+		h.Hub.NotifyConversationUpdated(conversation.ID, userID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(conversation)
+
+	case "PUT":
+		var req models.RenameConversationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := database.RenameConversation(req.ConversationID, userID, req.Title); err != nil {
+			writeConversationError(w, err, "Error renaming conversation")
+			return
+		}
+
+		h.Hub.NotifyConversationUpdated(req.ConversationID, userID)
+
+		conversation, err := database.GetConversation(req.ConversationID, userID)
+		if err != nil {
+			writeConversationError(w, err, "Error retrieving conversation")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(conversation)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleConversationMembers adds members to a group conversation (POST) or
+// leaves it (DELETE)
+func (h *Handlers) HandleConversationMembers(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		var req models.AddParticipantsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := database.AddConversationParticipants(req.ConversationID, userID, req.UserIDs); err != nil {
+			writeConversationError(w, err, "Error adding members")
+			return
+		}
+
+		h.Hub.NotifyConversationUpdated(req.ConversationID, userID)
+
+		conversation, err := database.GetConversation(req.ConversationID, userID)
+		if err != nil {
+			writeConversationError(w, err, "Error retrieving conversation")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(conversation)
+
+	case "DELETE":
+		conversationID, err := strconv.Atoi(r.URL.Query().Get("conversation_id"))
+		if err != nil {
+			http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := database.LeaveConversation(conversationID, userID); err != nil {
+			writeConversationError(w, err, "Error leaving conversation")
+			return
+		}
+
+		h.Hub.NotifyConversationUpdated(conversationID, userID, userID)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleConversationMessages retrieves a page of messages in a conversation
+func (h *Handlers) HandleConversationMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conversationID, err := strconv.Atoi(r.URL.Query().Get("conversation_id"))
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	member, err := database.IsConversationParticipant(conversationID, userID)
+	if err != nil {
+		http.Error(w, "Error retrieving messages", http.StatusInternalServerError)
+		return
+	}
+	if !member {
+		writeConversationError(w, database.ErrNotParticipant, "")
+		return
+	}
+
+	messages, err := database.GetConversationMessages(conversationID, userID, offset)
+	if err != nil {
+		http.Error(w, "Error retrieving messages", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+// HandleConversationRead records the last message a participant has read
+func (h *Handlers) HandleConversationRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Hub.MarkRead(userID, req.ConversationID, req.MessageID); err != nil {
+		writeConversationError(w, err, "Error marking conversation read")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleMessageRequests lists (GET) and accepts or declines (POST) the
+// conversations waiting in the user's message requests
+func (h *Handlers) HandleMessageRequests(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		conversations, err := database.GetMessageRequests(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving message requests", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(conversations)
+
+	case "POST":
+		var req models.MessageRequestAction
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conversation, err := database.GetConversation(req.ConversationID, userID)
+		if err != nil {
+			writeConversationError(w, err, "Error updating message request")
+			return
+		}
+
+		accept := req.Action == models.RequestActionAccept
+		if err := database.RespondToMessageRequest(req.ConversationID, userID, accept); err != nil {
+			writeConversationError(w, err, "Error updating message request")
+			return
+		}
+
+		// Declining a one-to-one request is not announced to the sender
+		if accept {
+			h.Hub.NotifyConversationUpdated(req.ConversationID, userID)
+		} else if conversation.IsGroup {
+			h.Hub.NotifyConversationUpdated(req.ConversationID, userID, userID)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleMessageRevisions retrieves the edit history of a message
+func (h *Handlers) HandleMessageRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	messageID, err := strconv.Atoi(r.URL.Query().Get("message_id"))
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := database.GetMessageRevisions(messageID, userID)
+	if err != nil {
+		writeConversationError(w, err, "Error retrieving message history")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/utils"
+)
+
+// HandleDigestSettings shows (GET) and changes (PUT {frequency}) how often
+// the current user gets an email digest
+func (h *Handlers) HandleDigestSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var settings models.DigestSettings
+	switch r.Method {
+	case "GET":
+		settings.Frequency, err = database.GetDigestFrequency(userID)
+		if err != nil {
+			http.Error(w, "Error retrieving digest settings", http.StatusInternalServerError)
+			return
+		}
+	case "PUT":
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := settings.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := database.SetDigestFrequency(userID, settings.Frequency); err != nil {
+			http.Error(w, "Error updating digest settings", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// HandleDigestUnsubscribe turns off email digests for the user an
+// unsubscribe token belongs to. It needs no session: GET serves the link in
+// the email and POST serves one-click unsubscribe from mail clients.
+func (h *Handlers) HandleDigestUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.UnsubscribeDigest(token); err != nil {
+		if err == database.ErrInvalidUnsubscribeToken {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error unsubscribing", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("You will no longer receive email digests. You can turn them back on in the notification settings.\n"))
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"strconv"
+)
+
+// HandleUserGroups lets admins list user groups (GET), create one (POST
+// {name}), rename one (PUT ?id= {name}) and delete one (DELETE ?id=), which
+// also drops the category permissions granted to it
+func (h *Handlers) HandleUserGroups(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireRole(w, r, models.RoleAdmin); !ok {
+		return
+	}
+
+	var groupID int
+	if r.Method == "PUT" || r.Method == "DELETE" {
+		var err error
+		groupID, err = strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || groupID <= 0 {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch r.Method {
+	case "GET":
+		groups, err := database.GetUserGroups()
+		if err != nil {
+			http.Error(w, "Error retrieving groups", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(groups)
+
+	case "POST", "PUT":
+		var req models.UserGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var group *models.UserGroup
+		var err error
+		status := http.StatusOK
+		if r.Method == "POST" {
+			group, err = database.CreateUserGroup(req.Name)
+			status = http.StatusCreated
+		} else {
+			group, err = database.RenameUserGroup(groupID, req.Name)
+		}
+		if err != nil {
+			switch err {
+			case database.ErrGroupExists:
+				http.Error(w, err.Error(), http.StatusConflict)
+			case database.ErrGroupNotFound:
+				http.Error(w, err.Error(), http.StatusNotFound)
+			default:
+				http.Error(w, "Error saving group", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(group)
+
+	case "DELETE":
+		if err := database.DeleteUserGroup(groupID); err != nil {
+			if err == database.ErrGroupNotFound {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Error deleting group", http.StatusInternalServerError)
+			return
+		}
+		h.Hub.NotifyCategoriesUpdated()
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleUserGroupMembers lets admins list the members of a group (GET
+// ?group_id=), add one (POST {groupId, userId}) and remove one (DELETE
+// ?group_id=&user_id=)
+func (h *Handlers) HandleUserGroupMembers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireRole(w, r, models.RoleAdmin); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	switch r.Method {
+	case "GET":
+		groupID, err := strconv.Atoi(query.Get("group_id"))
+		if err != nil || groupID <= 0 {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		members, err := database.GetGroupMembers(groupID)
+		if err != nil {
+			if err == database.ErrGroupNotFound {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Error retrieving group members", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(members)
+
+	case "POST":
+		var req models.GroupMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := database.AddGroupMember(req.GroupID, req.UserID); err != nil {
+			switch err {
+			case database.ErrGroupNotFound, database.ErrUserNotFound:
+				http.Error(w, err.Error(), http.StatusNotFound)
+			default:
+				http.Error(w, "Error adding group member", http.StatusInternalServerError)
+			}
+			return
+		}
+		h.Hub.NotifyCategoriesUpdated()
+		w.WriteHeader(http.StatusNoContent)
+
+	case "DELETE":
+		groupID, _ := strconv.Atoi(query.Get("group_id"))
+		userID, _ := strconv.Atoi(query.Get("user_id"))
+		if groupID <= 0 || userID <= 0 {
+			http.Error(w, models.ErrInvalidGroupMember.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := database.RemoveGroupMember(groupID, userID); err != nil {
+			http.Error(w, "Error removing group member", http.StatusInternalServerError)
+			return
+		}
+		h.Hub.NotifyCategoriesUpdated()
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
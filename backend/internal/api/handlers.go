@@ -5,21 +5,25 @@ import (
 	"net/http"
 	"real-time-forum/backend/internal/database"
 	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/uploads"
 	"real-time-forum/backend/internal/utils"
 	"real-time-forum/backend/internal/websocket"
 	"strconv"
+	"strings"
 	// "time"
 )
 
 // Handlers contains all HTTP handlers and dependencies
 type Handlers struct {
-	Hub *websocket.Hub
+	Hub     *websocket.Hub
+	Uploads *uploads.Service
 }
 
 // NewHandlers creates a new handlers instance
-func NewHandlers(hub *websocket.Hub) *Handlers {
+func NewHandlers(hub *websocket.Hub, uploader *uploads.Service) *Handlers {
 	return &Handlers{
-		Hub: hub,
+		Hub:     hub,
+		Uploads: uploader,
 	}
 }
 
@@ -76,12 +80,23 @@ func (h *Handlers) HandleProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Update user in database
 	if err := database.UpdateUser(userID, &userData); err != nil {
+		if err == models.ErrInvalidDMPrivacy || err == models.ErrInvalidAvatarColor {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Error updating profile", http.StatusInternalServerError)
 		return
 	}
+	h.Hub.NotifyProfileUpdated(userID)
+
+	user, err := database.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Error retrieving user info", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(userData)
+	json.NewEncoder(w).Encode(user)
 }
 
 // HandleRegister handles user registration
@@ -167,6 +182,17 @@ func (h *Handlers) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Banned users cannot log in until their ban ends
+	ban, err := database.GetActiveBan(user.ID)
+	if err != nil {
+		http.Error(w, "Authentication error", http.StatusInternalServerError)
+		return
+	}
+	if ban != nil {
+		http.Error(w, ban.Message(), http.StatusForbidden)
+		return
+	}
+
 	// Create session
 	sessionID, err := utils.CreateSession(user.ID)
 	if err != nil {
@@ -214,22 +240,67 @@ func (h *Handlers) HandlePosts(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		categoryIDStr := r.URL.Query().Get("category_id")
-		var posts []models.Post
-		if categoryIDStr != "" {
+		opts := models.PostListOptions{
+			Sort:  r.URL.Query().Get("sort"),
+			Range: r.URL.Query().Get("range"),
+		}
+		if postIDStr := r.URL.Query().Get("post_id"); postIDStr != "" {
+			postID, err := strconv.Atoi(postIDStr)
+			if err != nil || postID <= 0 {
+				http.Error(w, "Invalid post ID", http.StatusBadRequest)
+				return
+			}
+			opts.PostID = postID
+		}
+		if categoryIDStr := r.URL.Query().Get("category_id"); categoryIDStr != "" {
 			categoryID, err := strconv.Atoi(categoryIDStr)
 			if err != nil {
 				http.Error(w, "Invalid category ID", http.StatusBadRequest)
 				return
 			}
-			posts, err = database.GetPosts(categoryID)
-		} else {
-			posts, err = database.GetPosts(0) // 0 means all categories
+			opts.CategoryID = categoryID
+		}
+		if watching := r.URL.Query().Get("watching"); watching != "" {
+			opts.Watching, err = strconv.ParseBool(watching)
+			if err != nil {
+				http.Error(w, "Invalid watching filter", http.StatusBadRequest)
+				return
+			}
+		}
+		if includeArchived := r.URL.Query().Get("include_archived"); includeArchived != "" {
+			opts.IncludeArchived, err = strconv.ParseBool(includeArchived)
+			if err != nil {
+				http.Error(w, "Invalid include_archived filter", http.StatusBadRequest)
+				return
+			}
+		}
+		// Tags may be repeated or comma-separated
+		for _, tags := range r.URL.Query()["tag"] {
+			opts.Tags = append(opts.Tags, strings.Split(tags, ",")...)
 		}
+		opts.TagMatch = r.URL.Query().Get("tag_match")
+		opts.Offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+		if err := opts.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		posts, err := database.GetPosts(opts, userID)
 		if err != nil {
 			http.Error(w, "Error retrieving posts", http.StatusInternalServerError)
 			return
 		}
+
+		// A single post was asked for
+		if opts.PostID != 0 {
+			if len(posts) == 0 {
+				http.Error(w, "Post not found", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(posts[0])
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(posts)
 
@@ -252,10 +323,33 @@ func (h *Handlers) HandlePosts(w http.ResponseWriter, r *http.Request) {
 			Title:      req.Title,
 			Content:    req.Content,
 			CategoryID: req.CategoryID,
+			Tags:       req.Tags,
 		}
 
-		if err := database.CreatePost(&post); err != nil {
-			http.Error(w, "Error creating post", http.StatusInternalServerError)
+		if err := database.CheckCategoryOpen(req.CategoryID); err != nil {
+			switch err {
+			case database.ErrCategoryNotFound, database.ErrCategoryArchived:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, "Error creating post", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if err := database.CheckAttachments(userID, req.AttachmentIDs); err != nil {
+			writeAttachmentError(w, err, "Error creating post")
+			return
+		}
+
+		if err := database.CreatePost(&post, req.AttachmentIDs); err != nil {
+			switch err {
+			case database.ErrCategoryNotFound, database.ErrAttachmentUnavailable:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case database.ErrCategoryForbidden:
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				http.Error(w, "Error creating post", http.StatusInternalServerError)
+			}
 			return
 		}
 
@@ -274,7 +368,7 @@ func (h *Handlers) HandlePosts(w http.ResponseWriter, r *http.Request) {
 		// }
 
 		// Broadcast new post event
-		h.Hub.HandleNewPost(&post, user.Nickname, user.AvatarColor)
+		h.Hub.HandleNewPost(&post, user)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(post)
@@ -298,7 +392,11 @@ func (h *Handlers) HandleComments(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		comments, err := database.GetComments(postID)
+		comments, err := database.GetComments(postID, userID)
+		if err == database.ErrPostNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Error retrieving comments", http.StatusInternalServerError)
 			return
@@ -321,13 +419,30 @@ func (h *Handlers) HandleComments(w http.ResponseWriter, r *http.Request) {
 
 		// Create comment from request
 		comment := models.Comment{
-			PostID:  req.PostID,
-			UserID:  userID,
-			Content: req.Content,
+			PostID:   req.PostID,
+			ParentID: req.ParentID,
+			UserID:   userID,
+			Content:  req.Content,
+		}
+
+		if err := database.CheckAttachments(userID, req.AttachmentIDs); err != nil {
+			writeAttachmentError(w, err, "Error creating comment")
+			return
 		}
 
-		if err := database.CreateComment(&comment); err != nil {
-			http.Error(w, "Error creating comment", http.StatusInternalServerError)
+		if err := database.CreateComment(&comment, req.AttachmentIDs); err != nil {
+			switch err {
+			case database.ErrCommentNotFound:
+				http.Error(w, "Parent comment not found on this post", http.StatusBadRequest)
+			case database.ErrAttachmentUnavailable:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case database.ErrPostNotFound:
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case database.ErrCategoryForbidden, database.ErrPostLocked, database.ErrPostArchived:
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				http.Error(w, "Error creating comment", http.StatusInternalServerError)
+			}
 			return
 		}
 
@@ -346,7 +461,7 @@ func (h *Handlers) HandleComments(w http.ResponseWriter, r *http.Request) {
 		// }
 
 		// Broadcast new comment event
-		h.Hub.HandleNewComment(&comment, user.Nickname, user.AvatarColor)
+		h.Hub.HandleNewComment(&comment, user)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(comment)
@@ -380,6 +495,79 @@ func (h *Handlers) HandleMessages(w http.ResponseWriter, r *http.Request) {
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(messages)
+
+	case "POST":
+		var req models.CreateMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		// Validate message request
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Store and deliver through the hub, same as the WebSocket path
+		response, err := h.Hub.SendPrivateMessage(userID, req)
+		if err == websocket.ErrRateLimited {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if err != nil {
+			writeConversationError(w, err, "Error sending message")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+
+	case "PUT":
+		var req models.EditMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		message, err := h.Hub.EditMessage(userID, req)
+		if err != nil {
+			writeConversationError(w, err, "Error editing message")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(message)
+
+	case "DELETE":
+		messageID, err := strconv.Atoi(r.URL.Query().Get("message_id"))
+		if err != nil {
+			http.Error(w, "Invalid message ID", http.StatusBadRequest)
+			return
+		}
+
+		req := models.DeleteMessageRequest{
+			MessageID: messageID,
+			Scope:     r.URL.Query().Get("scope"),
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.Hub.DeleteMessage(userID, req); err != nil {
+			writeConversationError(w, err, "Error deleting message")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
@@ -407,19 +595,46 @@ func (h *Handlers) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	websocket.HandleWebSocket(h.Hub, w, r)
 }
 
-// HandleCategories retrieves all categories
-func (h *Handlers) HandleCategories(w http.ResponseWriter, r *http.Request) {
+// HandleEvents streams real-time events over Server-Sent Events
+func (h *Handlers) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	websocket.HandleSSE(h.Hub, w, r)
+}
+
+// HandlePoll delivers real-time events over long polling
+func (h *Handlers) HandlePoll(w http.ResponseWriter, r *http.Request) {
+	websocket.HandleLongPoll(h.Hub, w, r)
+}
+
+// HandleRealtimeActions accepts client events from SSE and long-poll clients
+func (h *Handlers) HandleRealtimeActions(w http.ResponseWriter, r *http.Request) {
+	websocket.HandleClientAction(h.Hub, w, r)
+}
+
+// HandleWebSocketMetrics reports send queue and dropped frame metrics for
+// the hub. Moderators see every connection; others only their own, so as not
+// to show who else is connected.
+func (h *Handlers) HandleWebSocketMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	categories, err := database.GetCategories()
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role, err := database.GetUserRole(userID)
 	if err != nil {
-		http.Error(w, "Error retrieving categories", http.StatusInternalServerError)
+		http.Error(w, "Error checking permissions", http.StatusInternalServerError)
 		return
 	}
 
+	metrics := h.Hub.Metrics()
+	if !models.RoleAtLeast(role, models.RoleModerator) {
+		metrics.OnlyUser(userID)
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(categories)
-}
\ No newline at end of file
+	json.NewEncoder(w).Encode(metrics)
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
 	"real-time-forum/backend/internal/utils"
 )
 
@@ -22,6 +24,33 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// requireRole returns the ID of the user making the request if they have
+// one of the given roles, or writes an error and returns false. Admins pass
+// every check.
+func requireRole(w http.ResponseWriter, r *http.Request, roles ...string) (int, bool) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+
+	role, err := database.GetUserRole(userID)
+	if err != nil {
+		http.Error(w, "Error checking permissions", http.StatusInternalServerError)
+		return 0, false
+	}
+	if role == models.RoleAdmin {
+		return userID, true
+	}
+	for _, allowed := range roles {
+		if role == allowed {
+			return userID, true
+		}
+	}
+	http.Error(w, "Forbidden", http.StatusForbidden)
+	return 0, false
+}
+
 // CORSMiddleware provides CORS headers for API requests
 func CORSMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"strconv"
+)
+
+// HandlePostState lets moderators pin, lock and archive a post (PUT
+// ?post_id= {pinned, locked, archived}, where fields left out are
+// unchanged and a pinned of "" unpins)
+func (h *Handlers) HandlePostState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PUT" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := requireRole(w, r, models.RoleModerator)
+	if !ok {
+		return
+	}
+	postID, err := strconv.Atoi(r.URL.Query().Get("post_id"))
+	if err != nil || postID <= 0 {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.PostStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.Hub.UpdatePostState(userID, postID, req)
+	if err != nil {
+		if err == database.ErrPostNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error updating post", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+)
+
+// HandleNotifications lists the current user's notifications, newest first,
+// with their unread count (GET ?offset=)
+func (h *Handlers) HandleNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	notifications, err := database.GetNotifications(userID, max(offset, 0))
+	if err != nil {
+		http.Error(w, "Error retrieving notifications", http.StatusInternalServerError)
+		return
+	}
+	count, err := database.GetUnreadNotificationCount(userID)
+	if err != nil {
+		http.Error(w, "Error counting notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NotificationList{Notifications: notifications, UnreadCount: count})
+}
+
+// HandleMarkNotificationsRead marks some of the current user's
+// notifications as read (POST {notificationIds})
+func (h *Handlers) HandleMarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.MarkNotificationsReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changed, err := database.MarkNotificationsRead(userID, req.NotificationIDs)
+	h.writeUnreadCount(w, userID, changed, err)
+}
+
+// HandleMarkAllNotificationsRead marks all of the current user's
+// notifications as read (POST)
+func (h *Handlers) HandleMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	changed, err := database.MarkAllNotificationsRead(userID)
+	h.writeUnreadCount(w, userID, changed, err)
+}
+
+// writeUnreadCount finishes a mark-read request: it tells the user's other
+// connections when anything changed and responds with the unread count
+func (h *Handlers) writeUnreadCount(w http.ResponseWriter, userID, changed int, err error) {
+	if err != nil {
+		http.Error(w, "Error marking notifications read", http.StatusInternalServerError)
+		return
+	}
+	if changed > 0 {
+		h.Hub.NotifyNotificationsRead(userID)
+	}
+
+	count, err := database.GetUnreadNotificationCount(userID)
+	if err != nil {
+		http.Error(w, "Error counting notifications", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"unreadCount": count})
+}
+
+// HandleNotificationPreferences shows (GET) and changes (PUT) which
+// notification types the current user receives. PUT takes a map of type to
+// on or off; types left out are unchanged.
+func (h *Handlers) HandleNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var prefs models.NotificationPreferences
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := prefs.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := database.SetNotificationPreferences(userID, prefs); err != nil {
+			http.Error(w, "Error updating preferences", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefs, err := database.GetNotificationPreferences(userID)
+	if err != nil {
+		http.Error(w, "Error retrieving preferences", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+)
+
+// HandleReactions adds (POST) or removes (DELETE) the user's reaction to a
+// post, comment or message and returns the target's counts
+func (h *Handlers) HandleReactions(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ReactionRequest
+	switch r.Method {
+	case "POST":
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	case "DELETE":
+		query := r.URL.Query()
+		req.TargetType = query.Get("target_type")
+		req.TargetID, _ = strconv.Atoi(query.Get("target_id"))
+		req.Emoji = query.Get("emoji")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	counts, err := h.Hub.React(userID, req, r.Method == "POST")
+	if err != nil {
+		switch err {
+		case database.ErrPostNotFound, database.ErrCommentNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case database.ErrPostArchived:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case database.ErrTooManyReactionEmojis:
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			writeConversationError(w, err, "Error updating reaction")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(counts)
+}
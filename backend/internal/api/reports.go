@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+)
+
+// HandleReports lets any user report a post, comment, message or user
+// (POST {targetType, targetId, reason, note}), and moderators work through
+// the queue: list it (GET ?status=&assignee_id=&target_type=&offset=), see
+// one report (GET ?id=) and assign, resolve or reopen one (PUT ?id=
+// {status, assigneeId, resolutionNote}, where fields left out are
+// unchanged)
+func (h *Handlers) HandleReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch r.Method {
+	case "POST":
+		userID, err := utils.GetUserIDFromSession(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req models.ReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := h.Hub.CreateReport(userID, req)
+		if err != nil {
+			writeReportError(w, err, "Error saving report")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(report)
+
+	case "GET":
+		userID, ok := requireRole(w, r, models.RoleModerator)
+		if !ok {
+			return
+		}
+		if query.Has("id") {
+			reportID, err := strconv.Atoi(query.Get("id"))
+			if err != nil || reportID <= 0 {
+				http.Error(w, "Invalid report ID", http.StatusBadRequest)
+				return
+			}
+			report, err := database.GetReport(reportID, userID)
+			if err != nil {
+				writeReportError(w, err, "Error retrieving report")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(report)
+			return
+		}
+
+		var opts models.ReportListOptions
+		opts.Offset, _ = strconv.Atoi(query.Get("offset"))
+		opts.Offset = max(opts.Offset, 0)
+		if opts.Status = query.Get("status"); opts.Status != "" && !models.IsValidReportStatus(opts.Status) {
+			http.Error(w, models.ErrInvalidReportStatus.Error(), http.StatusBadRequest)
+			return
+		}
+		if assignee := query.Get("assignee_id"); assignee != "" {
+			var err error
+			if opts.AssigneeID, err = strconv.Atoi(assignee); err != nil || opts.AssigneeID <= 0 {
+				http.Error(w, models.ErrInvalidReportAssignee.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if opts.TargetType = query.Get("target_type"); opts.TargetType != "" && !models.IsValidReportTarget(opts.TargetType) {
+			http.Error(w, models.ErrInvalidReportTarget.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reports, err := database.GetReports(userID, opts)
+		if err != nil {
+			http.Error(w, "Error retrieving reports", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reports)
+
+	case "PUT":
+		userID, ok := requireRole(w, r, models.RoleModerator)
+		if !ok {
+			return
+		}
+		reportID, err := strconv.Atoi(query.Get("id"))
+		if err != nil || reportID <= 0 {
+			http.Error(w, "Invalid report ID", http.StatusBadRequest)
+			return
+		}
+		var req models.UpdateReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := h.Hub.UpdateReport(userID, reportID, req)
+		if err != nil {
+			writeReportError(w, err, "Error updating report")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeReportError responds to a failed report or report change
+func writeReportError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case database.ErrReportNotFound, database.ErrPostNotFound, database.ErrCommentNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case database.ErrReportExists, database.ErrDuplicateOpenReport:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case database.ErrCannotReportSelf, database.ErrReportAssigneeNotStaff:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeConversationError(w, err, fallback)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"real-time-forum/backend/internal/utils"
+	"strconv"
+)
+
+// HandleTags lists the tags in use with their post counts (GET ?q=&sort=
+// &offset=), or shows one tag, found by its name or a synonym (GET ?name=)
+func (h *Handlers) HandleTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	if name := query.Get("name"); name != "" {
+		name = models.NormalizeTag(name)
+		if !models.IsValidTag(name) {
+			http.Error(w, models.ErrInvalidTag.Error(), http.StatusBadRequest)
+			return
+		}
+		tag, err := database.GetTag(name, userID)
+		if err != nil {
+			if err == database.ErrTagNotFound {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Error retrieving tag", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tag)
+		return
+	}
+
+	opts := models.TagListOptions{Prefix: query.Get("q"), Sort: query.Get("sort")}
+	opts.Offset, _ = strconv.Atoi(query.Get("offset"))
+	opts.Offset = max(opts.Offset, 0)
+	if err := opts.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tags, err := database.GetTags(opts, userID)
+	if err != nil {
+		http.Error(w, "Error retrieving tags", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// HandleTagSynonyms lets moderators make a name stand for a tag (POST
+// {synonym, tag}) or stop it doing so (DELETE ?name=)
+func (h *Handlers) HandleTagSynonyms(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireRole(w, r, models.RoleModerator)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		var req models.TagSynonymRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := database.AddTagSynonym(req.Synonym, req.Tag); err != nil {
+			if err == models.ErrInvalidTagSynonym {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Error adding synonym", http.StatusInternalServerError)
+			return
+		}
+
+		tag, err := database.GetTag(req.Tag, userID)
+		if err == database.ErrTagNotFound {
+			// The tag is not on any post yet
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error retrieving tag", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tag)
+
+	case "DELETE":
+		name := models.NormalizeTag(r.URL.Query().Get("name"))
+		if !models.IsValidTag(name) {
+			http.Error(w, models.ErrInvalidTag.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := database.RemoveTagSynonym(name); err != nil {
+			if err == database.ErrTagSynonymNotFound {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Error removing synonym", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"real-time-forum/backend/internal/models"
+	"strings"
+	"time"
+)
+
+// attachmentColumns lists the columns scanned by scanAttachment
+const attachmentColumns = `id, uploader_id, COALESCE(target_type, ''), COALESCE(target_id, 0),
+	filename, content_type, size, width, height, storage_key, thumbnail_key, created_at`
+
+// scanAttachment scans a row selected with attachmentColumns and fills in its download URLs
+func scanAttachment(row interface{ Scan(...interface{}) error }) (*models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.UploaderID, &a.TargetType, &a.TargetID,
+		&a.Filename, &a.ContentType, &a.Size, &a.Width, &a.Height, &a.StorageKey, &a.ThumbnailKey, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	a.URL = fmt.Sprintf("/api/attachments/download?id=%d", a.ID)
+	if a.ThumbnailKey != "" {
+		a.ThumbnailURL = a.URL + "&thumb=1"
+	}
+	return &a, nil
+}
+
+// CreateAttachment records an uploaded file that is not attached to anything yet
+func CreateAttachment(a *models.Attachment) error {
+	result, err := DB.Exec(`
+		INSERT INTO attachments (uploader_id, filename, content_type, size, width, height, storage_key, thumbnail_key)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.UploaderID, a.Filename, a.ContentType, a.Size, a.Width, a.Height, a.StorageKey, a.ThumbnailKey)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	created, err := GetAttachment(int(id))
+	if err != nil {
+		return err
+	}
+	*a = *created
+	return nil
+}
+
+// GetAttachment retrieves an attachment by its ID
+func GetAttachment(attachmentID int) (*models.Attachment, error) {
+	a, err := scanAttachment(DB.QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE id = ?", attachmentID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// CanViewAttachment reports whether a user may see an attachment: unused
+// uploads only by their uploader, message files only by the conversation's
+// participants while the message exists, and post or comment files by
+// users who may view the post's category
+func CanViewAttachment(attachment *models.Attachment, userID int) (bool, error) {
+	switch attachment.TargetType {
+	case "":
+		return attachment.UploaderID == userID, nil
+	case models.AttachmentTargetMessage:
+		message, err := GetMessage(attachment.TargetID, userID)
+		if err == ErrMessageNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return !message.IsDeleted, nil
+	}
+
+	postID := attachment.TargetID
+	if attachment.TargetType == models.AttachmentTargetComment {
+		var err error
+		if postID, err = GetCommentPostID(attachment.TargetID); err == ErrCommentNotFound {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+	err := CheckPostAccess(userID, postID, models.CategoryActionView)
+	if err == ErrPostNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CheckAttachments verifies that every attachment was uploaded by userID
+// and has not been used yet
+func CheckAttachments(userID int, attachmentIDs []int) error {
+	attachmentIDs = uniqueIDs(attachmentIDs)
+	if len(attachmentIDs) == 0 {
+		return nil
+	}
+
+	placeholders, args := idArgs(attachmentIDs)
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM attachments
+		WHERE uploader_id = ? AND target_type IS NULL AND id IN (`+placeholders+`)
+	`, append([]interface{}{userID}, args...)...).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != len(attachmentIDs) {
+		return ErrAttachmentUnavailable
+	}
+	return nil
+}
+
+// attachFiles attaches unused uploads of userID to a post, comment or
+// message as part of the transaction creating it
+func attachFiles(tx *sql.Tx, userID int, targetType string, targetID int, attachmentIDs []int) error {
+	attachmentIDs = uniqueIDs(attachmentIDs)
+	if len(attachmentIDs) == 0 {
+		return nil
+	}
+
+	placeholders, args := idArgs(attachmentIDs)
+	result, err := tx.Exec(`
+		UPDATE attachments SET target_type = ?, target_id = ?
+		WHERE uploader_id = ? AND target_type IS NULL AND id IN (`+placeholders+`)
+	`, append([]interface{}{targetType, targetID, userID}, args...)...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); int(n) != len(attachmentIDs) {
+		return ErrAttachmentUnavailable
+	}
+	return nil
+}
+
+// getAttachments retrieves the attachments of several posts, comments or
+// messages, keyed by target ID
+func getAttachments(targetType string, targetIDs []int) (map[int][]models.Attachment, error) {
+	attachments := make(map[int][]models.Attachment)
+	if len(targetIDs) == 0 {
+		return attachments, nil
+	}
+
+	placeholders, args := idArgs(targetIDs)
+	rows, err := DB.Query(`
+		SELECT `+attachmentColumns+` FROM attachments
+		WHERE target_type = ? AND target_id IN (`+placeholders+`)
+		ORDER BY id
+	`, append([]interface{}{targetType}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments[a.TargetID] = append(attachments[a.TargetID], *a)
+	}
+	return attachments, rows.Err()
+}
+
+// DeleteUnusedAttachment removes an upload of userID that was never attached
+// and returns it so its files can be deleted
+func DeleteUnusedAttachment(attachmentID, userID int) (*models.Attachment, error) {
+	a, err := GetAttachment(attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a.UploaderID != userID || a.TargetType != "" {
+		return nil, ErrAttachmentUnavailable
+	}
+
+	if _, err := DB.Exec("DELETE FROM attachments WHERE id = ?", attachmentID); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// GetOrphanedAttachments retrieves uploads that were never attached and are
+// older than the cutoff, along with attachments of deleted messages and of
+// posts or comments that no longer exist
+func GetOrphanedAttachments(cutoff time.Time) ([]models.Attachment, error) {
+	rows, err := DB.Query(`
+		SELECT `+attachmentColumns+` FROM attachments a
+		WHERE (a.target_type IS NULL AND a.created_at < ?)
+			OR (a.target_type = 'post' AND NOT EXISTS (SELECT 1 FROM posts WHERE id = a.target_id))
+			OR (a.target_type = 'comment' AND NOT EXISTS (SELECT 1 FROM comments WHERE id = a.target_id))
+			OR (a.target_type = 'message' AND NOT EXISTS (
+				SELECT 1 FROM messages WHERE id = a.target_id AND deleted_at IS NULL))
+	`, cutoff.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+	return attachments, rows.Err()
+}
+
+// DeleteAttachmentRecord removes an attachment row once its files are gone
+func DeleteAttachmentRecord(attachmentID int) error {
+	_, err := DB.Exec("DELETE FROM attachments WHERE id = ?", attachmentID)
+	return err
+}
+
+// idArgs builds the placeholders and arguments of an IN clause
+func idArgs(ids []int) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","), args
+}
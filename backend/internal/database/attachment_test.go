@@ -0,0 +1,83 @@
+package database
+
+import (
+	"fmt"
+	"real-time-forum/backend/internal/models"
+	"testing"
+	"time"
+)
+
+var testAttachmentCount int
+
+// newTestAttachment stores an unused upload of uploaderID
+func newTestAttachment(t *testing.T, uploaderID int) int {
+	t.Helper()
+	testAttachmentCount++
+	a := &models.Attachment{UploaderID: uploaderID, Filename: "notes.txt", ContentType: "text/plain",
+		StorageKey: fmt.Sprintf("upload-%d", testAttachmentCount)}
+	if err := CreateAttachment(a); err != nil {
+		t.Fatal(err)
+	}
+	return a.ID
+}
+
+func TestAttachmentsAreClaimedWithTheirTarget(t *testing.T) {
+	author := newTestUser(t, models.RoleUser)
+	other := newTestUser(t, models.RoleUser)
+	categoryID := newTestCategory(t, "")
+	post := &models.Post{UserID: author, CategoryID: categoryID, Title: "Files", Content: "hi"}
+	if err := CreatePost(post, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		create func(attachmentIDs []int) error
+		stored func() int
+	}{
+		{
+			"post",
+			func(ids []int) error {
+				return CreatePost(&models.Post{UserID: author, CategoryID: categoryID, Title: "Files", Content: "hi"}, ids)
+			},
+			func() int { return countRows(t, "SELECT COUNT(*) FROM posts WHERE user_id = ?", author) },
+		},
+		{
+			"comment",
+			func(ids []int) error {
+				return CreateComment(&models.Comment{PostID: post.ID, UserID: author, Content: "hi"}, ids)
+			},
+			func() int { return countRows(t, "SELECT COUNT(*) FROM comments WHERE user_id = ?", author) },
+		},
+		{
+			"message",
+			func(ids []int) error {
+				return CreateMessage(&models.Message{SenderID: author, RecipientID: other, Content: "hi", CreatedAt: time.Now()}, ids)
+			},
+			func() int { return countRows(t, "SELECT COUNT(*) FROM messages WHERE sender_id = ?", author) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := tt.stored()
+			own, foreign := newTestAttachment(t, author), newTestAttachment(t, other)
+
+			if err := tt.create([]int{own, foreign}); err != ErrAttachmentUnavailable {
+				t.Fatalf("create with another user's upload error = %v, want %v", err, ErrAttachmentUnavailable)
+			}
+			if got := tt.stored(); got != before {
+				t.Errorf("%d rows stored after a failed create, want %d", got, before)
+			}
+			if n := countRows(t, "SELECT COUNT(*) FROM attachments WHERE id = ? AND target_type IS NULL", own); n != 1 {
+				t.Error("the author's upload was claimed by a create that failed")
+			}
+
+			if err := tt.create([]int{own}); err != nil {
+				t.Fatal(err)
+			}
+			if n := countRows(t, "SELECT COUNT(*) FROM attachments WHERE id = ? AND target_type = ?", own, tt.name); n != 1 {
+				t.Error("the upload was not attached")
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+	"strings"
+	"time"
+)
+
+// activeBan keeps bans that have neither expired nor been lifted
+const activeBan = `lifted_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`
+
+// BannedUsersQuery selects the users with an active ban
+const BannedUsersQuery = `SELECT user_id FROM user_bans WHERE ` + activeBan
+
+// banColumns selects a ban along with the names of the users it involves
+// and whether it is active; banJoins must follow FROM user_bans b
+const banColumns = `b.id, b.user_id, COALESCE(u.nickname, ''), COALESCE(b.banned_by, 0), COALESCE(m.nickname, ''),
+	b.reason, b.expires_at, b.hide_content, ` + activeBan + `,
+	b.created_at, b.lifted_at, COALESCE(b.lifted_by, 0), COALESCE(l.nickname, '')`
+
+// banJoins joins what banColumns needs
+const banJoins = `
+	LEFT JOIN users u ON u.id = b.user_id
+	LEFT JOIN users m ON m.id = b.banned_by
+	LEFT JOIN users l ON l.id = b.lifted_by`
+
+// scanBan scans a row selected with banColumns
+func scanBan(row interface{ Scan(...interface{}) error }) (*models.Ban, error) {
+	var b models.Ban
+	var expiresAt, liftedAt sql.NullTime
+	err := row.Scan(&b.ID, &b.UserID, &b.UserName, &b.BannedBy, &b.BannedByName,
+		&b.Reason, &expiresAt, &b.HideContent, &b.Active,
+		&b.CreatedAt, &liftedAt, &b.LiftedBy, &b.LiftedByName)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		b.ExpiresAt = &expiresAt.Time
+	}
+	if liftedAt.Valid {
+		b.LiftedAt = &liftedAt.Time
+	}
+	return &b, nil
+}
+
+// checkCanBan makes sure moderatorID ranks above the user they want to ban
+// or unban, so moderators cannot ban each other or admins
+func checkCanBan(moderatorID, userID int) error {
+	role, err := GetUserRole(userID)
+	if err != nil {
+		return err
+	}
+	moderatorRole, err := GetUserRole(moderatorID)
+	if err != nil {
+		return err
+	}
+	if models.RoleAtLeast(role, moderatorRole) {
+		return ErrCannotBanUser
+	}
+	return nil
+}
+
+// BanUser bans a user, replacing any ban they already have: the old one is
+// recorded as lifted by the moderator
+func BanUser(moderatorID int, req models.BanRequest) (*models.Ban, error) {
+	if err := checkCanBan(moderatorID, req.UserID); err != nil {
+		return nil, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE user_bans SET lifted_at = CURRENT_TIMESTAMP, lifted_by = ?
+		WHERE user_id = ? AND `+activeBan, moderatorID, req.UserID); err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO user_bans (user_id, banned_by, reason, expires_at, hide_content)
+		VALUES (?, ?, ?, CASE WHEN ? > 0 THEN datetime('now', '+' || ? || ' hours') END, ?)
+		RETURNING id
+	`, req.UserID, moderatorID, req.Reason, req.Hours, req.Hours, req.HideContent).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return scanBan(DB.QueryRow(`SELECT `+banColumns+` FROM user_bans b `+banJoins+` WHERE b.id = ?`, id))
+}
+
+// LiftBan ends a user's active ban early
+func LiftBan(moderatorID, userID int) (*models.Ban, error) {
+	if err := checkCanBan(moderatorID, userID); err != nil {
+		return nil, err
+	}
+
+	var id int
+	err := DB.QueryRow(`
+		UPDATE user_bans SET lifted_at = CURRENT_TIMESTAMP, lifted_by = ?
+		WHERE user_id = ? AND `+activeBan+`
+		RETURNING id
+	`, moderatorID, userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotBanned
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBan(DB.QueryRow(`SELECT `+banColumns+` FROM user_bans b `+banJoins+` WHERE b.id = ?`, id))
+}
+
+// GetActiveBan retrieves a user's active ban, or nil if they are not banned
+func GetActiveBan(userID int) (*models.Ban, error) {
+	ban, err := scanBan(DB.QueryRow(`
+		SELECT `+banColumns+` FROM user_bans b `+banJoins+`
+		WHERE b.user_id = ? AND `+activeBan+`
+		ORDER BY b.id DESC LIMIT 1
+	`, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return ban, err
+}
+
+// GetContentHidingBans retrieves the users whose active ban hides their
+// content, and when the first of those bans runs out; the time is zero when
+// all of them are permanent
+func GetContentHidingBans() (map[int]bool, time.Time, error) {
+	rows, err := DB.Query(`SELECT user_id, expires_at FROM user_bans WHERE hide_content AND ` + activeBan)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+
+	users := make(map[int]bool)
+	var until time.Time
+	for rows.Next() {
+		var userID int
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&userID, &expiresAt); err != nil {
+			return nil, time.Time{}, err
+		}
+		users[userID] = true
+		if expiresAt.Valid && (until.IsZero() || expiresAt.Time.Before(until)) {
+			until = expiresAt.Time
+		}
+	}
+	return users, until, rows.Err()
+}
+
+// GetBans retrieves the ban history, most recent first, 50 at a time
+func GetBans(opts models.BanListOptions) ([]models.Ban, error) {
+	conditions := []string{"1"}
+	var args []interface{}
+	if opts.UserID != 0 {
+		conditions = append(conditions, "b.user_id = ?")
+		args = append(args, opts.UserID)
+	}
+	if opts.ActiveOnly {
+		conditions = append(conditions, activeBan)
+	}
+	args = append(args, opts.Offset)
+
+	rows, err := DB.Query(`
+		SELECT `+banColumns+` FROM user_bans b `+banJoins+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY b.id DESC
+		LIMIT 50 OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bans := []models.Ban{}
+	for rows.Next() {
+		b, err := scanBan(rows)
+		if err != nil {
+			return nil, err
+		}
+		bans = append(bans, *b)
+	}
+	return bans, rows.Err()
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"real-time-forum/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestGetContentHidingBans(t *testing.T) {
+	moderator := newTestUser(t, models.RoleModerator)
+	permanent := newTestUser(t, models.RoleUser)
+	temporary := newTestUser(t, models.RoleUser)
+	visible := newTestUser(t, models.RoleUser)
+	expired := newTestUser(t, models.RoleUser)
+
+	bans := []models.BanRequest{
+		{UserID: permanent, Reason: "spam", HideContent: true},
+		{UserID: temporary, Reason: "spam", Hours: 2, HideContent: true},
+		{UserID: visible, Reason: "spam", Hours: 1},
+		{UserID: expired, Reason: "spam", Hours: 1, HideContent: true},
+	}
+	for _, req := range bans {
+		if _, err := BanUser(moderator, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := DB.Exec("UPDATE user_bans SET expires_at = datetime('now', '-1 minute') WHERE user_id = ?", expired); err != nil {
+		t.Fatal(err)
+	}
+
+	users, until, err := GetContentHidingBans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[int]bool{permanent: true, temporary: true, visible: false, expired: false} {
+		if users[id] != want {
+			t.Errorf("user %d hidden = %v, want %v", id, users[id], want)
+		}
+	}
+	if wait := time.Until(until); wait < time.Hour || wait > 2*time.Hour {
+		t.Errorf("first expiry in %v, want about 2h", wait)
+	}
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"real-time-forum/backend/internal/models"
+)
+
+// restrictedAuthorsQuery selects the users a viewer blocked or muted; it
+// takes the viewer's ID twice
+const restrictedAuthorsQuery = `SELECT blocked_id FROM user_blocks WHERE blocker_id = ?
+	UNION SELECT muted_id FROM user_mutes WHERE muter_id = ?`
+
+// hiddenAuthorsQuery selects the users whose content a viewer has hidden by
+// blocking or muting them, and banned users whose content is hidden from
+// everyone; it takes the viewer's ID twice
+const hiddenAuthorsQuery = restrictedAuthorsQuery + `
+	UNION SELECT user_id FROM user_bans WHERE hide_content AND ` + activeBan
+
+// BlockUser adds a user to blockerID's block list
+func BlockUser(blockerID, blockedID int) error {
+	return restrictUser("INSERT OR IGNORE INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)", blockerID, blockedID)
+}
+
+// UnblockUser removes a user from blockerID's block list
+func UnblockUser(blockerID, blockedID int) error {
+	_, err := DB.Exec("DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?", blockerID, blockedID)
+	return err
+}
+
+// MuteUser adds a user to muterID's mute list
+func MuteUser(muterID, mutedID int) error {
+	return restrictUser("INSERT OR IGNORE INTO user_mutes (muter_id, muted_id) VALUES (?, ?)", muterID, mutedID)
+}
+
+// UnmuteUser removes a user from muterID's mute list
+func UnmuteUser(muterID, mutedID int) error {
+	_, err := DB.Exec("DELETE FROM user_mutes WHERE muter_id = ? AND muted_id = ?", muterID, mutedID)
+	return err
+}
+
+// restrictUser inserts a block or mute after checking the target exists
+func restrictUser(query string, userID, targetID int) error {
+	if _, err := GetUserByID(targetID); err != nil {
+		return err
+	}
+	_, err := DB.Exec(query, userID, targetID)
+	return err
+}
+
+// GetBlockedUsers retrieves the users userID has blocked, most recent first
+func GetBlockedUsers(userID int) ([]models.RestrictedUser, error) {
+	return getRestrictedUsers(`
+		SELECT u.id, u.nickname, u.avatar_color, u.avatar_key, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = ?
+		ORDER BY b.created_at DESC
+	`, userID)
+}
+
+// GetMutedUsers retrieves the users userID has muted, most recent first
+func GetMutedUsers(userID int) ([]models.RestrictedUser, error) {
+	return getRestrictedUsers(`
+		SELECT u.id, u.nickname, u.avatar_color, u.avatar_key, m.created_at
+		FROM user_mutes m
+		JOIN users u ON u.id = m.muted_id
+		WHERE m.muter_id = ?
+		ORDER BY m.created_at DESC
+	`, userID)
+}
+
+// getRestrictedUsers runs a block or mute list query
+func getRestrictedUsers(query string, userID int) ([]models.RestrictedUser, error) {
+	rows, err := DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.RestrictedUser{}
+	for rows.Next() {
+		var user models.RestrictedUser
+		var avatarKey string
+		if err := rows.Scan(&user.ID, &user.Nickname, &user.AvatarColor, &avatarKey, &user.Since); err != nil {
+			return nil, err
+		}
+		user.AvatarURL = models.AvatarURL(user.ID, avatarKey)
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// IsBlockedEitherWay reports whether either user has blocked the other
+func IsBlockedEitherWay(userID, otherUserID int) (bool, error) {
+	var blocked bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_blocks
+			WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?))
+	`, userID, otherUserID, otherUserID, userID).Scan(&blocked)
+	return blocked, err
+}
+
+// GetBlockRelations retrieves the users userID has blocked or been blocked by
+func GetBlockRelations(userID int) ([]int, error) {
+	return queryIDs(`
+		SELECT blocked_id FROM user_blocks WHERE blocker_id = ?
+		UNION SELECT blocker_id FROM user_blocks WHERE blocked_id = ?
+	`, userID, userID)
+}
+
+// GetHiddenAuthors retrieves the users whose content userID has blocked or muted
+func GetHiddenAuthors(userID int) ([]int, error) {
+	return queryIDs(restrictedAuthorsQuery, userID, userID)
+}
+
+// queryIDs runs a query returning a single column of IDs
+func queryIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
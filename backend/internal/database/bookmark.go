@@ -0,0 +1,228 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+	"strings"
+)
+
+// bookmarkColumns selects a bookmark with its folder, the bookmarked post or
+// comment, the post a comment was made on, and the author; it needs the
+// joins of bookmarkJoins after FROM bookmarks b
+const bookmarkColumns = `b.id, b.target_type, b.target_id, COALESCE(b.folder_id, 0), COALESCE(f.name, ''), b.note, b.created_at,
+	COALESCE(p.id, cp.id), COALESCE(p.title, cp.title), COALESCE(p.content, c.content), u.id, u.nickname, u.avatar_key`
+
+// bookmarkJoins joins what bookmarkColumns needs. Bookmarks of posts or
+// comments that no longer exist are dropped by the join on their author.
+const bookmarkJoins = `
+	LEFT JOIN bookmark_folders f ON f.id = b.folder_id
+	LEFT JOIN posts p ON b.target_type = 'post' AND p.id = b.target_id
+	LEFT JOIN comments c ON b.target_type = 'comment' AND c.id = b.target_id
+	LEFT JOIN posts cp ON cp.id = c.post_id
+	JOIN users u ON u.id = COALESCE(p.user_id, c.user_id)`
+
+// scanBookmark scans a row selected with bookmarkColumns
+func scanBookmark(row interface{ Scan(...interface{}) error }) (*models.Bookmark, error) {
+	var b models.Bookmark
+	var avatarKey, excerpt string
+	err := row.Scan(&b.ID, &b.TargetType, &b.TargetID, &b.FolderID, &b.FolderName, &b.Note, &b.CreatedAt,
+		&b.PostID, &b.PostTitle, &excerpt, &b.AuthorID, &b.AuthorName, &avatarKey)
+	if err != nil {
+		return nil, err
+	}
+	b.AuthorAvatar = models.AvatarURL(b.AuthorID, avatarKey)
+	b.Excerpt = truncateText(excerpt, maxExcerptLength)
+	return &b, nil
+}
+
+// SaveBookmark bookmarks a post or comment for a user, or updates the folder
+// and note of an existing bookmark, and returns the stored bookmark
+func SaveBookmark(userID int, req models.BookmarkRequest) (*models.Bookmark, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	postID := req.TargetID
+	if req.TargetType == models.BookmarkTargetComment {
+		var err error
+		if postID, err = GetCommentPostID(req.TargetID); err != nil {
+			return nil, err
+		}
+	}
+	if err := CheckPostAccess(userID, postID, models.CategoryActionView); err != nil {
+		if err == ErrPostNotFound && req.TargetType == models.BookmarkTargetComment {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+
+	var folderID interface{}
+	if req.FolderID > 0 {
+		if err := checkBookmarkFolder(userID, req.FolderID); err != nil {
+			return nil, err
+		}
+		folderID = req.FolderID
+	}
+
+	_, err := DB.Exec(`
+		INSERT INTO bookmarks (user_id, target_type, target_id, folder_id, note) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, target_type, target_id) DO UPDATE SET folder_id = excluded.folder_id, note = excluded.note
+	`, userID, req.TargetType, req.TargetID, folderID, req.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBookmark(DB.QueryRow(`
+		SELECT `+bookmarkColumns+` FROM bookmarks b `+bookmarkJoins+`
+		WHERE b.user_id = ? AND b.target_type = ? AND b.target_id = ?
+	`, userID, req.TargetType, req.TargetID))
+}
+
+// DeleteBookmark removes a user's bookmark of a post or comment, if any
+func DeleteBookmark(userID int, targetType string, targetID int) error {
+	_, err := DB.Exec("DELETE FROM bookmarks WHERE user_id = ? AND target_type = ? AND target_id = ?",
+		userID, targetType, targetID)
+	return err
+}
+
+// GetBookmarks retrieves a user's bookmarks, most recently saved first, 20
+// at a time. Bookmarks of users they have since blocked or muted, and of
+// posts in categories they may no longer view, are left out.
+func GetBookmarks(userID int, opts models.BookmarkListOptions) ([]models.Bookmark, error) {
+	visible, visibleArgs, err := categoryFilter(userID, models.CategoryActionView, "COALESCE(p.category_id, cp.category_id)")
+	if err != nil {
+		return nil, err
+	}
+	conditions := []string{"b.user_id = ?", "u.id NOT IN (" + hiddenAuthorsQuery + ")", visible}
+	args := append([]interface{}{userID, userID, userID}, visibleArgs...)
+	if opts.FolderID != 0 {
+		conditions = append(conditions, "b.folder_id = ?")
+		args = append(args, opts.FolderID)
+	}
+	if opts.TargetType != "" {
+		conditions = append(conditions, "b.target_type = ?")
+		args = append(args, opts.TargetType)
+	}
+	args = append(args, opts.Offset)
+
+	rows, err := DB.Query(`
+		SELECT `+bookmarkColumns+` FROM bookmarks b `+bookmarkJoins+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY b.id DESC
+		LIMIT 20 OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []models.Bookmark{}
+	for rows.Next() {
+		b, err := scanBookmark(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, *b)
+	}
+	return bookmarks, rows.Err()
+}
+
+// checkBookmarkFolder makes sure a folder exists and belongs to the user
+func checkBookmarkFolder(userID, folderID int) error {
+	var exists bool
+	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM bookmark_folders WHERE id = ? AND user_id = ?)", folderID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrBookmarkFolderNotFound
+	}
+	return nil
+}
+
+// GetBookmarkFolders retrieves a user's folders by name, with the number of
+// bookmarks in each
+func GetBookmarkFolders(userID int) ([]models.BookmarkFolder, error) {
+	rows, err := DB.Query(`
+		SELECT f.id, f.name, f.created_at, COUNT(b.id)
+		FROM bookmark_folders f
+		LEFT JOIN bookmarks b ON b.folder_id = f.id
+		WHERE f.user_id = ?
+		GROUP BY f.id
+		ORDER BY f.name COLLATE NOCASE
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []models.BookmarkFolder{}
+	for rows.Next() {
+		var f models.BookmarkFolder
+		if err := rows.Scan(&f.ID, &f.Name, &f.CreatedAt, &f.Count); err != nil {
+			return nil, err
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+// CreateBookmarkFolder creates a folder for a user
+func CreateBookmarkFolder(userID int, name string) (*models.BookmarkFolder, error) {
+	folder := models.BookmarkFolder{Name: name}
+	err := DB.QueryRow(`
+		INSERT INTO bookmark_folders (user_id, name) VALUES (?, ?)
+		RETURNING id, created_at
+	`, userID, name).Scan(&folder.ID, &folder.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrBookmarkFolderExists
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// RenameBookmarkFolder renames one of a user's folders
+func RenameBookmarkFolder(userID, folderID int, name string) (*models.BookmarkFolder, error) {
+	folder := models.BookmarkFolder{ID: folderID, Name: name}
+	err := DB.QueryRow(`
+		UPDATE bookmark_folders SET name = ? WHERE id = ? AND user_id = ?
+		RETURNING created_at, (SELECT COUNT(*) FROM bookmarks WHERE folder_id = ?)
+	`, name, folderID, userID, folderID).Scan(&folder.CreatedAt, &folder.Count)
+	if err == sql.ErrNoRows {
+		return nil, ErrBookmarkFolderNotFound
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrBookmarkFolderExists
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// DeleteBookmarkFolder deletes one of a user's folders. Its bookmarks are
+// kept outside any folder.
+func DeleteBookmarkFolder(userID, folderID int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM bookmark_folders WHERE id = ? AND user_id = ?", folderID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrBookmarkFolderNotFound
+	}
+
+	if _, err := tx.Exec("UPDATE bookmarks SET folder_id = NULL WHERE folder_id = ?", folderID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -1,27 +1,296 @@
 package database
 
-// Category represents a forum category
-type Category struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	CreatedAt string `json:"created_at"`
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// categoryColumns selects a category with its post count and the time of
+// its latest post or comment
+const categoryColumns = `c.id, c.name, COALESCE(c.description, ''), COALESCE(c.parent_id, 0), c.position,
+	c.archived_at IS NOT NULL, c.created_at,
+	(SELECT COUNT(*) FROM posts WHERE category_id = c.id),
+	(SELECT MAX(t) FROM (
+		SELECT MAX(created_at) AS t FROM posts WHERE category_id = c.id
+		UNION ALL
+		SELECT MAX(cm.created_at) FROM comments cm JOIN posts cp ON cp.id = cm.post_id WHERE cp.category_id = c.id
+	))`
+
+// categoryOrder lists top-level categories by position, each followed by
+// its subcategories by position; it needs categories c joined to their
+// parent as parent
+const categoryOrder = `COALESCE(parent.position, c.position), COALESCE(c.parent_id, c.id), c.parent_id IS NOT NULL, c.position`
+
+// scanCategory scans a row selected with categoryColumns
+func scanCategory(row interface{ Scan(...interface{}) error }) (*models.Category, error) {
+	var c models.Category
+	var lastActivity sql.NullString
+	err := row.Scan(&c.ID, &c.Name, &c.Description, &c.ParentID, &c.Position, &c.Archived, &c.CreatedAt,
+		&c.PostCount, &lastActivity)
+	if err != nil {
+		return nil, err
+	}
+	if lastActivity.Valid {
+		if t, ok := parseTimestamp(lastActivity.String); ok {
+			c.LastActivityAt = &t
+		}
+	}
+	return &c, nil
+}
+
+// parseTimestamp parses a timestamp SQLite returned as text, as it does for
+// aggregates of DATETIME columns
+func parseTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimSuffix(s, "Z")
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
 }
 
-// GetCategories retrieves all categories
-func GetCategories() ([]Category, error) {
-	rows, err := DB.Query("SELECT id, name, created_at FROM categories")
+// GetCategories retrieves the categories viewerID may view in display
+// order, with what they may do in each, leaving out archived ones unless
+// includeArchived is set
+func GetCategories(viewerID int, includeArchived bool) ([]models.Category, error) {
+	access, err := loadCategoryAccess(viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	where := ""
+	if !includeArchived {
+		where = "WHERE c.archived_at IS NULL AND parent.archived_at IS NULL"
+	}
+	rows, err := DB.Query(`
+		SELECT ` + categoryColumns + `
+		FROM categories c
+		LEFT JOIN categories parent ON parent.id = c.parent_id
+		` + where + `
+		ORDER BY ` + categoryOrder)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var categories []Category
+	categories := []models.Category{}
 	for rows.Next() {
-		var c Category
-		if err := rows.Scan(&c.ID, &c.Name, &c.CreatedAt); err != nil {
+		c, err := scanCategory(rows)
+		if err != nil {
 			return nil, err
 		}
-		categories = append(categories, c)
+		if !access.allows(c.ID, models.CategoryActionView) {
+			continue
+		}
+		c.CanPost = access.allows(c.ID, models.CategoryActionPost)
+		c.CanComment = access.allows(c.ID, models.CategoryActionComment)
+		categories = append(categories, *c)
+	}
+	return categories, rows.Err()
+}
+
+// GetCategory retrieves a category by ID
+func GetCategory(categoryID int) (*models.Category, error) {
+	c, err := scanCategory(DB.QueryRow(`SELECT `+categoryColumns+` FROM categories c WHERE c.id = ?`, categoryID))
+	if err == sql.ErrNoRows {
+		return nil, ErrCategoryNotFound
+	}
+	return c, err
+}
+
+// CheckCategoryOpen makes sure new posts can go in a category: it must
+// exist, and neither it nor its parent may be archived
+func CheckCategoryOpen(categoryID int) error {
+	var archived bool
+	err := DB.QueryRow(`
+		SELECT c.archived_at IS NOT NULL OR COALESCE(parent.archived_at IS NOT NULL, 0)
+		FROM categories c LEFT JOIN categories parent ON parent.id = c.parent_id
+		WHERE c.id = ?
+	`, categoryID).Scan(&archived)
+	if err == sql.ErrNoRows {
+		return ErrCategoryNotFound
 	}
-	return categories, nil
-}
\ No newline at end of file
+	if err != nil {
+		return err
+	}
+	if archived {
+		return ErrCategoryArchived
+	}
+	return nil
+}
+
+// checkCategoryParent makes sure a category can be the parent of
+// categoryID (0 for a new category): it must be an existing top-level
+// category other than categoryID, and categoryID must have no
+// subcategories of its own
+func checkCategoryParent(tx *sql.Tx, categoryID, parentID int) error {
+	if parentID == categoryID {
+		return ErrInvalidCategoryParent
+	}
+	var parentOfParent sql.NullInt64
+	err := tx.QueryRow("SELECT parent_id FROM categories WHERE id = ?", parentID).Scan(&parentOfParent)
+	if err == sql.ErrNoRows || parentOfParent.Valid {
+		return ErrInvalidCategoryParent
+	}
+	if err != nil {
+		return err
+	}
+
+	var hasChildren bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE parent_id = ?)", categoryID).Scan(&hasChildren)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return ErrInvalidCategoryParent
+	}
+	return nil
+}
+
+// nullableID turns an ID of 0 into NULL
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// CreateCategory creates a category, or a subcategory when it has a parent,
+// after the existing ones
+func CreateCategory(req models.CreateCategoryRequest) (*models.Category, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if req.ParentID != 0 {
+		if err := checkCategoryParent(tx, 0, req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	var categoryID int
+	err = tx.QueryRow(`
+		INSERT INTO categories (name, description, parent_id, position)
+		VALUES (?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM categories WHERE parent_id IS ?))
+		RETURNING id
+	`, req.Name, req.Description, nullableID(req.ParentID), nullableID(req.ParentID)).Scan(&categoryID)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetCategory(categoryID)
+}
+
+// UpdateCategory renames, describes, moves, archives or restores a
+// category. A moved category goes after its new siblings.
+func UpdateCategory(categoryID int, req models.UpdateCategoryRequest) (*models.Category, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var currentParent sql.NullInt64
+	err = tx.QueryRow("SELECT parent_id FROM categories WHERE id = ?", categoryID).Scan(&currentParent)
+	if err == sql.ErrNoRows {
+		return nil, ErrCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sets []string
+	var args []interface{}
+	if req.Name != nil {
+		sets = append(sets, "name = ?")
+		args = append(args, *req.Name)
+	}
+	if req.Description != nil {
+		sets = append(sets, "description = ?")
+		args = append(args, *req.Description)
+	}
+	if req.ParentID != nil && int(currentParent.Int64) != *req.ParentID {
+		if *req.ParentID != 0 {
+			if err := checkCategoryParent(tx, categoryID, *req.ParentID); err != nil {
+				return nil, err
+			}
+		}
+		sets = append(sets, "parent_id = ?", "position = (SELECT COALESCE(MAX(position), 0) + 1 FROM categories WHERE parent_id IS ?)")
+		args = append(args, nullableID(*req.ParentID), nullableID(*req.ParentID))
+	}
+	if req.Archived != nil {
+		if *req.Archived {
+			sets = append(sets, "archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP)")
+		} else {
+			sets = append(sets, "archived_at = NULL")
+		}
+	}
+
+	if len(sets) > 0 {
+		args = append(args, categoryID)
+		if _, err := tx.Exec("UPDATE categories SET "+strings.Join(sets, ", ")+" WHERE id = ?", args...); err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return nil, ErrCategoryExists
+			}
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetCategory(categoryID)
+}
+
+// ReorderCategories puts the subcategories of parentID, or the top-level
+// categories when it is 0, in the given order. Every one of them must be
+// listed.
+func ReorderCategories(parentID int, categoryIDs []int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id FROM categories WHERE parent_id IS ?", nullableID(parentID))
+	if err != nil {
+		return err
+	}
+	siblings := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		siblings[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(siblings) != len(categoryIDs) {
+		return models.ErrInvalidCategoryOrder
+	}
+	for position, id := range categoryIDs {
+		if !siblings[id] {
+			return models.ErrInvalidCategoryOrder
+		}
+		if _, err := tx.Exec("UPDATE categories SET position = ? WHERE id = ?", position+1, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
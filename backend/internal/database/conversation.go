@@ -0,0 +1,430 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"real-time-forum/backend/internal/models"
+)
+
+// directKey returns the unique key of the one-to-one conversation between two users
+func directKey(userID, otherUserID int) string {
+	if userID > otherUserID {
+		userID, otherUserID = otherUserID, userID
+	}
+	return fmt.Sprintf("%d:%d", userID, otherUserID)
+}
+
+// FindDirectConversation returns the one-to-one conversation between two
+// users, or 0 if they have never messaged each other
+func FindDirectConversation(userID, otherUserID int) (int, error) {
+	var conversationID int
+	err := DB.QueryRow("SELECT id FROM conversations WHERE direct_key = ?", directKey(userID, otherUserID)).Scan(&conversationID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return conversationID, err
+}
+
+// GetOrCreateDirectConversation returns the one-to-one conversation between
+// two users, creating it on first contact. A new conversation lands in the
+// other user's message requests unless their privacy setting lets userID in.
+func GetOrCreateDirectConversation(userID, otherUserID int) (int, error) {
+	if conversationID, err := FindDirectConversation(userID, otherUserID); err != nil || conversationID != 0 {
+		return conversationID, err
+	}
+
+	otherStatus, err := incomingStatus(otherUserID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	key := directKey(userID, otherUserID)
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO conversations (is_group, direct_key, created_by)
+		VALUES (0, ?, ?)
+	`, key, userID); err != nil {
+		return 0, err
+	}
+
+	var conversationID int
+	if err := tx.QueryRow("SELECT id FROM conversations WHERE direct_key = ?", key).Scan(&conversationID); err != nil {
+		return 0, err
+	}
+
+	statuses := map[int]string{userID: models.ParticipantAccepted, otherUserID: otherStatus}
+	for id, status := range statuses {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO conversation_participants (conversation_id, user_id, status)
+			VALUES (?, ?, ?)
+		`, conversationID, id, status); err != nil {
+			return 0, err
+		}
+	}
+
+	return conversationID, tx.Commit()
+}
+
+// CreateConversation creates a group conversation with the creator and the
+// given participants; it reaches each participant as their privacy setting allows
+func CreateConversation(creatorID int, title string, participantIDs []int) (*models.Conversation, error) {
+	members := uniqueIDs(append([]int{creatorID}, participantIDs...))
+	statuses, err := invitedStatuses(creatorID, members)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO conversations (title, is_group, created_by)
+		VALUES (?, 1, ?)
+	`, title, creatorID)
+	if err != nil {
+		return nil, err
+	}
+	conversationID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range members {
+		if _, err := tx.Exec(`
+			INSERT INTO conversation_participants (conversation_id, user_id, status)
+			VALUES (?, ?, ?)
+		`, conversationID, id, statuses[id]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetConversation(int(conversationID), creatorID)
+}
+
+// GetConversation retrieves a conversation with its active participants as seen by viewerID
+func GetConversation(conversationID, viewerID int) (*models.Conversation, error) {
+	var c models.Conversation
+	err := DB.QueryRow(`
+		SELECT c.id, c.title, c.is_group, COALESCE(c.created_by, 0), c.created_at, c.updated_at,
+			(SELECT COUNT(*) FROM messages m
+			 WHERE m.conversation_id = c.id AND m.id > p.last_read_message_id AND m.sender_id != p.user_id
+			   AND m.deleted_at IS NULL
+			   AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = p.user_id)
+			   AND `+notFromBlockedSender+`
+			   AND `+sinceJoined+`),
+			p.status
+		FROM conversations c
+		JOIN conversation_participants p ON p.conversation_id = c.id AND p.user_id = ? AND p.left_at IS NULL
+		WHERE c.id = ?
+	`, viewerID, conversationID).Scan(&c.ID, &c.Title, &c.IsGroup, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.UnreadCount, &c.Status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+
+	if err := fillConversation(&c, viewerID); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetConversationsForUser retrieves the conversations in a user's inbox, most recent first
+func GetConversationsForUser(userID int) ([]models.Conversation, error) {
+	return getConversations(userID, models.ParticipantAccepted)
+}
+
+// GetMessageRequests retrieves the conversations waiting in a user's
+// message requests, most recent first
+func GetMessageRequests(userID int) ([]models.Conversation, error) {
+	return getConversations(userID, models.ParticipantPending)
+}
+
+// getConversations retrieves the conversations a user takes part in with the given status
+func getConversations(userID int, status string) ([]models.Conversation, error) {
+	rows, err := DB.Query(`
+		SELECT c.id, c.title, c.is_group, COALESCE(c.created_by, 0), c.created_at, c.updated_at,
+			(SELECT COUNT(*) FROM messages m
+			 WHERE m.conversation_id = c.id AND m.id > p.last_read_message_id AND m.sender_id != p.user_id
+			   AND m.deleted_at IS NULL
+			   AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = p.user_id)
+			   AND `+notFromBlockedSender+`
+			   AND `+sinceJoined+`),
+			p.status
+		FROM conversations c
+		JOIN conversation_participants p ON p.conversation_id = c.id AND p.user_id = ? AND p.left_at IS NULL
+		WHERE p.status = ?
+		ORDER BY c.updated_at DESC, c.id DESC
+	`, userID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	var conversations []models.Conversation
+	for rows.Next() {
+		var c models.Conversation
+		if err := rows.Scan(&c.ID, &c.Title, &c.IsGroup, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.UnreadCount, &c.Status); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range conversations {
+		if err := fillConversation(&conversations[i], userID); err != nil {
+			return nil, err
+		}
+	}
+	return conversations, nil
+}
+
+// fillConversation loads the participants and the last message of a
+// conversation visible to viewerID
+func fillConversation(c *models.Conversation, viewerID int) error {
+	participants, err := getConversationParticipants(c.ID)
+	if err != nil {
+		return err
+	}
+	c.Participants = participants
+
+	m, err := scanMessage(DB.QueryRow(`
+		SELECT `+messageColumns+`
+		FROM messages m
+		JOIN conversation_participants p ON p.conversation_id = m.conversation_id AND p.user_id = ?
+		JOIN users s ON m.sender_id = s.id
+		LEFT JOIN users r ON m.recipient_id = r.id
+		WHERE m.conversation_id = ?
+			AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = p.user_id)
+			AND `+notFromBlockedSender+`
+			AND `+sinceJoined+`
+		ORDER BY m.id DESC
+		LIMIT 1
+	`, viewerID, c.ID))
+	if err == nil {
+		c.LastMessage = m
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+// getConversationParticipants retrieves the active participants of a conversation
+func getConversationParticipants(conversationID int) ([]models.ConversationParticipant, error) {
+	rows, err := DB.Query(`
+		SELECT p.user_id, u.nickname, u.avatar_color, u.avatar_key, p.last_read_message_id, p.joined_at
+		FROM conversation_participants p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.conversation_id = ? AND p.left_at IS NULL
+		ORDER BY u.nickname
+	`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var participants []models.ConversationParticipant
+	for rows.Next() {
+		var p models.ConversationParticipant
+		var avatarKey string
+		if err := rows.Scan(&p.UserID, &p.Nickname, &p.AvatarColor, &avatarKey, &p.LastReadMessageID, &p.JoinedAt); err != nil {
+			return nil, err
+		}
+		p.AvatarURL = models.AvatarURL(p.UserID, avatarKey)
+		participants = append(participants, p)
+	}
+	return participants, rows.Err()
+}
+
+// GetConversationParticipantIDs retrieves the user IDs of a conversation's active participants
+func GetConversationParticipantIDs(conversationID int) ([]int, error) {
+	rows, err := DB.Query(`
+		SELECT user_id FROM conversation_participants
+		WHERE conversation_id = ? AND left_at IS NULL
+	`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// IsConversationParticipant reports whether a user is an active participant of a conversation
+func IsConversationParticipant(conversationID, userID int) (bool, error) {
+	var exists bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM conversation_participants
+		WHERE conversation_id = ? AND user_id = ? AND left_at IS NULL)
+	`, conversationID, userID).Scan(&exists)
+	return exists, err
+}
+
+// requireGroupParticipant checks that a conversation is a group the user belongs to
+func requireGroupParticipant(conversationID, userID int) error {
+	var isGroup bool
+	err := DB.QueryRow("SELECT is_group FROM conversations WHERE id = ?", conversationID).Scan(&isGroup)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrConversationNotFound
+		}
+		return err
+	}
+
+	ok, err := IsConversationParticipant(conversationID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotParticipant
+	}
+	if !isGroup {
+		return ErrNotGroupConversation
+	}
+	return nil
+}
+
+// RenameConversation changes the title of a group conversation
+func RenameConversation(conversationID, userID int, title string) error {
+	if err := requireGroupParticipant(conversationID, userID); err != nil {
+		return err
+	}
+
+	_, err := DB.Exec("UPDATE conversations SET title = ? WHERE id = ?", title, conversationID)
+	return err
+}
+
+// AddConversationParticipants adds users to a group conversation, re-admitting
+// anyone who left. They only see the messages sent from now on.
+func AddConversationParticipants(conversationID, userID int, userIDs []int) error {
+	if err := requireGroupParticipant(conversationID, userID); err != nil {
+		return err
+	}
+
+	userIDs = uniqueIDs(userIDs)
+	statuses, err := invitedStatuses(userID, userIDs)
+	if err != nil {
+		return err
+	}
+
+	current, err := GetConversationParticipantIDs(conversationID)
+	if err != nil {
+		return err
+	}
+	if len(uniqueIDs(append(current, userIDs...))) > models.MaxConversationParticipants {
+		return models.ErrInvalidParticipants
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range userIDs {
+		if _, err := tx.Exec(`
+			INSERT INTO conversation_participants (conversation_id, user_id, status, history_from_message_id)
+			VALUES (?, ?, ?, (SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = ?))
+			ON CONFLICT (conversation_id, user_id)
+			DO UPDATE SET left_at = NULL, joined_at = CURRENT_TIMESTAMP, status = excluded.status,
+				history_from_message_id = excluded.history_from_message_id
+			WHERE left_at IS NOT NULL
+		`, conversationID, id, statuses[id], conversationID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("UPDATE conversations SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", conversationID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LeaveConversation removes a user from a group conversation
+func LeaveConversation(conversationID, userID int) error {
+	if err := requireGroupParticipant(conversationID, userID); err != nil {
+		return err
+	}
+
+	_, err := DB.Exec(`
+		UPDATE conversation_participants SET left_at = CURRENT_TIMESTAMP
+		WHERE conversation_id = ? AND user_id = ? AND left_at IS NULL
+	`, conversationID, userID)
+	return err
+}
+
+// MarkConversationRead advances a participant's read position, never moving it backwards
+func MarkConversationRead(conversationID, userID, messageID int) (int, error) {
+	result, err := DB.Exec(`
+		UPDATE conversation_participants
+		SET last_read_message_id = MAX(last_read_message_id,
+			MIN(?, (SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = ?)))
+		WHERE conversation_id = ? AND user_id = ? AND left_at IS NULL
+	`, messageID, conversationID, conversationID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, ErrNotParticipant
+	}
+
+	var lastRead int
+	err = DB.QueryRow(`
+		SELECT last_read_message_id FROM conversation_participants
+		WHERE conversation_id = ? AND user_id = ?
+	`, conversationID, userID).Scan(&lastRead)
+	return lastRead, err
+}
+
+// invitedStatuses checks that each user exists and works out how an
+// invitation from inviterID reaches them
+func invitedStatuses(inviterID int, userIDs []int) (map[int]string, error) {
+	statuses := make(map[int]string, len(userIDs))
+	for _, id := range userIDs {
+		if id == inviterID {
+			statuses[id] = models.ParticipantAccepted
+			continue
+		}
+		status, err := incomingStatus(id, inviterID)
+		if err != nil {
+			return nil, err
+		}
+		statuses[id] = status
+	}
+	return statuses, nil
+}
+
+// uniqueIDs removes duplicate IDs while keeping their order
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
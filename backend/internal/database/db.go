@@ -5,15 +5,26 @@ import (
 	"io/ioutil"
 	"log"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 var DB *sql.DB
 
+// driverName is the SQLite driver with the forum's SQL functions registered
+const driverName = "sqlite3_forum"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("hot_rank", sqlHotRank, true)
+		},
+	})
+}
+
 // InitDB initializes the database connection and runs migrations
 func InitDB() error {
 	var err error
-	DB, err = sql.Open("sqlite3", "./forum.db")
+	DB, err = sql.Open(driverName, "./forum.db")
 	if err != nil {
 		return err
 	}
@@ -27,17 +38,62 @@ func InitDB() error {
 		return err
 	}
 
+	if err = renderMissingContent(); err != nil {
+		return err
+	}
+
 	log.Println("Database initialized successfully")
 	return nil
 }
 
-// runMigrations executes all migration files in order
+// migrationFiles lists the migration files in the order they are applied
+var migrationFiles = []string{
+	"migrations/001_init.sql",
+	"migrations/002_conversations.sql",
+	"migrations/003_message_edits.sql",
+	"migrations/004_reactions.sql",
+	"migrations/005_votes.sql",
+	"migrations/006_blocks_mutes.sql",
+	"migrations/007_dm_privacy.sql",
+	"migrations/008_attachments.sql",
+	"migrations/009_avatars.sql",
+	"migrations/010_rendered_content.sql",
+	"migrations/011_mentions.sql",
+	"migrations/012_notifications.sql",
+	"migrations/013_email_digest.sql",
+	"migrations/014_post_watches.sql",
+	"migrations/015_bookmarks.sql",
+	"migrations/016_tags.sql",
+	"migrations/017_category_management.sql",
+	"migrations/018_category_permissions.sql",
+	"migrations/019_post_moderation.sql",
+	"migrations/020_reports.sql",
+	"migrations/021_user_bans.sql",
+	"migrations/023_conversation_history.sql",
+}
+
+// runMigrations executes all migration files in order, skipping the ones
+// already recorded in schema_migrations
 func runMigrations() error {
-	migrationFiles := []string{
-		"migrations/001_init.sql",
+	if _, err := DB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			filename TEXT PRIMARY KEY,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return err
 	}
 
 	for _, file := range migrationFiles {
+		var applied bool
+		err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE filename = ?)", file).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+
 		if err := executeMigrationFile(file); err != nil {
 			log.Printf("Error executing migration %s: %v", file, err)
 			return err
@@ -48,25 +104,33 @@ func runMigrations() error {
 	return nil
 }
 
-// executeMigrationFile reads and executes a migration file
+// executeMigrationFile reads and executes a migration file in a transaction
+// and records it as applied
 func executeMigrationFile(filename string) error {
 	content, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return err
 	}
 
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-
-	_, err = DB.Exec(string(content))
-	return err
+	if _, err = tx.Exec(string(content)); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("INSERT INTO schema_migrations (filename) VALUES (?)", filename); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-
-
 // CloseDB closes the database connection
 func CloseDB() error {
 	if DB != nil {
 		return DB.Close()
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain runs the tests against a fresh database in a temporary directory,
+// migrated from the repository's migrations folder
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "forum-db-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Migration paths are relative to the repository root
+	if err := os.Chdir("../../.."); err != nil {
+		log.Fatal(err)
+	}
+	DB, err = sql.Open(driverName, filepath.Join(dir, "forum.db"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := runMigrations(); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	DB.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+var testUserCount int
+
+// newTestUser inserts a user with the given role and returns its ID
+func newTestUser(t *testing.T, role string) int {
+	t.Helper()
+	testUserCount++
+	nickname := fmt.Sprintf("user%d", testUserCount)
+	var id int
+	err := DB.QueryRow(`
+		INSERT INTO users (nickname, email, password_hash, first_name, last_name, age, gender, role)
+		VALUES (?, ?, '', 'Test', 'User', 30, 'other', ?)
+		RETURNING id
+	`, nickname, nickname+"@example.com", role).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
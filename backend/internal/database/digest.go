@@ -0,0 +1,126 @@
+package database
+
+import (
+	"real-time-forum/backend/internal/models"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// digestTimeLayout matches how CURRENT_TIMESTAMP stores times, so digest
+// times compare correctly with last_seen and notification times
+const digestTimeLayout = "2006-01-02 15:04:05"
+
+// GetDueDigestRecipients retrieves the offline users whose daily or weekly
+// digest is due at now, giving any without an unsubscribe token a new one
+func GetDueDigestRecipients(now time.Time) ([]models.DigestRecipient, error) {
+	rows, err := DB.Query(`
+		SELECT id, nickname, email, digest_frequency, COALESCE(unsubscribe_token, '')
+		FROM users
+		WHERE digest_frequency != ? AND email != '' AND is_online = 0
+			AND (last_digest_at IS NULL
+				OR last_digest_at <= datetime(?, CASE digest_frequency WHEN ? THEN '-1 day' ELSE '-7 days' END))
+		ORDER BY id
+	`, models.DigestOff, now.UTC().Format(digestTimeLayout), models.DigestDaily)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []models.DigestRecipient
+	for rows.Next() {
+		var r models.DigestRecipient
+		if err := rows.Scan(&r.UserID, &r.Nickname, &r.Email, &r.Frequency, &r.UnsubscribeToken); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range recipients {
+		if recipients[i].UnsubscribeToken != "" {
+			continue
+		}
+		token := uuid.Must(uuid.NewV4()).String()
+		if _, err := DB.Exec("UPDATE users SET unsubscribe_token = ? WHERE id = ?", token, recipients[i].UserID); err != nil {
+			return nil, err
+		}
+		recipients[i].UnsubscribeToken = token
+	}
+	return recipients, nil
+}
+
+// GetDigestItems retrieves up to limit of a user's unread direct message,
+// reply and mention notifications from after they were last seen or last
+// sent a digest, up to until, newest first
+func GetDigestItems(userID int, until time.Time, limit int) ([]models.Notification, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(models.DigestNotificationTypes)), ",")
+	args := []interface{}{userID, userID, userID}
+	for _, t := range models.DigestNotificationTypes {
+		args = append(args, t)
+	}
+	args = append(args, userID, until.UTC().Format(digestTimeLayout), limit)
+
+	rows, err := DB.Query(`
+		SELECT `+notificationColumns+` FROM notifications n `+notificationJoins+`
+		WHERE `+visibleNotifications+` AND n.read_at IS NULL AND n.type IN (`+placeholders+`)
+			AND n.created_at > (SELECT MAX(COALESCE(last_seen, ''), COALESCE(last_digest_at, '')) FROM users WHERE id = ?)
+			AND n.created_at <= ?
+		ORDER BY n.created_at DESC, n.id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *n)
+	}
+	return items, rows.Err()
+}
+
+// MarkDigestSent records that a user's digest covered everything up to at
+func MarkDigestSent(userID int, at time.Time) error {
+	_, err := DB.Exec("UPDATE users SET last_digest_at = ? WHERE id = ?", at.UTC().Format(digestTimeLayout), userID)
+	return err
+}
+
+// GetDigestFrequency retrieves how often a user gets an email digest
+func GetDigestFrequency(userID int) (string, error) {
+	var frequency string
+	err := DB.QueryRow("SELECT digest_frequency FROM users WHERE id = ?", userID).Scan(&frequency)
+	return frequency, err
+}
+
+// SetDigestFrequency changes how often a user gets an email digest
+func SetDigestFrequency(userID int, frequency string) error {
+	settings := models.DigestSettings{Frequency: frequency}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	_, err := DB.Exec("UPDATE users SET digest_frequency = ? WHERE id = ?", frequency, userID)
+	return err
+}
+
+// UnsubscribeDigest turns off email digests for the user an unsubscribe
+// token belongs to
+func UnsubscribeDigest(token string) error {
+	result, err := DB.Exec("UPDATE users SET digest_frequency = ? WHERE unsubscribe_token = ?", models.DigestOff, token)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrInvalidUnsubscribeToken
+	}
+	return nil
+}
@@ -4,12 +4,46 @@ import "errors"
 
 // Database operation errors
 var (
-	ErrUserAlreadyExists   = errors.New("user already exists")
-	ErrInvalidCredentials  = errors.New("invalid credentials")
-	ErrUserNotFound        = errors.New("user not found")
-	ErrPostNotFound        = errors.New("post not found")
-	ErrCommentNotFound     = errors.New("comment not found")
-	ErrMessageNotFound     = errors.New("message not found")
-	ErrSessionNotFound     = errors.New("session not found")
-	ErrSessionExpired      = errors.New("session expired")
+	ErrUserAlreadyExists       = errors.New("user already exists")
+	ErrInvalidCredentials      = errors.New("invalid credentials")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrPostNotFound            = errors.New("post not found")
+	ErrCommentNotFound         = errors.New("comment not found")
+	ErrMessageNotFound         = errors.New("message not found")
+	ErrSessionNotFound         = errors.New("session not found")
+	ErrSessionExpired          = errors.New("session expired")
+	ErrConversationNotFound    = errors.New("conversation not found")
+	ErrNotParticipant          = errors.New("not a participant of this conversation")
+	ErrNotGroupConversation    = errors.New("operation only allowed on group conversations")
+	ErrNotMessageSender        = errors.New("only the sender can change this message")
+	ErrEditWindowExpired       = errors.New("message can no longer be edited")
+	ErrMessageDeleted          = errors.New("message has been deleted")
+	ErrBlocked                 = errors.New("you cannot message this user")
+	ErrMessagesNotAccepted     = errors.New("this user does not accept messages from you")
+	ErrRecipientMismatch       = errors.New("recipient is not the other participant of this conversation")
+	ErrNoMessageRequest        = errors.New("no pending message request for this conversation")
+	ErrAttachmentNotFound      = errors.New("attachment not found")
+	ErrAttachmentUnavailable   = errors.New("attachment is not an unused upload of yours")
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe link")
+	ErrBookmarkFolderNotFound  = errors.New("bookmark folder not found")
+	ErrBookmarkFolderExists    = errors.New("you already have a folder with this name")
+	ErrTagNotFound             = errors.New("tag not found")
+	ErrTagSynonymNotFound      = errors.New("tag synonym not found")
+	ErrCategoryNotFound        = errors.New("category not found")
+	ErrCategoryExists          = errors.New("a category with this name already exists")
+	ErrCategoryArchived        = errors.New("this category is archived")
+	ErrInvalidCategoryParent   = errors.New("a category can only be nested under a top-level category, and only if it has no subcategories")
+	ErrCategoryForbidden       = errors.New("you do not have permission to do this in this category")
+	ErrGroupNotFound           = errors.New("group not found")
+	ErrGroupExists             = errors.New("a group with this name already exists")
+	ErrPostLocked              = errors.New("this post is locked: no new comments can be added")
+	ErrPostArchived            = errors.New("this post is archived and read-only")
+	ErrReportNotFound          = errors.New("report not found")
+	ErrReportExists            = errors.New("you have already reported this")
+	ErrCannotReportSelf        = errors.New("you cannot report yourself or your own content")
+	ErrReportAssigneeNotStaff  = errors.New("reports can only be assigned to moderators")
+	ErrDuplicateOpenReport     = errors.New("the reporter already has another open report of this")
+	ErrCannotBanUser           = errors.New("you can only ban or unban users below your role")
+	ErrUserNotBanned           = errors.New("this user is not banned")
+	ErrTooManyReactionEmojis   = errors.New("this already has as many different reactions as allowed")
 )
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+)
+
+// FollowUser adds a user to followerID's follow list
+func FollowUser(followerID, followedID int) error {
+	return restrictUser("INSERT OR IGNORE INTO user_follows (follower_id, followed_id) VALUES (?, ?)", followerID, followedID)
+}
+
+// UnfollowUser removes a user from followerID's follow list
+func UnfollowUser(followerID, followedID int) error {
+	_, err := DB.Exec("DELETE FROM user_follows WHERE follower_id = ? AND followed_id = ?", followerID, followedID)
+	return err
+}
+
+// IsFollowing reports whether followerID follows followedID
+func IsFollowing(followerID, followedID int) (bool, error) {
+	var following bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_follows WHERE follower_id = ? AND followed_id = ?)
+	`, followerID, followedID).Scan(&following)
+	return following, err
+}
+
+// GetFollowing retrieves the users userID follows, most recent first
+func GetFollowing(userID int) ([]models.RestrictedUser, error) {
+	return getRestrictedUsers(`
+		SELECT u.id, u.nickname, u.avatar_color, u.avatar_key, f.created_at
+		FROM user_follows f
+		JOIN users u ON u.id = f.followed_id
+		WHERE f.follower_id = ?
+		ORDER BY f.created_at DESC
+	`, userID)
+}
+
+// GetFollowers retrieves the users following userID, most recent first
+func GetFollowers(userID int) ([]models.RestrictedUser, error) {
+	return getRestrictedUsers(`
+		SELECT u.id, u.nickname, u.avatar_color, u.avatar_key, f.created_at
+		FROM user_follows f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.followed_id = ?
+		ORDER BY f.created_at DESC
+	`, userID)
+}
+
+// incomingStatus decides how a new conversation from senderID reaches
+// recipientID under the recipient's privacy setting: accepted straight into
+// their inbox, pending as a message request, or refused
+func incomingStatus(recipientID, senderID int) (string, error) {
+	var privacy string
+	err := DB.QueryRow("SELECT dm_privacy FROM users WHERE id = ?", recipientID).Scan(&privacy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+
+	switch privacy {
+	case models.DMPrivacyNobody:
+		return "", ErrMessagesNotAccepted
+	case models.DMPrivacyFollowing:
+		following, err := IsFollowing(recipientID, senderID)
+		if err != nil {
+			return "", err
+		}
+		if !following {
+			return models.ParticipantPending, nil
+		}
+	}
+	return models.ParticipantAccepted, nil
+}
+
+// AcceptsMessagesFrom reports whether messages from senderID go straight to
+// recipientID's inbox, either through an accepted one-to-one conversation
+// or because the recipient's privacy setting lets the sender in
+func AcceptsMessagesFrom(recipientID, senderID int) (bool, error) {
+	var status string
+	err := DB.QueryRow(`
+		SELECT p.status FROM conversations c
+		JOIN conversation_participants p ON p.conversation_id = c.id AND p.user_id = ?
+		WHERE c.direct_key = ?
+	`, recipientID, directKey(recipientID, senderID)).Scan(&status)
+	if err == sql.ErrNoRows {
+		status, err = incomingStatus(recipientID, senderID)
+		if err == ErrMessagesNotAccepted {
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return status == models.ParticipantAccepted, nil
+}
+
+// GetParticipantStatuses retrieves the status of each active participant of a conversation
+func GetParticipantStatuses(conversationID int) (map[int]string, error) {
+	rows, err := DB.Query(`
+		SELECT user_id, status FROM conversation_participants
+		WHERE conversation_id = ? AND left_at IS NULL
+	`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var status string
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, err
+		}
+		statuses[id] = status
+	}
+	return statuses, rows.Err()
+}
+
+// RespondToMessageRequest accepts or declines a pending conversation.
+// Declining a group conversation also leaves it.
+func RespondToMessageRequest(conversationID, userID int, accept bool) error {
+	query := `UPDATE conversation_participants SET status = 'accepted'
+		WHERE conversation_id = ? AND user_id = ? AND status = 'pending' AND left_at IS NULL`
+	if !accept {
+		query = `UPDATE conversation_participants SET status = 'declined',
+			left_at = CASE WHEN (SELECT is_group FROM conversations WHERE id = conversation_id) THEN CURRENT_TIMESTAMP END
+		WHERE conversation_id = ? AND user_id = ? AND status = 'pending' AND left_at IS NULL`
+	}
+
+	result, err := DB.Exec(query, conversationID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNoMessageRequest
+	}
+	return nil
+}
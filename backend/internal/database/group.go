@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+	"strings"
+)
+
+// GetUserGroups lists the user groups by name with their member counts
+func GetUserGroups() ([]models.UserGroup, error) {
+	rows, err := DB.Query(`
+		SELECT g.id, g.name, g.created_at, (SELECT COUNT(*) FROM user_group_members WHERE group_id = g.id)
+		FROM user_groups g
+		ORDER BY g.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.UserGroup{}
+	for rows.Next() {
+		var g models.UserGroup
+		if err := rows.Scan(&g.ID, &g.Name, &g.CreatedAt, &g.MemberCount); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// CreateUserGroup creates an empty user group
+func CreateUserGroup(name string) (*models.UserGroup, error) {
+	group := models.UserGroup{Name: name}
+	err := DB.QueryRow(`
+		INSERT INTO user_groups (name) VALUES (?)
+		RETURNING id, created_at
+	`, name).Scan(&group.ID, &group.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrGroupExists
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// RenameUserGroup renames a user group
+func RenameUserGroup(groupID int, name string) (*models.UserGroup, error) {
+	group := models.UserGroup{ID: groupID, Name: name}
+	err := DB.QueryRow(`
+		UPDATE user_groups SET name = ? WHERE id = ?
+		RETURNING created_at, (SELECT COUNT(*) FROM user_group_members WHERE group_id = ?)
+	`, name, groupID, groupID).Scan(&group.CreatedAt, &group.MemberCount)
+	if err == sql.ErrNoRows {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrGroupExists
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// DeleteUserGroup deletes a user group along with its memberships and the
+// category permissions granted to it
+func DeleteUserGroup(groupID int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM user_groups WHERE id = ?", groupID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrGroupNotFound
+	}
+
+	for _, table := range []string{"user_group_members", "category_permissions"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE group_id = ?", groupID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetGroupMembers lists the members of a user group by nickname
+func GetGroupMembers(groupID int) ([]models.UserSummary, error) {
+	var exists bool
+	if err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM user_groups WHERE id = ?)", groupID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	rows, err := DB.Query(`
+		SELECT u.id, u.nickname, u.avatar_color, u.avatar_key
+		FROM user_group_members m JOIN users u ON u.id = m.user_id
+		WHERE m.group_id = ?
+		ORDER BY u.nickname
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.UserSummary{}
+	for rows.Next() {
+		var user models.UserSummary
+		var avatarKey string
+		if err := rows.Scan(&user.ID, &user.Nickname, &user.AvatarColor, &avatarKey); err != nil {
+			return nil, err
+		}
+		user.AvatarURL = models.AvatarURL(user.ID, avatarKey)
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// AddGroupMember adds a user to a user group
+func AddGroupMember(groupID, userID int) error {
+	var groupExists, userExists bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_groups WHERE id = ?), EXISTS(SELECT 1 FROM users WHERE id = ?)
+	`, groupID, userID).Scan(&groupExists, &userExists)
+	if err != nil {
+		return err
+	}
+	if !groupExists {
+		return ErrGroupNotFound
+	}
+	if !userExists {
+		return ErrUserNotFound
+	}
+
+	_, err = DB.Exec("INSERT OR IGNORE INTO user_group_members (group_id, user_id) VALUES (?, ?)", groupID, userID)
+	return err
+}
+
+// RemoveGroupMember removes a user from a user group
+func RemoveGroupMember(groupID, userID int) error {
+	_, err := DB.Exec("DELETE FROM user_group_members WHERE group_id = ? AND user_id = ?", groupID, userID)
+	return err
+}
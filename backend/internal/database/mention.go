@@ -0,0 +1,261 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/markdown"
+	"real-time-forum/backend/internal/models"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExcerptLength caps the text shown with a mention, in characters
+const maxExcerptLength = 140
+
+// mentionColumns selects a mention along with its author and the post or
+// conversation it belongs to; mentionJoins must follow FROM mentions m
+const mentionColumns = `m.id, m.user_id, m.target_type, m.target_id, m.author_id, u.nickname, u.avatar_key, m.created_at, ` + targetColumns
+
+// mentionJoins joins what mentionColumns needs
+var mentionJoins = targetJoins("m", "author_id")
+
+// targetColumns selects the post or conversation a post, comment or message
+// target belongs to and its text; it needs the joins of targetJoins
+const targetColumns = `
+	CASE WHEN p.id IS NOT NULL THEN p.id WHEN c.id IS NOT NULL THEN c.post_id ELSE 0 END,
+	COALESCE(msg.conversation_id, 0),
+	COALESCE(p.title, c.content, msg.content, '')`
+
+// targetJoins joins the user in the userColumn of the table aliased alias as
+// u, and the post, comment or message named by its target_type and target_id
+// as p, c and msg
+func targetJoins(alias, userColumn string) string {
+	return `
+	JOIN users u ON u.id = ` + alias + `.` + userColumn + `
+	LEFT JOIN posts p ON ` + alias + `.target_type = 'post' AND p.id = ` + alias + `.target_id
+	LEFT JOIN comments c ON ` + alias + `.target_type = 'comment' AND c.id = ` + alias + `.target_id
+	LEFT JOIN messages msg ON ` + alias + `.target_type = 'message' AND msg.id = ` + alias + `.target_id AND msg.deleted_at IS NULL`
+}
+
+// targetExists keeps rows whose post, comment or message still exists
+const targetExists = `(p.id IS NOT NULL OR c.id IS NOT NULL OR msg.id IS NOT NULL)`
+
+// scanMention scans a row selected with mentionColumns
+func scanMention(row interface{ Scan(...interface{}) error }) (*models.Mention, error) {
+	var m models.Mention
+	var avatarKey, excerpt string
+	err := row.Scan(&m.ID, &m.UserID, &m.TargetType, &m.TargetID, &m.AuthorID, &m.AuthorName, &avatarKey, &m.CreatedAt,
+		&m.PostID, &m.ConversationID, &excerpt)
+	if err != nil {
+		return nil, err
+	}
+	m.AuthorAvatar = models.AvatarURL(m.AuthorID, avatarKey)
+	m.Excerpt = truncateText(excerpt, maxExcerptLength)
+	return &m, nil
+}
+
+// truncateText shortens text to at most max characters, marking the cut
+func truncateText(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// ResolveMentions looks up the users behind mentioned names, keyed by
+// lowercased nickname. Names match regardless of case, preferring a user
+// whose nickname matches exactly. Only the first MaxMentions names count.
+func ResolveMentions(names []string) (map[string]int, error) {
+	users := make(map[string]int)
+	if len(names) > models.MaxMentions {
+		names = names[:models.MaxMentions]
+	}
+	if len(names) == 0 {
+		return users, nil
+	}
+
+	exact := make(map[string]bool)
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		exact[name] = true
+		args[i] = strings.ToLower(name)
+	}
+
+	rows, err := DB.Query(`
+		SELECT id, nickname FROM users
+		WHERE LOWER(nickname) IN (`+strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")+`)
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var nickname string
+		if err := rows.Scan(&id, &nickname); err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(nickname)
+		if _, taken := users[key]; !taken || exact[nickname] {
+			users[key] = id
+		}
+	}
+	return users, rows.Err()
+}
+
+// renderWithMentions renders Markdown content, linking the users it
+// mentions, and returns their IDs
+func renderWithMentions(content string) (string, []int, error) {
+	mentioned, err := ResolveMentions(markdown.FindMentions(content))
+	if err != nil {
+		return "", nil, err
+	}
+	userIDs := make([]int, 0, len(mentioned))
+	for _, id := range mentioned {
+		userIDs = append(userIDs, id)
+	}
+	return markdown.Render(content, mentioned), userIDs, nil
+}
+
+// recordMentions stores the users authorID mentioned in a post, comment or
+// message. The author is skipped, as are users on either side of a block
+// with them and users who muted them.
+func recordMentions(tx *sql.Tx, authorID int, targetType string, targetID int, userIDs []int) error {
+	for _, userID := range uniqueIDs(userIDs) {
+		if userID == authorID {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO mentions (user_id, author_id, target_type, target_id)
+			SELECT ?, ?, ?, ?
+			WHERE ? NOT IN (`+hiddenAuthorsQuery+`)
+				AND NOT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?)
+		`, userID, authorID, targetType, targetID, authorID, userID, userID, authorID, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordMessageMentions stores the mentions in a group message. Only
+// members of the conversation who can read it are mentioned.
+func RecordMessageMentions(message *models.Message) error {
+	var isGroup bool
+	err := DB.QueryRow("SELECT is_group FROM conversations WHERE id = ?", message.ConversationID).Scan(&isGroup)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrConversationNotFound
+		}
+		return err
+	}
+	if !isGroup {
+		return nil
+	}
+
+	mentioned, err := ResolveMentions(markdown.FindMentions(message.Content))
+	if err != nil || len(mentioned) == 0 {
+		return err
+	}
+	statuses, err := GetParticipantStatuses(message.ConversationID)
+	if err != nil {
+		return err
+	}
+
+	var userIDs []int
+	for _, id := range mentioned {
+		if statuses[id] == models.ParticipantAccepted {
+			userIDs = append(userIDs, id)
+		}
+	}
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := recordMentions(tx, message.SenderID, models.MentionTargetMessage, message.ID, userIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTargetMentions retrieves the mentions stored for a post, comment or message
+func GetTargetMentions(targetType string, targetID int) ([]models.Mention, error) {
+	return queryMentions(`
+		SELECT `+mentionColumns+` FROM mentions m `+mentionJoins+`
+		WHERE m.target_type = ? AND m.target_id = ? AND `+targetExists+`
+		ORDER BY m.id
+	`, targetType, targetID)
+}
+
+// GetMentions retrieves the mentions of userID, newest first, 20 at a time.
+// Mentions by users they have since blocked or muted, and mentions in posts
+// in categories they may not view, are left out.
+func GetMentions(userID, offset int) ([]models.Mention, error) {
+	visible, visibleArgs, err := categoryFilter(userID, models.CategoryActionView,
+		"(SELECT category_id FROM posts WHERE id = COALESCE(p.id, c.post_id))")
+	if err != nil {
+		return nil, err
+	}
+	args := append([]interface{}{userID, userID, userID}, visibleArgs...)
+	return queryMentions(`
+		SELECT `+mentionColumns+` FROM mentions m `+mentionJoins+`
+		WHERE m.user_id = ? AND `+targetExists+`
+			AND m.author_id NOT IN (`+hiddenAuthorsQuery+`)
+			AND (msg.id IS NOT NULL OR `+visible+`)
+		ORDER BY m.id DESC
+		LIMIT 20 OFFSET ?
+	`, append(args, offset)...)
+}
+
+// queryMentions runs a mention query
+func queryMentions(query string, args ...interface{}) ([]models.Mention, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []models.Mention{}
+	for rows.Next() {
+		m, err := scanMention(rows)
+		if err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, *m)
+	}
+	return mentions, rows.Err()
+}
+
+// SearchUsers finds up to limit users whose nickname starts with prefix,
+// leaving out the viewer and users on either side of a block with them
+func SearchUsers(viewerID int, prefix string, limit int) ([]models.UserSummary, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+	rows, err := DB.Query(`
+		SELECT id, nickname, avatar_color, avatar_key FROM users
+		WHERE nickname LIKE ? ESCAPE '\' AND id != ?
+			AND id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = ?)
+			AND id NOT IN (SELECT blocker_id FROM user_blocks WHERE blocked_id = ?)
+		ORDER BY LENGTH(nickname), nickname
+		LIMIT ?
+	`, escaped+"%", viewerID, viewerID, viewerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.UserSummary{}
+	for rows.Next() {
+		var user models.UserSummary
+		var avatarKey string
+		if err := rows.Scan(&user.ID, &user.Nickname, &user.AvatarColor, &avatarKey); err != nil {
+			return nil, err
+		}
+		user.AvatarURL = models.AvatarURL(user.ID, avatarKey)
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
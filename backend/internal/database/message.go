@@ -1,15 +1,93 @@
 package database
 
 import (
+	"database/sql"
 	"real-time-forum/backend/internal/models"
+	"time"
 )
 
-// CreateMessage creates a new message in the database
-func CreateMessage(message *models.Message) error {
-	result, err := DB.Exec(`
-		INSERT INTO messages (sender_id, recipient_id, content, is_read, created_at)
+// CreateMessage creates a new message in a conversation with the given
+// attachments. When only RecipientID is set, the one-to-one conversation with
+// the recipient is used, and it is created on first contact.
+func CreateMessage(message *models.Message, attachmentIDs []int) error {
+	if message.ConversationID == 0 {
+		conversationID, err := GetOrCreateDirectConversation(message.SenderID, message.RecipientID)
+		if err != nil {
+			return err
+		}
+		message.ConversationID = conversationID
+	}
+
+	var isGroup bool
+	err := DB.QueryRow("SELECT is_group FROM conversations WHERE id = ?", message.ConversationID).Scan(&isGroup)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrConversationNotFound
+		}
+		return err
+	}
+
+	statuses, err := GetParticipantStatuses(message.ConversationID)
+	if err != nil {
+		return err
+	}
+	senderStatus, ok := statuses[message.SenderID]
+	if !ok {
+		return ErrNotParticipant
+	}
+
+	// One-to-one messages keep their recipient for older clients
+	var recipientID interface{}
+	if isGroup {
+		message.RecipientID = 0
+	} else {
+		// The recipient is always the other participant; a recipient sent
+		// along with the conversation must match it
+		otherID, err := directRecipient(message.ConversationID, message.SenderID)
+		if err != nil {
+			return err
+		}
+		if message.RecipientID != 0 && message.RecipientID != otherID {
+			return ErrRecipientMismatch
+		}
+		message.RecipientID = otherID
+		recipientID = otherID
+
+		blocked, err := IsBlockedEitherWay(message.SenderID, message.RecipientID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrBlocked
+		}
+		if statuses[message.RecipientID] == models.ParticipantDeclined {
+			return ErrMessagesNotAccepted
+		}
+		if err := checkNobodyPrivacy(message.ConversationID, message.RecipientID, message.SenderID); err != nil {
+			return err
+		}
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Replying to a message request accepts it
+	if senderStatus != models.ParticipantAccepted {
+		if _, err := tx.Exec(`
+			UPDATE conversation_participants SET status = 'accepted'
+			WHERE conversation_id = ? AND user_id = ?
+		`, message.ConversationID, message.SenderID); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO messages (conversation_id, sender_id, recipient_id, content, created_at)
 		VALUES (?, ?, ?, ?, ?)
-	`, message.SenderID, message.RecipientID, message.Content, message.IsRead, message.CreatedAt)
+	`, message.ConversationID, message.SenderID, recipientID, message.Content, message.CreatedAt)
 	if err != nil {
 		return err
 	}
@@ -18,48 +96,322 @@ func CreateMessage(message *models.Message) error {
 	if err != nil {
 		return err
 	}
+	if err := attachFiles(tx, message.SenderID, models.AttachmentTargetMessage, int(messageID), attachmentIDs); err != nil {
+		return err
+	}
+
+	// Bump the conversation and count the message as read by its sender
+	if _, err := tx.Exec("UPDATE conversations SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", message.ConversationID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE conversation_participants SET last_read_message_id = MAX(last_read_message_id, ?)
+		WHERE conversation_id = ? AND user_id = ?
+	`, messageID, message.ConversationID, message.SenderID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 	message.ID = int(messageID)
 
-	// Populate Sender and Recipient names
-	sender, err := GetUserByID(message.SenderID)
+	attachments, err := getAttachments(models.AttachmentTargetMessage, []int{message.ID})
 	if err != nil {
 		return err
 	}
-	recipient, err := GetUserByID(message.RecipientID)
+	message.Attachments = attachments[message.ID]
+
+	// Populate Sender and Recipient names
+	sender, err := GetUserByID(message.SenderID)
 	if err != nil {
 		return err
 	}
 	message.SenderName = sender.Nickname
-	message.RecipientName = recipient.Nickname
+	if message.RecipientID != 0 {
+		recipient, err := GetUserByID(message.RecipientID)
+		if err != nil {
+			return err
+		}
+		message.RecipientName = recipient.Nickname
+	}
 
 	return nil
 }
 
+// checkNobodyPrivacy refuses messages to a recipient who accepts messages
+// from nobody, in conversations that already exist too, unless they have
+// written in the conversation themselves
+func checkNobodyPrivacy(conversationID, recipientID, senderID int) error {
+	if _, err := incomingStatus(recipientID, senderID); err != ErrMessagesNotAccepted {
+		return err
+	}
+	var wrote bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM messages WHERE conversation_id = ? AND sender_id = ?)
+	`, conversationID, recipientID).Scan(&wrote)
+	if err != nil {
+		return err
+	}
+	if !wrote {
+		return ErrMessagesNotAccepted
+	}
+	return nil
+}
+
+// directRecipient returns the participant of a one-to-one conversation
+// other than senderID
+func directRecipient(conversationID, senderID int) (int, error) {
+	ids, err := GetConversationParticipantIDs(conversationID)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if id != senderID {
+			return id, nil
+		}
+	}
+	return 0, ErrConversationNotFound
+}
+
 // GetMessages retrieves messages between two users
 func GetMessages(userID, otherUserID, offset int) ([]models.Message, error) {
+	conversationID, err := FindDirectConversation(userID, otherUserID)
+	if err != nil || conversationID == 0 {
+		return nil, err
+	}
+	return GetConversationMessages(conversationID, userID, offset)
+}
+
+// GetConversationMessages retrieves a page of messages in a conversation,
+// newest first, with read state as seen by viewerID. Messages the viewer
+// deleted for themselves, that come from users they blocked or that were sent
+// before they joined are left out.
+func GetConversationMessages(conversationID, viewerID, offset int) ([]models.Message, error) {
 	var messages []models.Message
 	rows, err := DB.Query(`
-		SELECT m.id, m.sender_id, m.recipient_id, m.content, m.is_read, m.created_at,
-			s.nickname AS sender, r.nickname AS recipient
+		SELECT `+messageColumns+`
 		FROM messages m
+		JOIN conversation_participants p ON p.conversation_id = m.conversation_id AND p.user_id = ? AND p.left_at IS NULL
 		JOIN users s ON m.sender_id = s.id
-		JOIN users r ON m.recipient_id = r.id
-		WHERE (m.sender_id = ? AND m.recipient_id = ?) OR (m.sender_id = ? AND m.recipient_id = ?)
-		ORDER BY m.created_at DESC
+		LEFT JOIN users r ON m.recipient_id = r.id
+		WHERE m.conversation_id = ?
+			AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = p.user_id)
+			AND `+notFromBlockedSender+`
+			AND `+sinceJoined+`
+		ORDER BY m.id DESC
 		LIMIT 20 OFFSET ?
-	`, userID, otherUserID, otherUserID, userID, offset)
+	`, viewerID, conversationID, offset)
 	if err != nil {
 		return messages, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var msg models.Message
-		if err := rows.Scan(&msg.ID, &msg.SenderID, &msg.RecipientID, &msg.Content, &msg.IsRead, &msg.CreatedAt, &msg.SenderName, &msg.RecipientName); err != nil {
+		msg, err := scanMessage(rows)
+		if err != nil {
 			return messages, err
 		}
-		messages = append(messages, msg)
+		messages = append(messages, *msg)
+	}
+	if err := rows.Err(); err != nil {
+		return messages, err
+	}
+
+	ids := make([]int, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	reactions, err := getReactionCounts(models.ReactionTargetMessage, ids, viewerID)
+	if err != nil {
+		return messages, err
+	}
+	attachments, err := getAttachments(models.AttachmentTargetMessage, ids)
+	if err != nil {
+		return messages, err
+	}
+	for i := range messages {
+		messages[i].Reactions = reactions[messages[i].ID]
+		if !messages[i].IsDeleted {
+			messages[i].Attachments = attachments[messages[i].ID]
+		}
 	}
 
 	return messages, nil
-}
\ No newline at end of file
+}
+
+// messageColumns selects a message joined with its participant row p, sender
+// s and recipient r, in the order scanMessage expects
+const messageColumns = `m.id, m.conversation_id, m.sender_id, COALESCE(m.recipient_id, 0), m.content,
+			m.id <= p.last_read_message_id, m.edited_at, m.deleted_at IS NOT NULL, m.created_at,
+			s.nickname AS sender, COALESCE(r.nickname, '') AS recipient`
+
+// notFromBlockedSender excludes messages from users the participant p blocked
+const notFromBlockedSender = `m.sender_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = p.user_id)`
+
+// sinceJoined excludes messages sent before the participant p joined
+const sinceJoined = `m.id > p.history_from_message_id`
+
+// scanMessage scans a row selected with messageColumns
+func scanMessage(row interface{ Scan(...interface{}) error }) (*models.Message, error) {
+	var msg models.Message
+	if err := row.Scan(&msg.ID, &msg.ConversationID, &msg.SenderID, &msg.RecipientID, &msg.Content,
+		&msg.IsRead, &msg.EditedAt, &msg.IsDeleted, &msg.CreatedAt, &msg.SenderName, &msg.RecipientName); err != nil {
+		return nil, err
+	}
+	msg.IsEdited = msg.EditedAt != nil
+	return &msg, nil
+}
+
+// GetMessage retrieves a single message as seen by viewerID, who must be an
+// active participant of its conversation
+func GetMessage(messageID, viewerID int) (*models.Message, error) {
+	msg, err := scanMessage(DB.QueryRow(`
+		SELECT `+messageColumns+`
+		FROM messages m
+		JOIN conversation_participants p ON p.conversation_id = m.conversation_id AND p.user_id = ? AND p.left_at IS NULL
+		JOIN users s ON m.sender_id = s.id
+		LEFT JOIN users r ON m.recipient_id = r.id
+		WHERE m.id = ?
+			AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = p.user_id)
+			AND `+notFromBlockedSender+`
+			AND `+sinceJoined+`
+	`, viewerID, messageID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// EditMessage replaces the content of a message sent by userID, keeping the
+// previous content as a revision. Messages older than editWindow can no
+// longer be edited; a zero window allows edits at any time.
+func EditMessage(messageID, userID int, content string, editWindow time.Duration) (*models.Message, error) {
+	msg, err := GetMessage(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.SenderID != userID {
+		return nil, ErrNotMessageSender
+	}
+	if msg.IsDeleted {
+		return nil, ErrMessageDeleted
+	}
+	if editWindow > 0 && time.Since(msg.CreatedAt) > editWindow {
+		return nil, ErrEditWindowExpired
+	}
+	if msg.Content == content {
+		return msg, nil
+	}
+
+	// The revision keeps the time the replaced content was written
+	revisedAt := msg.CreatedAt
+	if msg.EditedAt != nil {
+		revisedAt = *msg.EditedAt
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO message_revisions (message_id, content, created_at) VALUES (?, ?, ?)
+	`, messageID, msg.Content, revisedAt); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE messages SET content = ?, edited_at = ? WHERE id = ?
+	`, content, time.Now(), messageID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetMessage(messageID, userID)
+}
+
+// HideMessage deletes a message for userID only
+func HideMessage(messageID, userID int) (*models.Message, error) {
+	msg, err := GetMessage(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = DB.Exec(`
+		INSERT OR IGNORE INTO message_hidden (message_id, user_id) VALUES (?, ?)
+	`, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// DeleteMessageForEveryone replaces a message sent by userID with a deleted
+// marker for all participants and drops its revision history
+func DeleteMessageForEveryone(messageID, userID int) (*models.Message, error) {
+	msg, err := GetMessage(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.SenderID != userID {
+		return nil, ErrNotMessageSender
+	}
+	if msg.IsDeleted {
+		return msg, nil
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM message_revisions WHERE message_id = ?", messageID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE messages SET content = '', deleted_at = ? WHERE id = ?
+	`, time.Now(), messageID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetMessage(messageID, userID)
+}
+
+// GetMessageRevisions retrieves the earlier versions of a message, oldest
+// first, for a participant who can see it
+func GetMessageRevisions(messageID, viewerID int) ([]models.MessageRevision, error) {
+	if _, err := GetMessage(messageID, viewerID); err != nil {
+		return nil, err
+	}
+
+	rows, err := DB.Query(`
+		SELECT id, message_id, content, created_at
+		FROM message_revisions
+		WHERE message_id = ?
+		ORDER BY id ASC
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.MessageRevision{}
+	for rows.Next() {
+		var revision models.MessageRevision
+		if err := rows.Scan(&revision.ID, &revision.MessageID, &revision.Content, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"fmt"
+	"real-time-forum/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestCreateMessageTakesRecipientFromConversation(t *testing.T) {
+	alice := newTestUser(t, models.RoleUser)
+	bob := newTestUser(t, models.RoleUser)
+	carol := newTestUser(t, models.RoleUser)
+	if err := BlockUser(carol, alice); err != nil {
+		t.Fatal(err)
+	}
+
+	first := &models.Message{SenderID: alice, RecipientID: bob, Content: "hi", CreatedAt: time.Now()}
+	if err := CreateMessage(first, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		recipientID int
+		wantErr     error
+	}{
+		{"no recipient", 0, nil},
+		{"matching recipient", bob, nil},
+		{"someone else", carol, ErrRecipientMismatch},
+		{"sender", alice, ErrRecipientMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &models.Message{
+				ConversationID: first.ConversationID,
+				SenderID:       alice,
+				RecipientID:    tt.recipientID,
+				Content:        "spoofed",
+				CreatedAt:      time.Now(),
+			}
+			err := CreateMessage(msg, nil)
+			if err != tt.wantErr {
+				t.Fatalf("CreateMessage() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && msg.RecipientID != bob {
+				t.Errorf("RecipientID = %d, want %d", msg.RecipientID, bob)
+			}
+		})
+	}
+
+	var stored int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM messages WHERE recipient_id = ?", carol).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 {
+		t.Errorf("%d messages stored for a recipient outside the conversation", stored)
+	}
+}
+
+func TestCreateMessageHonorsNobodyInExistingConversations(t *testing.T) {
+	alice := newTestUser(t, models.RoleUser)
+	bob := newTestUser(t, models.RoleUser)
+	send := func(from, to int) error {
+		return CreateMessage(&models.Message{SenderID: from, RecipientID: to, Content: "hi", CreatedAt: time.Now()}, nil)
+	}
+
+	if err := send(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DB.Exec("UPDATE users SET dm_privacy = ? WHERE id = ?", models.DMPrivacyNobody, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(alice, bob); err != ErrMessagesNotAccepted {
+		t.Errorf("message to a user who accepts none = %v, want %v", err, ErrMessagesNotAccepted)
+	}
+
+	// Once bob writes in the conversation, alice can answer
+	if err := send(bob, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(alice, bob); err != nil {
+		t.Errorf("reply in a conversation bob wrote in = %v, want no error", err)
+	}
+}
+
+func TestGroupHistoryStartsWhenMembersJoin(t *testing.T) {
+	alice := newTestUser(t, models.RoleUser)
+	bob := newTestUser(t, models.RoleUser)
+	carol := newTestUser(t, models.RoleUser)
+
+	group, err := CreateConversation(alice, "group", []int{bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(content string) {
+		t.Helper()
+		msg := &models.Message{ConversationID: group.ID, SenderID: alice, Content: content, CreatedAt: time.Now()}
+		if err := CreateMessage(msg, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history := func(viewerID int) []string {
+		t.Helper()
+		messages, err := GetConversationMessages(group.ID, viewerID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var contents []string
+		for i := len(messages) - 1; i >= 0; i-- {
+			contents = append(contents, messages[i].Content)
+		}
+		return contents
+	}
+	unread := func(viewerID int) int {
+		t.Helper()
+		c, err := GetConversation(group.ID, viewerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.UnreadCount
+	}
+
+	send("before carol")
+	if err := AddConversationParticipants(group.ID, alice, []int{carol}); err != nil {
+		t.Fatal(err)
+	}
+	if got := history(carol); len(got) != 0 {
+		t.Errorf("new member sees %q, want no earlier messages", got)
+	}
+	if got := unread(carol); got != 0 {
+		t.Errorf("new member has %d unread messages, want 0", got)
+	}
+
+	send("with carol")
+	if err := LeaveConversation(group.ID, carol); err != nil {
+		t.Fatal(err)
+	}
+	send("after carol left")
+	if err := AddConversationParticipants(group.ID, alice, []int{carol}); err != nil {
+		t.Fatal(err)
+	}
+	send("carol is back")
+
+	if got := fmt.Sprint(history(carol)); got != "[carol is back]" {
+		t.Errorf("re-added member sees %s, want only messages since re-joining", got)
+	}
+	if got := unread(carol); got != 1 {
+		t.Errorf("re-added member has %d unread messages, want 1", got)
+	}
+	if got := len(history(bob)); got != 4 {
+		t.Errorf("founding member sees %d messages, want all 4", got)
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+	"strings"
+)
+
+// GetPostState retrieves the moderation state of a post
+func GetPostState(postID int) (*models.PostState, error) {
+	state := models.PostState{PostID: postID}
+	err := DB.QueryRow(`
+		SELECT category_id, COALESCE(pin_scope, ''), locked_at IS NOT NULL, archived_at IS NOT NULL
+		FROM posts WHERE id = ?
+	`, postID).Scan(&state.CategoryID, &state.Pinned, &state.Locked, &state.Archived)
+	if err == sql.ErrNoRows {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// UpdatePostState pins, unpins, locks, unlocks, archives or restores a post
+// and returns its new state. Pinning a pinned post again moves it to the
+// top of the pins.
+func UpdatePostState(postID int, req models.PostStateRequest) (*models.PostState, error) {
+	var sets []string
+	var args []interface{}
+	if req.Pinned != nil {
+		if *req.Pinned == "" {
+			sets = append(sets, "pin_scope = NULL", "pinned_at = NULL")
+		} else {
+			sets = append(sets, "pin_scope = ?", "pinned_at = CURRENT_TIMESTAMP")
+			args = append(args, *req.Pinned)
+		}
+	}
+	if req.Locked != nil {
+		if *req.Locked {
+			sets = append(sets, "locked_at = COALESCE(locked_at, CURRENT_TIMESTAMP)")
+		} else {
+			sets = append(sets, "locked_at = NULL")
+		}
+	}
+	if req.Archived != nil {
+		if *req.Archived {
+			sets = append(sets, "archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP)")
+		} else {
+			sets = append(sets, "archived_at = NULL")
+		}
+	}
+
+	args = append(args, postID)
+	result, err := DB.Exec("UPDATE posts SET "+strings.Join(sets, ", ")+" WHERE id = ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrPostNotFound
+	}
+	return GetPostState(postID)
+}
+
+// checkPostOpen makes sure a post can still change: archived posts are
+// read-only, and locked posts take no new comments when comments is set
+func checkPostOpen(postID int, comments bool) error {
+	state, err := GetPostState(postID)
+	if err != nil {
+		return err
+	}
+	if state.Archived {
+		return ErrPostArchived
+	}
+	if comments && state.Locked {
+		return ErrPostLocked
+	}
+	return nil
+}
+
+// CheckPostWritable makes sure a post is not archived, so it can still be
+// voted and reacted on
+func CheckPostWritable(postID int) error {
+	return checkPostOpen(postID, false)
+}
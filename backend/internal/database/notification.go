@@ -0,0 +1,267 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+)
+
+// notificationColumns selects a notification along with its actor and the
+// post or conversation it belongs to; notificationJoins must follow
+// FROM notifications n
+const notificationColumns = `n.id, n.user_id, n.type, n.actor_id, u.nickname, u.avatar_key, n.target_type, n.target_id,
+	n.detail, n.read_at IS NOT NULL, n.created_at, ` + targetColumns
+
+// notificationJoins joins what notificationColumns needs
+var notificationJoins = targetJoins("n", "actor_id")
+
+// visibleNotifications keeps the notifications of the user given twice whose
+// target still exists and whose actor they have not since blocked or muted
+const visibleNotifications = `n.user_id = ? AND ` + targetExists + `
+	AND n.actor_id NOT IN (` + hiddenAuthorsQuery + `)`
+
+// scanNotification scans a row selected with notificationColumns
+func scanNotification(row interface{ Scan(...interface{}) error }) (*models.Notification, error) {
+	var n models.Notification
+	var avatarKey, excerpt string
+	err := row.Scan(&n.ID, &n.UserID, &n.Type, &n.ActorID, &n.ActorName, &avatarKey, &n.TargetType, &n.TargetID,
+		&n.Detail, &n.Read, &n.CreatedAt, &n.PostID, &n.ConversationID, &excerpt)
+	if err != nil {
+		return nil, err
+	}
+	n.ActorAvatar = models.AvatarURL(n.ActorID, avatarKey)
+	n.Excerpt = truncateText(excerpt, maxExcerptLength)
+	return &n, nil
+}
+
+// CreateNotification stores a notification for userID about something
+// actorID did, unless the user turned that type off, it is their own doing,
+// or either of them blocked the other or the user muted the actor. An unread
+// notification about the same thing is brought back to the top instead of
+// adding another: a new reaction by the same user to the same target, or a
+// new direct message in the same conversation. It returns nil when nothing
+// was stored.
+func CreateNotification(userID int, notificationType string, actorID int, targetType string, targetID int, detail string) (*models.Notification, error) {
+	if userID == 0 || userID == actorID {
+		return nil, nil
+	}
+
+	var wanted bool
+	err := DB.QueryRow(`
+		SELECT COALESCE((SELECT enabled FROM notification_preferences WHERE user_id = ? AND type = ?), 1)
+			AND ? NOT IN (`+hiddenAuthorsQuery+`)
+			AND NOT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?)
+	`, userID, notificationType, actorID, userID, userID, actorID, userID).Scan(&wanted)
+	if err != nil || !wanted {
+		return nil, err
+	}
+
+	var id int
+	if notificationType == models.NotificationDirectMessage {
+		err = DB.QueryRow(`
+			UPDATE notifications SET target_id = ?, created_at = CURRENT_TIMESTAMP
+			WHERE id = (
+				SELECT n.id FROM notifications n
+				JOIN messages m ON m.id = n.target_id
+				WHERE n.user_id = ? AND n.type = ? AND n.actor_id = ? AND n.read_at IS NULL
+					AND m.conversation_id = (SELECT conversation_id FROM messages WHERE id = ?)
+				LIMIT 1
+			)
+			RETURNING id
+		`, targetID, userID, notificationType, actorID, targetID).Scan(&id)
+	} else {
+		err = DB.QueryRow(`
+			UPDATE notifications SET detail = ?, created_at = CURRENT_TIMESTAMP
+			WHERE user_id = ? AND type = ? AND actor_id = ? AND target_type = ? AND target_id = ? AND read_at IS NULL
+			RETURNING id
+		`, detail, userID, notificationType, actorID, targetType, targetID).Scan(&id)
+	}
+	if err == sql.ErrNoRows {
+		var result sql.Result
+		result, err = DB.Exec(`
+			INSERT INTO notifications (user_id, type, actor_id, target_type, target_id, detail)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, userID, notificationType, actorID, targetType, targetID, detail)
+		if err != nil {
+			return nil, err
+		}
+		var lastID int64
+		lastID, err = result.LastInsertId()
+		id = int(lastID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return scanNotification(DB.QueryRow(`
+		SELECT `+notificationColumns+` FROM notifications n `+notificationJoins+`
+		WHERE n.id = ?
+	`, id))
+}
+
+// GetNotifications retrieves a user's notifications, newest first, 20 at a time
+func GetNotifications(userID, offset int) ([]models.Notification, error) {
+	rows, err := DB.Query(`
+		SELECT `+notificationColumns+` FROM notifications n `+notificationJoins+`
+		WHERE `+visibleNotifications+`
+		ORDER BY n.created_at DESC, n.id DESC
+		LIMIT 20 OFFSET ?
+	`, userID, userID, userID, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, *n)
+	}
+	return notifications, rows.Err()
+}
+
+// GetUnreadNotificationCount counts the unread notifications a user can see
+func GetUnreadNotificationCount(userID int) (int, error) {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM notifications n `+notificationJoins+`
+		WHERE `+visibleNotifications+` AND n.read_at IS NULL
+	`, userID, userID, userID).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationsRead marks some of a user's notifications as read,
+// ignoring IDs that are not theirs. It returns how many were unread.
+func MarkNotificationsRead(userID int, ids []int) (int, error) {
+	placeholders, args := idArgs(uniqueIDs(ids))
+	result, err := DB.Exec(`
+		UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND read_at IS NULL AND id IN (`+placeholders+`)
+	`, append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// MarkAllNotificationsRead marks all of a user's notifications as read and
+// returns how many were unread
+func MarkAllNotificationsRead(userID int) (int, error) {
+	result, err := DB.Exec(`
+		UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND read_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// MarkConversationNotificationsRead marks a user's direct message
+// notifications for a conversation as read up to a message, once they have
+// read it there. It returns how many were unread.
+func MarkConversationNotificationsRead(userID, conversationID, messageID int) (int, error) {
+	result, err := DB.Exec(`
+		UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND type = ? AND read_at IS NULL AND target_id IN (
+			SELECT id FROM messages WHERE conversation_id = ? AND id <= ?
+		)
+	`, userID, models.NotificationDirectMessage, conversationID, messageID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// GetNotificationPreferences retrieves whether each notification type is on
+// for a user; types they never changed are on
+func GetNotificationPreferences(userID int) (models.NotificationPreferences, error) {
+	prefs := make(models.NotificationPreferences, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		prefs[t] = true
+	}
+
+	rows, err := DB.Query("SELECT type, enabled FROM notification_preferences WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t string
+		var enabled bool
+		if err := rows.Scan(&t, &enabled); err != nil {
+			return nil, err
+		}
+		if _, known := prefs[t]; known {
+			prefs[t] = enabled
+		}
+	}
+	return prefs, rows.Err()
+}
+
+// SetNotificationPreferences turns notification types on or off for a user,
+// leaving types not given unchanged
+func SetNotificationPreferences(userID int, prefs models.NotificationPreferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for t, enabled := range prefs {
+		_, err := tx.Exec(`
+			INSERT INTO notification_preferences (user_id, type, enabled) VALUES (?, ?, ?)
+			ON CONFLICT (user_id, type) DO UPDATE SET enabled = excluded.enabled
+		`, userID, t, enabled)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetTargetAuthorID returns the author of a post or comment or the sender
+// of a message
+func GetTargetAuthorID(targetType string, targetID int) (int, error) {
+	var query string
+	var notFound error
+	switch targetType {
+	case models.ReactionTargetPost:
+		query, notFound = "SELECT user_id FROM posts WHERE id = ?", ErrPostNotFound
+	case models.ReactionTargetComment:
+		query, notFound = "SELECT user_id FROM comments WHERE id = ?", ErrCommentNotFound
+	case models.ReactionTargetMessage:
+		query, notFound = "SELECT sender_id FROM messages WHERE id = ?", ErrMessageNotFound
+	default:
+		return 0, models.ErrInvalidReactionTarget
+	}
+
+	var authorID int
+	err := DB.QueryRow(query, targetID).Scan(&authorID)
+	if err == sql.ErrNoRows {
+		return 0, notFound
+	}
+	return authorID, err
+}
+
+// GetDirectMessageRecipients returns who a message in a one-to-one
+// conversation is for: the other participant, unless they declined it.
+// Messages in groups have no direct recipients.
+func GetDirectMessageRecipients(message *models.Message) ([]int, error) {
+	return queryIDs(`
+		SELECT cp.user_id FROM conversation_participants cp
+		JOIN conversations c ON c.id = cp.conversation_id
+		WHERE cp.conversation_id = ? AND c.is_group = 0 AND cp.user_id != ?
+			AND cp.left_at IS NULL AND cp.status != ?
+	`, message.ConversationID, message.SenderID, models.ParticipantDeclined)
+}
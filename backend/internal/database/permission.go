@@ -0,0 +1,281 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+	"sort"
+)
+
+// categoryAccess holds everything that decides where one user may view,
+// post and comment: their role and groups, and every category's parent and
+// permission rules
+type categoryAccess struct {
+	role    string
+	groups  map[int]bool
+	parents map[int]int
+	rules   map[int]map[string][]models.CategoryPermission
+}
+
+// loadCategoryAccess loads what decides where userID may act; a userID of
+// 0 stands for someone not logged in, who has no role or groups
+func loadCategoryAccess(userID int) (*categoryAccess, error) {
+	a := &categoryAccess{
+		groups:  make(map[int]bool),
+		parents: make(map[int]int),
+		rules:   make(map[int]map[string][]models.CategoryPermission),
+	}
+
+	if userID > 0 {
+		role, err := GetUserRole(userID)
+		if err != nil && err != ErrUserNotFound {
+			return nil, err
+		}
+		a.role = role
+		groupIDs, err := queryIDs("SELECT group_id FROM user_group_members WHERE user_id = ?", userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range groupIDs {
+			a.groups[id] = true
+		}
+	}
+
+	rows, err := DB.Query("SELECT id, COALESCE(parent_id, 0) FROM categories")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, parentID int
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return nil, err
+		}
+		a.parents[id] = parentID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rules, err := DB.Query("SELECT category_id, action, COALESCE(role, ''), COALESCE(group_id, 0) FROM category_permissions")
+	if err != nil {
+		return nil, err
+	}
+	defer rules.Close()
+	for rules.Next() {
+		var categoryID int
+		var p models.CategoryPermission
+		if err := rules.Scan(&categoryID, &p.Action, &p.Role, &p.GroupID); err != nil {
+			return nil, err
+		}
+		if a.rules[categoryID] == nil {
+			a.rules[categoryID] = make(map[string][]models.CategoryPermission)
+		}
+		a.rules[categoryID][p.Action] = append(a.rules[categoryID][p.Action], p)
+	}
+	return a, rules.Err()
+}
+
+// grants reports whether the rules for an action in a category, or in its
+// parent when it has none of its own, let the user take it. No rules at
+// all let everyone take it.
+func (a *categoryAccess) grants(categoryID int, action string) bool {
+	rules := a.rules[categoryID][action]
+	if len(rules) == 0 && a.parents[categoryID] != 0 {
+		rules = a.rules[a.parents[categoryID]][action]
+	}
+	if len(rules) == 0 {
+		return true
+	}
+	for _, p := range rules {
+		if p.Role != "" && models.RoleAtLeast(a.role, p.Role) {
+			return true
+		}
+		if p.GroupID != 0 && a.groups[p.GroupID] {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether the user may take an action in a category.
+// Posting and commenting need the category to be visible too, and a
+// subcategory is only visible when its parent is. Admins may do anything.
+func (a *categoryAccess) allows(categoryID int, action string) bool {
+	parentID, ok := a.parents[categoryID]
+	if !ok {
+		return false
+	}
+	if a.role == models.RoleAdmin {
+		return true
+	}
+	if !a.grants(categoryID, models.CategoryActionView) {
+		return false
+	}
+	if parentID != 0 && !a.grants(parentID, models.CategoryActionView) {
+		return false
+	}
+	return action == models.CategoryActionView || a.grants(categoryID, action)
+}
+
+// AllowedCategoryIDs returns the categories in which userID may take an
+// action, in ID order
+func AllowedCategoryIDs(userID int, action string) ([]int, error) {
+	a, err := loadCategoryAccess(userID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for id := range a.parents {
+		if a.allows(id, action) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// CanAccessCategory reports whether userID may take an action in a category
+func CanAccessCategory(userID, categoryID int, action string) (bool, error) {
+	a, err := loadCategoryAccess(userID)
+	if err != nil {
+		return false, err
+	}
+	return a.allows(categoryID, action), nil
+}
+
+// checkCategoryAccess makes sure userID may take an action in a category.
+// Categories the user cannot view are reported as not found.
+func checkCategoryAccess(userID, categoryID int, action string) error {
+	a, err := loadCategoryAccess(userID)
+	if err != nil {
+		return err
+	}
+	if !a.allows(categoryID, models.CategoryActionView) {
+		return ErrCategoryNotFound
+	}
+	if !a.allows(categoryID, action) {
+		return ErrCategoryForbidden
+	}
+	return nil
+}
+
+// CheckPostAccess makes sure userID may take an action on a post. Posts in
+// categories the user cannot view are reported as not found, so their
+// existence does not leak.
+func CheckPostAccess(userID, postID int, action string) error {
+	var categoryID int
+	err := DB.QueryRow("SELECT category_id FROM posts WHERE id = ?", postID).Scan(&categoryID)
+	if err == sql.ErrNoRows {
+		return ErrPostNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	a, err := loadCategoryAccess(userID)
+	if err != nil {
+		return err
+	}
+	if !a.allows(categoryID, models.CategoryActionView) {
+		return ErrPostNotFound
+	}
+	if !a.allows(categoryID, action) {
+		return ErrCategoryForbidden
+	}
+	return nil
+}
+
+// categoryFilter returns a condition keeping rows whose category, in the
+// given column, userID may take an action in
+func categoryFilter(userID int, action, column string) (string, []interface{}, error) {
+	ids, err := AllowedCategoryIDs(userID, action)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(ids) == 0 {
+		return "0", nil, nil
+	}
+	placeholders, args := idArgs(ids)
+	return column + " IN (" + placeholders + ")", args, nil
+}
+
+// GetCategoryPermissions retrieves the permission rules of a category
+func GetCategoryPermissions(categoryID int) ([]models.CategoryPermission, error) {
+	if _, err := GetCategory(categoryID); err != nil {
+		return nil, err
+	}
+
+	rows, err := DB.Query(`
+		SELECT cp.action, COALESCE(cp.role, ''), COALESCE(cp.group_id, 0), COALESCE(g.name, '')
+		FROM category_permissions cp
+		LEFT JOIN user_groups g ON g.id = cp.group_id
+		WHERE cp.category_id = ?
+		ORDER BY cp.action, cp.role IS NULL, cp.role, g.name
+	`, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []models.CategoryPermission{}
+	for rows.Next() {
+		var p models.CategoryPermission
+		if err := rows.Scan(&p.Action, &p.Role, &p.GroupID, &p.GroupName); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+// SetCategoryPermissions replaces the permission rules of a category
+func SetCategoryPermissions(categoryID int, permissions []models.CategoryPermission) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE id = ?)", categoryID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCategoryNotFound
+	}
+
+	var groupIDs []int
+	for _, p := range permissions {
+		if p.GroupID != 0 {
+			groupIDs = append(groupIDs, p.GroupID)
+		}
+	}
+	if groupIDs = uniqueIDs(groupIDs); len(groupIDs) > 0 {
+		placeholders, args := idArgs(groupIDs)
+		var found int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM user_groups WHERE id IN ("+placeholders+")", args...).Scan(&found); err != nil {
+			return err
+		}
+		if found != len(groupIDs) {
+			return ErrGroupNotFound
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM category_permissions WHERE category_id = ?", categoryID); err != nil {
+		return err
+	}
+	for _, p := range permissions {
+		var role, groupID interface{}
+		if p.Role != "" {
+			role = p.Role
+		} else {
+			groupID = p.GroupID
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO category_permissions (category_id, action, role, group_id) VALUES (?, ?, ?, ?)
+		`, categoryID, p.Action, role, groupID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"fmt"
+	"real-time-forum/backend/internal/models"
+	"testing"
+)
+
+func TestCategoryAccessAllows(t *testing.T) {
+	const (
+		open          = 1 // no rules
+		staffOnly     = 2 // view: moderator
+		staffChild    = 3 // under staffOnly, no rules of its own
+		openChild     = 4 // under open, post: group 7
+		groupOnly     = 5 // view: group 7
+		modPosting    = 6 // post: moderator
+		staffChildOwn = 7 // under staffOnly, view: user
+		missing       = 99
+	)
+	rules := map[int]map[string][]models.CategoryPermission{
+		staffOnly:     {models.CategoryActionView: {{Role: models.RoleModerator}}},
+		openChild:     {models.CategoryActionPost: {{GroupID: 7}}},
+		groupOnly:     {models.CategoryActionView: {{GroupID: 7}}},
+		modPosting:    {models.CategoryActionPost: {{Role: models.RoleModerator}}},
+		staffChildOwn: {models.CategoryActionView: {{Role: models.RoleUser}}},
+	}
+	parents := map[int]int{
+		open: 0, staffOnly: 0, staffChild: staffOnly, openChild: open,
+		groupOnly: 0, modPosting: 0, staffChildOwn: staffOnly,
+	}
+	access := func(role string, groups ...int) *categoryAccess {
+		a := &categoryAccess{role: role, groups: make(map[int]bool), parents: parents, rules: rules}
+		for _, id := range groups {
+			a.groups[id] = true
+		}
+		return a
+	}
+
+	tests := []struct {
+		name       string
+		access     *categoryAccess
+		categoryID int
+		action     string
+		want       bool
+	}{
+		{"no rules let everyone view", access(models.RoleUser), open, models.CategoryActionView, true},
+		{"no rules let everyone post", access(models.RoleUser), open, models.CategoryActionPost, true},
+		{"no rules let anonymous users view", access(""), open, models.CategoryActionView, true},
+		{"role rule keeps out lower roles", access(models.RoleUser), staffOnly, models.CategoryActionView, false},
+		{"role rule lets the role in", access(models.RoleModerator), staffOnly, models.CategoryActionView, true},
+		{"role rule lets higher roles in", access(models.RoleAdmin), staffOnly, models.CategoryActionView, true},
+		{"role rule keeps out anonymous users", access(""), staffOnly, models.CategoryActionView, false},
+		{"subcategory inherits parent rules", access(models.RoleUser), staffChild, models.CategoryActionView, false},
+		{"subcategory inherits for the allowed role", access(models.RoleModerator), staffChild, models.CategoryActionView, true},
+		{"hidden parent hides subcategory with own rules", access(models.RoleUser), staffChildOwn, models.CategoryActionView, false},
+		{"visible parent shows subcategory with own rules", access(models.RoleModerator), staffChildOwn, models.CategoryActionView, true},
+		{"group rule keeps out non-members", access(models.RoleModerator), groupOnly, models.CategoryActionView, false},
+		{"group rule lets members in", access(models.RoleUser, 7), groupOnly, models.CategoryActionView, true},
+		{"posting rule keeps out non-members", access(models.RoleUser), openChild, models.CategoryActionPost, false},
+		{"posting rule lets members post", access(models.RoleUser, 7), openChild, models.CategoryActionPost, true},
+		{"posting rule leaves viewing open", access(models.RoleUser), openChild, models.CategoryActionView, true},
+		{"posting rule leaves commenting open", access(models.RoleUser), modPosting, models.CategoryActionComment, true},
+		{"posting rule keeps out users", access(models.RoleUser), modPosting, models.CategoryActionPost, false},
+		{"posting needs viewing", access(models.RoleUser), staffOnly, models.CategoryActionPost, false},
+		{"commenting needs viewing", access(models.RoleUser), staffChild, models.CategoryActionComment, false},
+		{"admins override view rules", access(models.RoleAdmin), groupOnly, models.CategoryActionView, true},
+		{"admins override post rules", access(models.RoleAdmin), openChild, models.CategoryActionPost, true},
+		{"missing category is never allowed", access(models.RoleAdmin), missing, models.CategoryActionView, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.access.allows(tt.categoryID, tt.action); got != tt.want {
+				t.Errorf("allows(%d, %q) = %v, want %v", tt.categoryID, tt.action, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryAccessGrants(t *testing.T) {
+	a := &categoryAccess{
+		role:    models.RoleUser,
+		groups:  map[int]bool{},
+		parents: map[int]int{1: 0, 2: 1},
+		rules: map[int]map[string][]models.CategoryPermission{
+			1: {models.CategoryActionView: {{Role: models.RoleModerator}}},
+			2: {models.CategoryActionPost: {{Role: models.RoleUser}}},
+		},
+	}
+
+	tests := []struct {
+		categoryID int
+		action     string
+		want       bool
+	}{
+		{1, models.CategoryActionView, false},
+		{1, models.CategoryActionPost, true},
+		// A subcategory with rules for another action still inherits this one
+		{2, models.CategoryActionView, false},
+		{2, models.CategoryActionPost, true},
+		{2, models.CategoryActionComment, true},
+	}
+	for _, tt := range tests {
+		if got := a.grants(tt.categoryID, tt.action); got != tt.want {
+			t.Errorf("grants(%d, %q) = %v, want %v", tt.categoryID, tt.action, got, tt.want)
+		}
+	}
+}
+
+var testCategoryCount int
+
+// newTestCategory creates a category whose view permission is limited to role
+func newTestCategory(t *testing.T, viewRole string) int {
+	t.Helper()
+	testCategoryCount++
+	var id int
+	err := DB.QueryRow("INSERT INTO categories (name) VALUES (?) RETURNING id",
+		fmt.Sprintf("test category %d", testCategoryCount)).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if viewRole != "" {
+		permissions := []models.CategoryPermission{{Action: models.CategoryActionView, Role: viewRole}}
+		if err := SetCategoryPermissions(id, permissions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return id
+}
+
+func TestRestrictedContentIsHidden(t *testing.T) {
+	moderator := newTestUser(t, models.RoleModerator)
+	user := newTestUser(t, models.RoleUser)
+	categoryID := newTestCategory(t, models.RoleModerator)
+
+	post := &models.Post{UserID: moderator, CategoryID: categoryID, Title: "Staff only", Content: "secret"}
+	if err := CreatePost(post, nil); err != nil {
+		t.Fatal(err)
+	}
+	comment := &models.Comment{PostID: post.ID, UserID: moderator, Content: "also secret"}
+	if err := CreateComment(comment, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var attachments []*models.Attachment
+	for _, target := range []struct {
+		targetType string
+		targetID   int
+	}{{models.AttachmentTargetPost, post.ID}, {models.AttachmentTargetComment, comment.ID}} {
+		a := &models.Attachment{UploaderID: moderator, Filename: "notes.txt", ContentType: "text/plain",
+			StorageKey: fmt.Sprintf("%s-%d", target.targetType, target.targetID)}
+		if err := CreateAttachment(a); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DB.Exec("UPDATE attachments SET target_type = ?, target_id = ? WHERE id = ?",
+			target.targetType, target.targetID, a.ID); err != nil {
+			t.Fatal(err)
+		}
+		a.TargetType, a.TargetID = target.targetType, target.targetID
+		attachments = append(attachments, a)
+	}
+
+	tests := []struct {
+		name    string
+		viewer  int
+		visible bool
+	}{
+		{"moderator", moderator, true},
+		{"user", user, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, err := GetPosts(models.PostListOptions{CategoryID: categoryID}, tt.viewer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(posts) == 1; got != tt.visible {
+				t.Errorf("GetPosts returned %d posts, want visible = %v", len(posts), tt.visible)
+			}
+
+			posts, err = GetPosts(models.PostListOptions{}, tt.viewer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			listed := false
+			for _, p := range posts {
+				listed = listed || p.ID == post.ID
+			}
+			if listed != tt.visible {
+				t.Errorf("post listed on the front page = %v, want %v", listed, tt.visible)
+			}
+
+			comments, err := GetComments(post.ID, tt.viewer)
+			if tt.visible {
+				if err != nil || len(comments) != 1 {
+					t.Errorf("GetComments = %d comments, %v; want 1 comment", len(comments), err)
+				}
+			} else if err != ErrPostNotFound || len(comments) != 0 {
+				t.Errorf("GetComments = %d comments, %v; want %v", len(comments), err, ErrPostNotFound)
+			}
+
+			for _, a := range attachments {
+				allowed, err := CanViewAttachment(a, tt.viewer)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if allowed != tt.visible {
+					t.Errorf("CanViewAttachment(%s) = %v, want %v", a.TargetType, allowed, tt.visible)
+				}
+			}
+		})
+	}
+}
@@ -1,16 +1,41 @@
 package database
 
 import (
+	"database/sql"
+	"real-time-forum/backend/internal/markdown"
 	"real-time-forum/backend/internal/models"
+	"strings"
 	"time"
 )
 
-// CreatePost creates a new post in the database
-func CreatePost(post *models.Post) error {
-	result, err := DB.Exec(`
-		INSERT INTO posts (user_id, title, content, category_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, post.UserID, post.Title, post.Content, post.CategoryID, time.Now().Format("2006-01-02 15:04:05"), time.Now().Format("2006-01-02 15:04:05"))
+// CreatePost creates a new post in the database, together with its
+// mentions, tags, attachments and the author's watch, all in one transaction
+func CreatePost(post *models.Post, attachmentIDs []int) error {
+	if err := checkCategoryAccess(post.UserID, post.CategoryID, models.CategoryActionPost); err != nil {
+		return err
+	}
+
+	var mentioned []int
+	var err error
+	post.ContentHTML, mentioned, err = renderWithMentions(post.Content)
+	if err != nil {
+		return err
+	}
+	tags, err := resolveTags(post.Tags)
+	if err != nil {
+		return err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO posts (user_id, title, content, content_html, category_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, post.UserID, post.Title, post.Content, post.ContentHTML, post.CategoryID, time.Now().Format("2006-01-02 15:04:05"), time.Now().Format("2006-01-02 15:04:05"))
 	if err != nil {
 		return err
 	}
@@ -19,48 +44,120 @@ func CreatePost(post *models.Post) error {
 	if err != nil {
 		return err
 	}
-	post.ID = int(postID)
+
+	if err := recordMentions(tx, post.UserID, models.MentionTargetPost, int(postID), mentioned); err != nil {
+		return err
+	}
+	if err := autoWatch(tx, post.UserID, int(postID), "auto_watch_posts"); err != nil {
+		return err
+	}
+	if err := setPostTags(tx, int(postID), tags); err != nil {
+		return err
+	}
+	if err := attachFiles(tx, post.UserID, models.AttachmentTargetPost, int(postID), attachmentIDs); err != nil {
+		return err
+	}
 
 	// Get category name
 	var categoryName string
-	err = DB.QueryRow("SELECT name FROM categories WHERE id = ?", post.CategoryID).Scan(&categoryName)
+	err = tx.QueryRow("SELECT name FROM categories WHERE id = ?", post.CategoryID).Scan(&categoryName)
 	if err != nil {
 		return err
 	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	post.ID = int(postID)
+	post.Tags = tags
 	post.CategoryName = categoryName
 
+	attachments, err := getAttachments(models.AttachmentTargetPost, []int{post.ID})
+	if err != nil {
+		return err
+	}
+	post.Attachments = attachments[post.ID]
 	return nil
 }
 
-// GetPosts retrieves posts, optionally filtered by category_id
-func GetPosts(categoryID int) ([]models.Post, error) {
+// GetPosts retrieves posts, optionally filtered by category, by tags or to
+// the posts viewerID watches, in the order given by opts after pinned posts,
+// models.PostsPerPage at a time from opts.Offset, leaving out archived posts
+// unless asked for them, with tags, scores, votes, watch and bookmark state
+// and reaction counts as seen by viewerID.
+// Posts by users the viewer blocked or muted, and posts in categories they
+// may not view, are left out.
+func GetPosts(opts models.PostListOptions, viewerID int) ([]models.Post, error) {
 	var posts []models.Post
-	var query string
-	var args []interface{}
-
-	if categoryID != 0 {
-		query = `
-			SELECT p.id, p.user_id, p.title, p.content, p.category_id, c.name, p.created_at, p.updated_at, u.nickname, u.avatar_color,
-				(SELECT COUNT(*) FROM comments WHERE post_id = p.id) as comment_count
-			FROM posts p
-			JOIN users u ON p.user_id = u.id
-			JOIN categories c ON p.category_id = c.id
-			WHERE p.category_id = ?
-			ORDER BY p.created_at DESC
-		`
-		args = append(args, categoryID)
-	} else {
-		query = `
-			SELECT p.id, p.user_id, p.title, p.content, p.category_id, c.name, p.created_at, p.updated_at, u.nickname, u.avatar_color,
-				(SELECT COUNT(*) FROM comments WHERE post_id = p.id) as comment_count
-			FROM posts p
-			JOIN users u ON p.user_id = u.id
-			JOIN categories c ON p.category_id = c.id
-			ORDER BY p.created_at DESC
-		`
-	}
-
-	rows, err := DB.Query(query, args...)
+	conditions := []string{"p.user_id NOT IN (" + hiddenAuthorsQuery + ")"}
+	args := []interface{}{viewerID, viewerID, viewerID, viewerID, viewerID}
+
+	visible, visibleArgs, err := categoryFilter(viewerID, models.CategoryActionView, "p.category_id")
+	if err != nil {
+		return posts, err
+	}
+	conditions = append(conditions, visible)
+	args = append(args, visibleArgs...)
+
+	if opts.PostID != 0 {
+		conditions = append(conditions, "p.id = ?")
+		args = append(args, opts.PostID)
+	} else if !opts.IncludeArchived {
+		conditions = append(conditions, "p.archived_at IS NULL")
+	}
+	if opts.CategoryID != 0 {
+		// A category's posts include those in its subcategories
+		conditions = append(conditions, "p.category_id IN (SELECT id FROM categories WHERE id = ? OR parent_id = ?)")
+		args = append(args, opts.CategoryID, opts.CategoryID)
+	}
+	if opts.Watching {
+		conditions = append(conditions, "w.watching = 1")
+	}
+	if len(opts.Tags) > 0 {
+		condition, tagArgs, err := tagFilter(opts.Tags, opts.TagMatch)
+		if err != nil {
+			return posts, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, tagArgs...)
+	}
+	if opts.Sort == models.SortTop {
+		if d := models.TopRanges[opts.Range]; d > 0 {
+			conditions = append(conditions, "p.created_at >= ?")
+			args = append(args, time.Now().Add(-d).Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	where := "WHERE " + strings.Join(conditions, " AND ")
+	// Pinned posts come first, newest pin first: global pins in any listing
+	// they appear in, and category pins when listing a category
+	pinOrder := "pin_rank DESC, CASE WHEN pin_rank > 0 THEN p.pinned_at END DESC, "
+	orderBy := pinOrder + "p.created_at DESC"
+	switch opts.Sort {
+	case models.SortTop:
+		orderBy = pinOrder + "p.score DESC, p.created_at DESC"
+	case models.SortHot:
+		orderBy = pinOrder + "hot_rank(p.score, unixepoch(p.created_at)) DESC, p.created_at DESC"
+	}
+
+	query := `
+		SELECT p.id, p.user_id, p.title, p.content, p.content_html, p.category_id, c.name, p.created_at, p.updated_at, u.nickname, u.avatar_color, u.avatar_key,
+			(SELECT COUNT(*) FROM comments WHERE post_id = p.id) as comment_count,
+			p.score, COALESCE(v.value, 0), COALESCE(w.watching, 0), b.id IS NOT NULL,
+			COALESCE(p.pin_scope, ''), p.locked_at IS NOT NULL, p.archived_at IS NOT NULL,
+			CASE WHEN p.pin_scope = 'global' THEN 2 WHEN p.pin_scope = 'category' AND ? THEN 1 ELSE 0 END AS pin_rank
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		JOIN categories c ON p.category_id = c.id
+		LEFT JOIN votes v ON v.target_type = 'post' AND v.target_id = p.id AND v.user_id = ?
+		LEFT JOIN post_watches w ON w.post_id = p.id AND w.user_id = ?
+		LEFT JOIN bookmarks b ON b.target_type = 'post' AND b.target_id = p.id AND b.user_id = ?
+		` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT ? OFFSET ?`
+	args = append(args, models.PostsPerPage, opts.Offset)
+
+	rows, err := DB.Query(query, append([]interface{}{opts.CategoryID != 0}, args...)...)
 	if err != nil {
 		return posts, err
 	}
@@ -68,21 +165,87 @@ func GetPosts(categoryID int) ([]models.Post, error) {
 
 	for rows.Next() {
 		var post models.Post
-		if err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CategoryID, &post.CategoryName, &post.CreatedAt, &post.UpdatedAt, &post.Author, &post.AuthorColor, &post.ReplyCount); err != nil {
+		var avatarKey string
+		var pinRank int
+		if err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.ContentHTML, &post.CategoryID, &post.CategoryName, &post.CreatedAt, &post.UpdatedAt, &post.Author, &post.AuthorColor, &avatarKey, &post.ReplyCount, &post.Score, &post.UserVote, &post.Watching, &post.Bookmarked,
+			&post.Pinned, &post.Locked, &post.Archived, &pinRank); err != nil {
 			return posts, err
 		}
+		post.AuthorAvatar = models.AvatarURL(post.UserID, avatarKey)
 		posts = append(posts, post)
 	}
+	if err := rows.Err(); err != nil {
+		return posts, err
+	}
+
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	reactions, err := getReactionCounts(models.ReactionTargetPost, ids, viewerID)
+	if err != nil {
+		return posts, err
+	}
+	attachments, err := getAttachments(models.AttachmentTargetPost, ids)
+	if err != nil {
+		return posts, err
+	}
+	tags, err := getPostTags(ids)
+	if err != nil {
+		return posts, err
+	}
+	for i := range posts {
+		posts[i].Reactions = reactionsOrEmpty(reactions[posts[i].ID])
+		posts[i].Attachments = attachments[posts[i].ID]
+		posts[i].Tags = tags[posts[i].ID]
+		if posts[i].Tags == nil {
+			posts[i].Tags = []string{}
+		}
+	}
 
 	return posts, nil
 }
 
-// CreateComment creates a new comment in the database
-func CreateComment(comment *models.Comment) error {
-	result, err := DB.Exec(`
-		INSERT INTO comments (post_id, user_id, content, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
-	`, comment.PostID, comment.UserID, comment.Content, time.Now().Format("2006-01-02 15:04:05"), time.Now().Format("2006-01-02 15:04:05"))
+// CreateComment creates a new comment in the database, together with its
+// mentions, attachments and the author's watch, all in one transaction
+func CreateComment(comment *models.Comment, attachmentIDs []int) error {
+	if err := CheckPostAccess(comment.UserID, comment.PostID, models.CategoryActionComment); err != nil {
+		return err
+	}
+	if err := checkPostOpen(comment.PostID, true); err != nil {
+		return err
+	}
+
+	// A reply must be to a comment on the same post
+	var parentID interface{}
+	if comment.ParentID > 0 {
+		postID, err := GetCommentPostID(comment.ParentID)
+		if err != nil {
+			return err
+		}
+		if postID != comment.PostID {
+			return ErrCommentNotFound
+		}
+		parentID = comment.ParentID
+	}
+
+	var mentioned []int
+	var err error
+	comment.ContentHTML, mentioned, err = renderWithMentions(comment.Content)
+	if err != nil {
+		return err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO comments (post_id, parent_id, user_id, content, content_html, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, comment.PostID, parentID, comment.UserID, comment.Content, comment.ContentHTML, time.Now().Format("2006-01-02 15:04:05"), time.Now().Format("2006-01-02 15:04:05"))
 	if err != nil {
 		return err
 	}
@@ -91,21 +254,50 @@ func CreateComment(comment *models.Comment) error {
 	if err != nil {
 		return err
 	}
+
+	if err := autoWatch(tx, comment.UserID, comment.PostID, "auto_watch_comments"); err != nil {
+		return err
+	}
+	if err := recordMentions(tx, comment.UserID, models.MentionTargetComment, int(commentID), mentioned); err != nil {
+		return err
+	}
+	if err := attachFiles(tx, comment.UserID, models.AttachmentTargetComment, int(commentID), attachmentIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 	comment.ID = int(commentID)
 
+	attachments, err := getAttachments(models.AttachmentTargetComment, []int{comment.ID})
+	if err != nil {
+		return err
+	}
+	comment.Attachments = attachments[comment.ID]
 	return nil
 }
 
-// GetComments retrieves comments for a specific post
-func GetComments(postID int) ([]models.Comment, error) {
+// GetComments retrieves comments for a specific post, with votes and
+// reaction counts as seen by viewerID. Comments by users the viewer blocked
+// or muted are left out, and a post in a category the viewer may not view
+// is not found.
+func GetComments(postID, viewerID int) ([]models.Comment, error) {
 	var comments []models.Comment
+	if err := CheckPostAccess(viewerID, postID, models.CategoryActionView); err != nil {
+		return comments, err
+	}
+
 	rows, err := DB.Query(`
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, c.updated_at, u.nickname, u.avatar_color
+		SELECT c.id, c.post_id, COALESCE(c.parent_id, 0), c.user_id, c.content, c.content_html, c.created_at, c.updated_at, u.nickname, u.avatar_color, u.avatar_key,
+			c.score, COALESCE(v.value, 0), b.id IS NOT NULL
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
-		WHERE c.post_id = ?
+		LEFT JOIN votes v ON v.target_type = 'comment' AND v.target_id = c.id AND v.user_id = ?
+		LEFT JOIN bookmarks b ON b.target_type = 'comment' AND b.target_id = c.id AND b.user_id = ?
+		WHERE c.post_id = ? AND c.user_id NOT IN (`+hiddenAuthorsQuery+`)
 		ORDER BY c.created_at ASC
-	`, postID)
+	`, viewerID, viewerID, postID, viewerID, viewerID)
 
 	if err != nil {
 		return comments, err
@@ -114,11 +306,82 @@ func GetComments(postID int) ([]models.Comment, error) {
 
 	for rows.Next() {
 		var comment models.Comment
-		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt, &comment.Author, &comment.AuthorColor); err != nil {
+		var avatarKey string
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.UserID, &comment.Content, &comment.ContentHTML, &comment.CreatedAt, &comment.UpdatedAt, &comment.Author, &comment.AuthorColor, &avatarKey, &comment.Score, &comment.UserVote, &comment.Bookmarked); err != nil {
 			return comments, err
 		}
+		comment.AuthorAvatar = models.AvatarURL(comment.UserID, avatarKey)
 		comments = append(comments, comment)
 	}
+	if err := rows.Err(); err != nil {
+		return comments, err
+	}
+
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	reactions, err := getReactionCounts(models.ReactionTargetComment, ids, viewerID)
+	if err != nil {
+		return comments, err
+	}
+	attachments, err := getAttachments(models.AttachmentTargetComment, ids)
+	if err != nil {
+		return comments, err
+	}
+	for i := range comments {
+		comments[i].Reactions = reactionsOrEmpty(reactions[comments[i].ID])
+		comments[i].Attachments = attachments[comments[i].ID]
+	}
 
 	return comments, nil
-}
\ No newline at end of file
+}
+
+// renderMissingContent renders the Markdown of posts and comments stored
+// before their HTML was kept alongside them
+func renderMissingContent() error {
+	for _, table := range []string{"posts", "comments"} {
+		rows, err := DB.Query("SELECT id, content FROM " + table + " WHERE content_html = ''")
+		if err != nil {
+			return err
+		}
+		rendered := make(map[int]string)
+		for rows.Next() {
+			var id int
+			var content string
+			if err := rows.Scan(&id, &content); err != nil {
+				rows.Close()
+				return err
+			}
+			rendered[id] = markdown.Render(content, nil)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, html := range rendered {
+			if _, err := DB.Exec("UPDATE "+table+" SET content_html = ? WHERE id = ?", html, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PostExists reports whether a post with the given ID exists
+func PostExists(postID int) (bool, error) {
+	var exists bool
+	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM posts WHERE id = ?)", postID).Scan(&exists)
+	return exists, err
+}
+
+// GetCommentPostID returns the post a comment belongs to
+func GetCommentPostID(commentID int) (int, error) {
+	var postID int
+	err := DB.QueryRow("SELECT post_id FROM comments WHERE id = ?", commentID).Scan(&postID)
+	if err == sql.ErrNoRows {
+		return 0, ErrCommentNotFound
+	}
+	return postID, err
+}
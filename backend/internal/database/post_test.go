@@ -0,0 +1,147 @@
+package database
+
+import (
+	"fmt"
+	"real-time-forum/backend/internal/models"
+	"sort"
+	"testing"
+	"time"
+)
+
+// failInserts makes every insert into table fail until the test ends
+func failInserts(t *testing.T, table string) {
+	t.Helper()
+	if _, err := DB.Exec(`CREATE TRIGGER fail_` + table + ` BEFORE INSERT ON ` + table + `
+		BEGIN SELECT RAISE(ABORT, 'insert failed'); END`); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := DB.Exec("DROP TRIGGER fail_" + table); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func countRows(t *testing.T, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := DB.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCreatePostRollsBackOnFollowUpFailure(t *testing.T) {
+	author := newTestUser(t, models.RoleUser)
+	categoryID := newTestCategory(t, "")
+
+	for _, table := range []string{"post_tags", "post_watches"} {
+		t.Run(table, func(t *testing.T) {
+			failInserts(t, table)
+			post := &models.Post{UserID: author, CategoryID: categoryID, Title: "Atomic", Content: "hi", Tags: []string{"atomic"}}
+			if err := CreatePost(post, nil); err == nil {
+				t.Fatal("CreatePost() succeeded although storing its " + table + " failed")
+			}
+			if post.ID != 0 {
+				t.Errorf("post ID = %d after a failed create, want 0", post.ID)
+			}
+			if n := countRows(t, "SELECT COUNT(*) FROM posts WHERE user_id = ?", author); n != 0 {
+				t.Errorf("%d posts stored after a failed create, want 0", n)
+			}
+		})
+	}
+
+	post := &models.Post{UserID: author, CategoryID: categoryID, Title: "Atomic", Content: "hi", Tags: []string{"atomic"}}
+	if err := CreatePost(post, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, "SELECT COUNT(*) FROM post_tags WHERE post_id = ?", post.ID); n != 1 {
+		t.Errorf("%d tags stored, want 1", n)
+	}
+}
+
+func TestCreateCommentRollsBackOnFollowUpFailure(t *testing.T) {
+	author := newTestUser(t, models.RoleUser)
+	mentioned := newTestUser(t, models.RoleUser)
+	var nickname string
+	if err := DB.QueryRow("SELECT nickname FROM users WHERE id = ?", mentioned).Scan(&nickname); err != nil {
+		t.Fatal(err)
+	}
+	post := &models.Post{UserID: author, CategoryID: newTestCategory(t, ""), Title: "Comments", Content: "hi"}
+	if err := CreatePost(post, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	failInserts(t, "mentions")
+	comment := &models.Comment{PostID: post.ID, UserID: author, Content: "hey @" + nickname}
+	if err := CreateComment(comment, nil); err == nil {
+		t.Fatal("CreateComment() succeeded although storing its mention failed")
+	}
+	if n := countRows(t, "SELECT COUNT(*) FROM comments WHERE post_id = ?", post.ID); n != 0 {
+		t.Errorf("%d comments stored after a failed create, want 0", n)
+	}
+}
+
+func TestGetPostsHotPages(t *testing.T) {
+	author := newTestUser(t, models.RoleUser)
+	category := newTestCategory(t, "")
+	insert := func(score int, age time.Duration) int {
+		t.Helper()
+		var id int
+		err := DB.QueryRow(`
+			INSERT INTO posts (user_id, title, content, category_id, score, created_at)
+			VALUES (?, 'post', 'content', ?, ?, ?)
+			RETURNING id
+		`, author, category, score, time.Now().UTC().Add(-age).Format("2006-01-02 15:04:05")).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	// An old post with no votes stays on top while pinned
+	pinned := insert(0, 30*24*time.Hour)
+	if _, err := DB.Exec("UPDATE posts SET pin_scope = 'category', pinned_at = CURRENT_TIMESTAMP WHERE id = ?", pinned); err != nil {
+		t.Fatal(err)
+	}
+	type ranked struct {
+		id   int
+		rank float64
+	}
+	var unpinned []ranked
+	for i := 0; i < models.PostsPerPage+5; i++ {
+		score := (i*37)%200 - 50
+		age := time.Duration(i*7%48) * time.Hour
+		id := insert(score, age)
+		var createdAt time.Time
+		if err := DB.QueryRow("SELECT created_at FROM posts WHERE id = ?", id).Scan(&createdAt); err != nil {
+			t.Fatal(err)
+		}
+		unpinned = append(unpinned, ranked{id, hotRank(score, createdAt)})
+	}
+	sort.SliceStable(unpinned, func(i, j int) bool { return unpinned[i].rank > unpinned[j].rank })
+	want := []int{pinned}
+	for _, p := range unpinned {
+		want = append(want, p.id)
+	}
+
+	var got []int
+	for offset := 0; ; offset += models.PostsPerPage {
+		posts, err := GetPosts(models.PostListOptions{CategoryID: category, Sort: models.SortHot, Offset: offset}, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) > models.PostsPerPage {
+			t.Fatalf("page at offset %d has %d posts, want at most %d", offset, len(posts), models.PostsPerPage)
+		}
+		for _, post := range posts {
+			got = append(got, post.ID)
+		}
+		if len(posts) < models.PostsPerPage {
+			break
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("hot order = %v, want %v", got, want)
+	}
+}
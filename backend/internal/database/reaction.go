@@ -0,0 +1,108 @@
+package database
+
+import (
+	"real-time-forum/backend/internal/models"
+	"strings"
+)
+
+// AddReaction records a user's reaction to a target. It reports false when
+// the user had already reacted with that emoji. A target takes at most
+// models.MaxReactionEmojis different emoji.
+func AddReaction(userID int, targetType string, targetID int, emoji string) (bool, error) {
+	result, err := DB.Exec(`
+		INSERT OR IGNORE INTO reactions (user_id, target_type, target_id, emoji)
+		SELECT ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM reactions WHERE target_type = ? AND target_id = ? AND emoji = ?)
+			OR (SELECT COUNT(DISTINCT emoji) FROM reactions WHERE target_type = ? AND target_id = ?) < ?
+	`, userID, targetType, targetID, emoji, targetType, targetID, emoji, targetType, targetID, models.MaxReactionEmojis)
+	if err != nil {
+		return false, err
+	}
+	added, err := result.RowsAffected()
+	if err != nil || added > 0 {
+		return added > 0, err
+	}
+
+	// Nothing was added: either the reaction exists or the target is full
+	var exists bool
+	err = DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM reactions
+			WHERE user_id = ? AND target_type = ? AND target_id = ? AND emoji = ?)
+	`, userID, targetType, targetID, emoji).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, ErrTooManyReactionEmojis
+	}
+	return false, nil
+}
+
+// RemoveReaction removes a user's reaction from a target. It reports false
+// when there was nothing to remove.
+func RemoveReaction(userID int, targetType string, targetID int, emoji string) (bool, error) {
+	result, err := DB.Exec(`
+		DELETE FROM reactions
+		WHERE user_id = ? AND target_type = ? AND target_id = ? AND emoji = ?
+	`, userID, targetType, targetID, emoji)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+// GetReactionCounts retrieves the reaction counts of a single target as seen by viewerID
+func GetReactionCounts(targetType string, targetID, viewerID int) ([]models.ReactionCount, error) {
+	counts, err := getReactionCounts(targetType, []int{targetID}, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return reactionsOrEmpty(counts[targetID]), nil
+}
+
+// getReactionCounts retrieves the reaction counts of several targets of one
+// type, keyed by target ID, with emojis in the order they were first used
+func getReactionCounts(targetType string, targetIDs []int, viewerID int) (map[int][]models.ReactionCount, error) {
+	counts := make(map[int][]models.ReactionCount)
+	if len(targetIDs) == 0 {
+		return counts, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(targetIDs)), ",")
+	args := []interface{}{viewerID, targetType}
+	for _, id := range targetIDs {
+		args = append(args, id)
+	}
+
+	rows, err := DB.Query(`
+		SELECT target_id, emoji, COUNT(*), MAX(user_id = ?)
+		FROM reactions
+		WHERE target_type = ? AND target_id IN (`+placeholders+`)
+		GROUP BY target_id, emoji
+		ORDER BY target_id, MIN(id)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var targetID int
+		var count models.ReactionCount
+		if err := rows.Scan(&targetID, &count.Emoji, &count.Count, &count.Reacted); err != nil {
+			return nil, err
+		}
+		counts[targetID] = append(counts[targetID], count)
+	}
+	return counts, rows.Err()
+}
+
+// reactionsOrEmpty returns counts, or an empty list so targets without
+// reactions encode as [] rather than null
+func reactionsOrEmpty(counts []models.ReactionCount) []models.ReactionCount {
+	if counts == nil {
+		return []models.ReactionCount{}
+	}
+	return counts
+}
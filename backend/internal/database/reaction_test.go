@@ -0,0 +1,41 @@
+package database
+
+import (
+	"real-time-forum/backend/internal/models"
+	"testing"
+)
+
+func TestAddReactionCapsDistinctEmoji(t *testing.T) {
+	author := newTestUser(t, models.RoleUser)
+	first := newTestUser(t, models.RoleUser)
+	second := newTestUser(t, models.RoleUser)
+	post := &models.Post{UserID: author, CategoryID: newTestCategory(t, ""), Title: "Reactions", Content: "react"}
+	if err := CreatePost(post, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	emojis := []rune("😀😁😂😃😄😅😆😇😈😉😊😋😌😍😎😏😐😑😒😓😔")
+	for _, r := range emojis[:models.MaxReactionEmojis] {
+		if added, err := AddReaction(first, models.ReactionTargetPost, post.ID, string(r)); err != nil || !added {
+			t.Fatalf("AddReaction(%q) = %v, %v; want added", string(r), added, err)
+		}
+	}
+
+	extra := string(emojis[models.MaxReactionEmojis])
+	if _, err := AddReaction(first, models.ReactionTargetPost, post.ID, extra); err != ErrTooManyReactionEmojis {
+		t.Errorf("AddReaction() past the cap error = %v, want %v", err, ErrTooManyReactionEmojis)
+	}
+	if added, err := AddReaction(second, models.ReactionTargetPost, post.ID, string(emojis[0])); err != nil || !added {
+		t.Errorf("AddReaction() with an emoji already used = %v, %v; want added", added, err)
+	}
+	if added, err := AddReaction(first, models.ReactionTargetPost, post.ID, string(emojis[0])); err != nil || added {
+		t.Errorf("AddReaction() again = %v, %v; want not added and no error", added, err)
+	}
+
+	if _, err := RemoveReaction(first, models.ReactionTargetPost, post.ID, string(emojis[1])); err != nil {
+		t.Fatal(err)
+	}
+	if added, err := AddReaction(second, models.ReactionTargetPost, post.ID, extra); err != nil || !added {
+		t.Errorf("AddReaction() after an emoji was removed = %v, %v; want added", added, err)
+	}
+}
@@ -0,0 +1,289 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+	"strings"
+)
+
+// maxReportExcerptLength caps the text kept with a report, in characters
+const maxReportExcerptLength = 500
+
+// reportColumns selects a report along with the names of the users it
+// involves; reportJoins must follow FROM reports r
+const reportColumns = `r.id, r.reporter_id, COALESCE(ru.nickname, ''), r.target_type, r.target_id,
+	r.reported_user_id, COALESCE(tu.nickname, ''), COALESCE(r.post_id, 0), COALESCE(r.conversation_id, 0),
+	r.excerpt, r.reason, r.note, r.status, COALESCE(r.assignee_id, 0), COALESCE(a.nickname, ''),
+	r.resolution_note, COALESCE(r.resolved_by, 0), r.resolved_at, r.created_at, r.updated_at`
+
+// reportJoins joins what reportColumns needs, and the reported post as p
+const reportJoins = `
+	LEFT JOIN users ru ON ru.id = r.reporter_id
+	LEFT JOIN users tu ON tu.id = r.reported_user_id
+	LEFT JOIN users a ON a.id = r.assignee_id
+	LEFT JOIN posts p ON p.id = r.post_id`
+
+// scanReport scans a row selected with reportColumns
+func scanReport(row interface{ Scan(...interface{}) error }) (*models.Report, error) {
+	var r models.Report
+	var resolvedAt sql.NullTime
+	err := row.Scan(&r.ID, &r.ReporterID, &r.ReporterName, &r.TargetType, &r.TargetID,
+		&r.ReportedUserID, &r.ReportedUserName, &r.PostID, &r.ConversationID,
+		&r.Excerpt, &r.Reason, &r.Note, &r.Status, &r.AssigneeID, &r.AssigneeName,
+		&r.ResolutionNote, &r.ResolvedBy, &resolvedAt, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		r.ResolvedAt = &resolvedAt.Time
+	}
+	r.Link = models.ReportLink(r.TargetType, r.PostID)
+	return &r, nil
+}
+
+// visibleReports returns a condition keeping the reports viewerID may see:
+// those of posts and comments only in categories they may view
+func visibleReports(viewerID int) (string, []interface{}, error) {
+	visible, args, err := categoryFilter(viewerID, models.CategoryActionView, "p.category_id")
+	if err != nil {
+		return "", nil, err
+	}
+	return "(p.id IS NULL OR " + visible + ")", args, nil
+}
+
+// reportTarget is what a report keeps about the reported thing
+type reportTarget struct {
+	userID         int
+	postID         int
+	conversationID int
+	excerpt        string
+}
+
+// loadReportTarget looks up a post, comment, message or user that
+// reporterID reports. Things they cannot see are reported as not found.
+func loadReportTarget(reporterID int, targetType string, targetID int) (*reportTarget, error) {
+	var target reportTarget
+	switch targetType {
+	case models.ReportTargetPost:
+		if err := CheckPostAccess(reporterID, targetID, models.CategoryActionView); err != nil {
+			return nil, err
+		}
+		var title, content string
+		err := DB.QueryRow("SELECT user_id, title, content FROM posts WHERE id = ?", targetID).Scan(&target.userID, &title, &content)
+		if err != nil {
+			return nil, err
+		}
+		target.postID = targetID
+		target.excerpt = title + "\n\n" + content
+
+	case models.ReportTargetComment:
+		postID, err := GetCommentPostID(targetID)
+		if err != nil {
+			return nil, err
+		}
+		if err := CheckPostAccess(reporterID, postID, models.CategoryActionView); err == ErrPostNotFound {
+			return nil, ErrCommentNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		err = DB.QueryRow("SELECT user_id, content FROM comments WHERE id = ?", targetID).Scan(&target.userID, &target.excerpt)
+		if err != nil {
+			return nil, err
+		}
+		target.postID = postID
+
+	case models.ReportTargetMessage:
+		message, err := GetMessage(targetID, reporterID)
+		if err != nil {
+			return nil, err
+		}
+		if message.IsDeleted {
+			return nil, ErrMessageNotFound
+		}
+		target.userID = message.SenderID
+		target.conversationID = message.ConversationID
+		target.excerpt = message.Content
+
+	case models.ReportTargetUser:
+		err := DB.QueryRow("SELECT id, nickname FROM users WHERE id = ?", targetID).Scan(&target.userID, &target.excerpt)
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, models.ErrInvalidReportTarget
+	}
+	return &target, nil
+}
+
+// CreateReport stores a user's report of a post, comment, message or user,
+// keeping who is reported, where and an excerpt of what they wrote. A user
+// can have one open report of the same thing at a time.
+func CreateReport(reporterID int, req models.ReportRequest) (*models.Report, error) {
+	target, err := loadReportTarget(reporterID, req.TargetType, req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if target.userID == reporterID {
+		return nil, ErrCannotReportSelf
+	}
+
+	var id int
+	err = DB.QueryRow(`
+		INSERT INTO reports (reporter_id, target_type, target_id, reported_user_id, post_id, conversation_id, excerpt, reason, note)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, reporterID, req.TargetType, req.TargetID, target.userID, nullableID(target.postID), nullableID(target.conversationID),
+		truncateText(strings.TrimSpace(target.excerpt), maxReportExcerptLength), req.Reason, req.Note).Scan(&id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrReportExists
+		}
+		return nil, err
+	}
+
+	return scanReport(DB.QueryRow(`
+		SELECT `+reportColumns+` FROM reports r `+reportJoins+`
+		WHERE r.id = ?
+	`, id))
+}
+
+// GetReport retrieves a report that viewerID may see
+func GetReport(reportID, viewerID int) (*models.Report, error) {
+	visible, args, err := visibleReports(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	report, err := scanReport(DB.QueryRow(`
+		SELECT `+reportColumns+` FROM reports r `+reportJoins+`
+		WHERE r.id = ? AND `+visible+`
+	`, append([]interface{}{reportID}, args...)...))
+	if err == sql.ErrNoRows {
+		return nil, ErrReportNotFound
+	}
+	return report, err
+}
+
+// GetReports retrieves the moderation queue as viewerID may see it, 50
+// reports at a time: open reports oldest first, then the others most
+// recently made first. Reports of posts and comments in categories they may
+// not view are left out.
+func GetReports(viewerID int, opts models.ReportListOptions) (*models.ReportList, error) {
+	visible, visibleArgs, err := visibleReports(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	conditions := []string{visible}
+	args := visibleArgs
+	if opts.Status != "" {
+		conditions = append(conditions, "r.status = ?")
+		args = append(args, opts.Status)
+	}
+	if opts.AssigneeID != 0 {
+		conditions = append(conditions, "r.assignee_id = ?")
+		args = append(args, opts.AssigneeID)
+	}
+	if opts.TargetType != "" {
+		conditions = append(conditions, "r.target_type = ?")
+		args = append(args, opts.TargetType)
+	}
+	args = append(args, opts.Offset)
+
+	rows, err := DB.Query(`
+		SELECT `+reportColumns+` FROM reports r `+reportJoins+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY r.status != 'open', CASE WHEN r.status = 'open' THEN r.id ELSE -r.id END
+		LIMIT 50 OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := models.ReportList{Reports: []models.Report{}}
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		list.Reports = append(list.Reports, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	list.OpenCount, err = CountOpenReports(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// CountOpenReports counts the open reports viewerID may see
+func CountOpenReports(viewerID int) (int, error) {
+	visible, args, err := visibleReports(viewerID)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = DB.QueryRow(`
+		SELECT COUNT(*) FROM reports r LEFT JOIN posts p ON p.id = r.post_id
+		WHERE r.status = 'open' AND `+visible, args...).Scan(&count)
+	return count, err
+}
+
+// UpdateReport applies a moderator's changes to a report they may see.
+// Closing a report as actioned or dismissed records who closed it and
+// when; reopening it clears that. Reports can only be assigned to
+// moderators and admins.
+func UpdateReport(moderatorID, reportID int, req models.UpdateReportRequest) (*models.Report, error) {
+	if _, err := GetReport(reportID, moderatorID); err != nil {
+		return nil, err
+	}
+
+	sets := []string{"updated_at = CURRENT_TIMESTAMP"}
+	var args []interface{}
+	if req.Status != nil {
+		if *req.Status == models.ReportStatusOpen {
+			sets = append(sets, "status = ?", "resolved_by = NULL", "resolved_at = NULL")
+			args = append(args, *req.Status)
+		} else {
+			// A report moved between actioned and dismissed keeps who
+			// closed it and when
+			sets = append(sets,
+				"resolved_by = CASE WHEN status = 'open' THEN ? ELSE resolved_by END",
+				"resolved_at = CASE WHEN status = 'open' THEN CURRENT_TIMESTAMP ELSE resolved_at END",
+				"status = ?")
+			args = append(args, moderatorID, *req.Status)
+		}
+	}
+	if req.AssigneeID != nil {
+		if *req.AssigneeID != 0 {
+			role, err := GetUserRole(*req.AssigneeID)
+			if err == ErrUserNotFound || (err == nil && !models.RoleAtLeast(role, models.RoleModerator)) {
+				return nil, ErrReportAssigneeNotStaff
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		sets = append(sets, "assignee_id = ?")
+		args = append(args, nullableID(*req.AssigneeID))
+	}
+	if req.ResolutionNote != nil {
+		sets = append(sets, "resolution_note = ?")
+		args = append(args, *req.ResolutionNote)
+	}
+
+	args = append(args, reportID)
+	if _, err := DB.Exec("UPDATE reports SET "+strings.Join(sets, ", ")+" WHERE id = ?", args...); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrDuplicateOpenReport
+		}
+		return nil, err
+	}
+	return GetReport(reportID, moderatorID)
+}
@@ -0,0 +1,312 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+	"strings"
+)
+
+// stringArgs returns placeholders and arguments for a list of strings in an
+// IN clause
+func stringArgs(values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(values)), ","), args
+}
+
+// resolveTags turns normalized tag names that are synonyms into the tags
+// they stand for, dropping repeats this creates
+func resolveTags(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return names, nil
+	}
+
+	placeholders, args := stringArgs(names)
+	rows, err := DB.Query(`
+		SELECT s.name, t.name FROM tag_synonyms s JOIN tags t ON t.id = s.tag_id
+		WHERE s.name IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	canonical := make(map[string]string)
+	for rows.Next() {
+		var synonym, name string
+		if err := rows.Scan(&synonym, &name); err != nil {
+			return nil, err
+		}
+		canonical[synonym] = name
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resolved := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if c, ok := canonical[name]; ok {
+			name = c
+		}
+		if !seen[name] {
+			seen[name] = true
+			resolved = append(resolved, name)
+		}
+	}
+	return resolved, nil
+}
+
+// setPostTags tags a new post with names already resolved by resolveTags,
+// creating tags not used before
+func setPostTags(tx *sql.Tx, postID int, names []string) error {
+	for _, name := range names {
+		var tagID int
+		err := tx.QueryRow(`
+			INSERT INTO tags (name) VALUES (?)
+			ON CONFLICT (name) DO UPDATE SET name = excluded.name
+			RETURNING id
+		`, name).Scan(&tagID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO post_tags (post_id, tag_id) VALUES (?, ?)", postID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getPostTags retrieves the tag names of several posts, by name
+func getPostTags(postIDs []int) (map[int][]string, error) {
+	tags := make(map[int][]string)
+	if len(postIDs) == 0 {
+		return tags, nil
+	}
+
+	placeholders, args := idArgs(postIDs)
+	rows, err := DB.Query(`
+		SELECT pt.post_id, t.name FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.post_id IN (`+placeholders+`)
+		ORDER BY t.name
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			return nil, err
+		}
+		tags[postID] = append(tags[postID], name)
+	}
+	return tags, rows.Err()
+}
+
+// tagFilter returns a condition keeping posts with any, or with all, of the
+// given tags, after resolving synonyms
+func tagFilter(names []string, match string) (string, []interface{}, error) {
+	names, err := resolveTags(names)
+	if err != nil {
+		return "", nil, err
+	}
+
+	placeholders, args := stringArgs(names)
+	condition := `p.id IN (
+		SELECT pt.post_id FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+		WHERE t.name IN (` + placeholders + `)`
+	if match == models.TagMatchAll {
+		condition += `
+		GROUP BY pt.post_id HAVING COUNT(*) = ?`
+		args = append(args, len(names))
+	}
+	return condition + ")", args, nil
+}
+
+// tagColumns selects a tag with the number of posts carrying it; tagJoins
+// must follow FROM tags t
+const tagColumns = `t.id, t.name, t.created_at, COUNT(pt.post_id)`
+
+// tagJoins joins what tagColumns needs, leaving out tags no post carries;
+// the posts p are there to filter by category
+const tagJoins = `JOIN post_tags pt ON pt.tag_id = t.id JOIN posts p ON p.id = pt.post_id`
+
+// queryTags runs a tag query and fills in the synonyms of each tag
+func queryTags(query string, args ...interface{}) ([]models.Tag, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		t := models.Tag{Synonyms: []string{}}
+		if err := rows.Scan(&t.ID, &t.Name, &t.CreatedAt, &t.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return tags, nil
+	}
+
+	ids := make([]int, len(tags))
+	index := make(map[int]int, len(tags))
+	for i, t := range tags {
+		ids[i] = t.ID
+		index[t.ID] = i
+	}
+	placeholders, idList := idArgs(ids)
+	synonyms, err := DB.Query("SELECT tag_id, name FROM tag_synonyms WHERE tag_id IN ("+placeholders+") ORDER BY name", idList...)
+	if err != nil {
+		return nil, err
+	}
+	defer synonyms.Close()
+
+	for synonyms.Next() {
+		var tagID int
+		var name string
+		if err := synonyms.Scan(&tagID, &name); err != nil {
+			return nil, err
+		}
+		tags[index[tagID]].Synonyms = append(tags[index[tagID]].Synonyms, name)
+	}
+	return tags, synonyms.Err()
+}
+
+// GetTags lists the tags in use with how many posts viewerID may see carry
+// each, 50 at a time, most used or by name
+func GetTags(opts models.TagListOptions, viewerID int) ([]models.Tag, error) {
+	where, args, err := categoryFilter(viewerID, models.CategoryActionView, "p.category_id")
+	if err != nil {
+		return nil, err
+	}
+	if opts.Prefix != "" {
+		pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(opts.Prefix) + "%"
+		where += ` AND (t.name LIKE ? ESCAPE '\' OR t.id IN (SELECT tag_id FROM tag_synonyms WHERE name LIKE ? ESCAPE '\'))`
+		args = append(args, pattern, pattern)
+	}
+	orderBy := "COUNT(pt.post_id) DESC, t.name"
+	if opts.Sort == models.TagSortName {
+		orderBy = "t.name"
+	}
+	args = append(args, opts.Offset)
+
+	return queryTags(`
+		SELECT `+tagColumns+` FROM tags t `+tagJoins+`
+		WHERE `+where+`
+		GROUP BY t.id
+		ORDER BY `+orderBy+`
+		LIMIT 50 OFFSET ?
+	`, args...)
+}
+
+// GetTag retrieves a tag by its name or one of its synonyms, counting the
+// posts viewerID may see
+func GetTag(name string, viewerID int) (*models.Tag, error) {
+	names, err := resolveTags([]string{name})
+	if err != nil {
+		return nil, err
+	}
+	visible, args, err := categoryFilter(viewerID, models.CategoryActionView, "p.category_id")
+	if err != nil {
+		return nil, err
+	}
+	tags, err := queryTags(`
+		SELECT `+tagColumns+` FROM tags t `+tagJoins+`
+		WHERE t.name = ? AND `+visible+`
+		GROUP BY t.id
+	`, append([]interface{}{names[0]}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, ErrTagNotFound
+	}
+	return &tags[0], nil
+}
+
+// AddTagSynonym makes synonym another name for a tag. If the synonym was a
+// tag itself, its posts and synonyms move to the tag. If the tag is itself
+// a synonym, the synonym is added to the tag it stands for.
+func AddTagSynonym(synonym, tag string) error {
+	names, err := resolveTags([]string{tag})
+	if err != nil {
+		return err
+	}
+	tag = names[0]
+	if tag == synonym {
+		return models.ErrInvalidTagSynonym
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var tagID int
+	err = tx.QueryRow(`
+		INSERT INTO tags (name) VALUES (?)
+		ON CONFLICT (name) DO UPDATE SET name = excluded.name
+		RETURNING id
+	`, tag).Scan(&tagID)
+	if err != nil {
+		return err
+	}
+
+	var oldID int
+	err = tx.QueryRow("SELECT id FROM tags WHERE name = ?", synonym).Scan(&oldID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		for _, stmt := range []string{
+			"INSERT OR IGNORE INTO post_tags (post_id, tag_id) SELECT post_id, ? FROM post_tags WHERE tag_id = ?",
+			"UPDATE tag_synonyms SET tag_id = ? WHERE tag_id = ?",
+		} {
+			if _, err := tx.Exec(stmt, tagID, oldID); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", oldID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM tags WHERE id = ?", oldID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO tag_synonyms (name, tag_id) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET tag_id = excluded.tag_id
+	`, synonym, tagID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveTagSynonym stops a name from standing for a tag. Posts already
+// tagged through it keep the tag.
+func RemoveTagSynonym(synonym string) error {
+	result, err := DB.Exec("DELETE FROM tag_synonyms WHERE name = ?", synonym)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTagSynonymNotFound
+	}
+	return nil
+}
@@ -32,6 +32,7 @@ func CreateUser(user *models.User, password string) error {
 
 	userID, _ := result.LastInsertId()
 	user.ID = int(userID)
+	user.AvatarURL = models.AvatarURL(user.ID, "")
 	return nil
 }
 
@@ -41,11 +42,11 @@ func AuthenticateUser(identifier, password string) (*models.User, error) {
 	var passwordHash string
 
 	err := DB.QueryRow(`
-		SELECT id, nickname, email, password_hash, first_name, last_name, age, gender, avatar_color
+		SELECT id, nickname, email, password_hash, first_name, last_name, age, gender, avatar_color, avatar_key, dm_privacy, role
 		FROM users WHERE email = ? OR nickname = ?
 	`, identifier, identifier).Scan(
 		&user.ID, &user.Nickname, &user.Email, &passwordHash,
-		&user.FirstName, &user.LastName, &user.Age, &user.Gender, &user.AvatarColor,
+		&user.FirstName, &user.LastName, &user.Age, &user.Gender, &user.AvatarColor, &user.AvatarKey, &user.DMPrivacy, &user.Role,
 	)
 
 	if err != nil {
@@ -60,6 +61,7 @@ func AuthenticateUser(identifier, password string) (*models.User, error) {
 		return nil, ErrInvalidCredentials
 	}
 
+	user.AvatarURL = models.AvatarURL(user.ID, user.AvatarKey)
 	return &user, nil
 }
 
@@ -67,12 +69,12 @@ func AuthenticateUser(identifier, password string) (*models.User, error) {
 func GetUserByID(userID int) (*models.User, error) {
 	var user models.User
 	err := DB.QueryRow(`
-		SELECT id, nickname, email, first_name, last_name, age, gender, avatar_color, is_online, last_seen
+		SELECT id, nickname, email, first_name, last_name, age, gender, avatar_color, avatar_key, dm_privacy, role, is_online, last_seen
 		FROM users WHERE id = ?
 	`, userID).Scan(
 		&user.ID, &user.Nickname, &user.Email,
-		&user.FirstName, &user.LastName, &user.Age, &user.Gender, &user.AvatarColor,
-		&user.IsOnline, &user.LastSeen,
+		&user.FirstName, &user.LastName, &user.Age, &user.Gender, &user.AvatarColor, &user.AvatarKey,
+		&user.DMPrivacy, &user.Role, &user.IsOnline, &user.LastSeen,
 	)
 
 	if err != nil {
@@ -82,6 +84,7 @@ func GetUserByID(userID int) (*models.User, error) {
 		return nil, err
 	}
 
+	user.AvatarURL = models.AvatarURL(user.ID, user.AvatarKey)
 	return &user, nil
 }
 
@@ -89,7 +92,7 @@ func GetUserByID(userID int) (*models.User, error) {
 func GetAllUsers() ([]models.User, error) {
 	var users []models.User
 	rows, err := DB.Query(`
-		SELECT id, nickname, email, first_name, last_name, age, gender, avatar_color, is_online, last_seen
+		SELECT id, nickname, email, first_name, last_name, age, gender, avatar_color, avatar_key, dm_privacy, is_online, last_seen
 		FROM users
 		ORDER BY nickname
 	`)
@@ -102,11 +105,12 @@ func GetAllUsers() ([]models.User, error) {
 	for rows.Next() {
 		var user models.User
 		err := rows.Scan(&user.ID, &user.Nickname, &user.Email,
-			&user.FirstName, &user.LastName, &user.Age, &user.Gender, &user.AvatarColor,
-			&user.IsOnline, &user.LastSeen)
+			&user.FirstName, &user.LastName, &user.Age, &user.Gender, &user.AvatarColor, &user.AvatarKey,
+			&user.DMPrivacy, &user.IsOnline, &user.LastSeen)
 		if err != nil {
 			return users, err
 		}
+		user.AvatarURL = models.AvatarURL(user.ID, user.AvatarKey)
 		users = append(users, user)
 	}
 
@@ -135,7 +139,7 @@ func UpdateUser(userID int, user *models.User) error {
 		existingUser.LastName = user.LastName
 	}
 	if user.Age != 0 {
-		if user.Age < 13{
+		if user.Age < 13 {
 			return errors.New("invalid age: must be between 13 and 120")
 		}
 		existingUser.Age = user.Age
@@ -144,14 +148,23 @@ func UpdateUser(userID int, user *models.User) error {
 		existingUser.Gender = user.Gender
 	}
 	if user.AvatarColor != "" {
+		if !models.IsValidAvatarColor(user.AvatarColor) {
+			return models.ErrInvalidAvatarColor
+		}
 		existingUser.AvatarColor = user.AvatarColor
 	}
+	if user.DMPrivacy != "" {
+		if !models.IsValidDMPrivacy(user.DMPrivacy) {
+			return models.ErrInvalidDMPrivacy
+		}
+		existingUser.DMPrivacy = user.DMPrivacy
+	}
 
 	_, err = DB.Exec(`
 		UPDATE users
-		SET nickname = ?, email = ?, first_name = ?, last_name = ?, age = ?, gender = ?, avatar_color = ?
+		SET nickname = ?, email = ?, first_name = ?, last_name = ?, age = ?, gender = ?, avatar_color = ?, dm_privacy = ?
 		WHERE id = ?
-	`, existingUser.Nickname, existingUser.Email, existingUser.FirstName, existingUser.LastName, existingUser.Age, existingUser.Gender, existingUser.AvatarColor, userID)
+	`, existingUser.Nickname, existingUser.Email, existingUser.FirstName, existingUser.LastName, existingUser.Age, existingUser.Gender, existingUser.AvatarColor, existingUser.DMPrivacy, userID)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			return ErrUserAlreadyExists
@@ -161,6 +174,24 @@ func UpdateUser(userID int, user *models.User) error {
 	return nil
 }
 
+// SetUserAvatar stores a user's avatar key, or clears it with an empty key,
+// and returns the previous key so its files can be deleted
+func SetUserAvatar(userID int, avatarKey string) (string, error) {
+	var previous string
+	err := DB.QueryRow("SELECT avatar_key FROM users WHERE id = ?", userID).Scan(&previous)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+
+	if _, err := DB.Exec("UPDATE users SET avatar_key = ? WHERE id = ?", avatarKey, userID); err != nil {
+		return "", err
+	}
+	return previous, nil
+}
+
 // UpdateUserOnlineStatus updates a user's online status
 func UpdateUserOnlineStatus(userID int, isOnline bool) error {
 	_, err := DB.Exec(`
@@ -174,7 +205,7 @@ func UpdateUserOnlineStatus(userID int, isOnline bool) error {
 func GetOnlineUsers() ([]models.User, error) {
 	var users []models.User
 	rows, err := DB.Query(`
-		SELECT id, nickname, email, first_name, last_name, age, gender, avatar_color, is_online, last_seen
+		SELECT id, nickname, email, first_name, last_name, age, gender, avatar_color, avatar_key, dm_privacy, is_online, last_seen
 		FROM users
 		WHERE is_online = 1
 		ORDER BY nickname
@@ -188,13 +219,33 @@ func GetOnlineUsers() ([]models.User, error) {
 	for rows.Next() {
 		var user models.User
 		err := rows.Scan(&user.ID, &user.Nickname, &user.Email,
-			&user.FirstName, &user.LastName, &user.Age, &user.Gender, &user.AvatarColor,
-			&user.IsOnline, &user.LastSeen)
+			&user.FirstName, &user.LastName, &user.Age, &user.Gender, &user.AvatarColor, &user.AvatarKey,
+			&user.DMPrivacy, &user.IsOnline, &user.LastSeen)
 		if err != nil {
 			return users, err
 		}
+		user.AvatarURL = models.AvatarURL(user.ID, user.AvatarKey)
 		users = append(users, user)
 	}
 
 	return users, nil
-}
\ No newline at end of file
+}
+
+// SetAllUsersOffline marks every user offline, used when the server stops
+func SetAllUsersOffline() error {
+	_, err := DB.Exec(`
+		UPDATE users SET is_online = 0, last_seen = CURRENT_TIMESTAMP
+		WHERE is_online = 1
+	`)
+	return err
+}
+
+// GetUserRole returns a user's role
+func GetUserRole(userID int) (string, error) {
+	var role string
+	err := DB.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
+	}
+	return role, err
+}
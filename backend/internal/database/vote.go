@@ -0,0 +1,62 @@
+package database
+
+import (
+	"math"
+	"real-time-forum/backend/internal/models"
+	"time"
+)
+
+// hotEpoch is the reference time for hot ranking
+var hotEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// hotDecay is how many seconds of age are worth a tenfold change in score
+const hotDecay = 45000
+
+// hotRank scores a post for hot ordering: the order of magnitude of its score
+// plus a bonus that grows with creation time, so newer posts need fewer votes
+// to rank as high as older ones. Ranks never change unless the score does.
+func hotRank(score int, createdAt time.Time) float64 {
+	order := math.Log10(math.Max(math.Abs(float64(score)), 1))
+	sign := 0.0
+	if score > 0 {
+		sign = 1
+	} else if score < 0 {
+		sign = -1
+	}
+	return sign*order + createdAt.Sub(hotEpoch).Seconds()/hotDecay
+}
+
+// sqlHotRank is hotRank as the hot_rank SQL function, taking the creation
+// time in Unix seconds
+func sqlHotRank(score int, createdAt int64) float64 {
+	return hotRank(score, time.Unix(createdAt, 0))
+}
+
+// SetVote records a user's vote on a post or comment, replacing any earlier
+// vote; a value of 0 clears it. It returns the target's new score.
+func SetVote(userID int, targetType string, targetID, value int) (int, error) {
+	var err error
+	if value == 0 {
+		_, err = DB.Exec(`
+			DELETE FROM votes WHERE user_id = ? AND target_type = ? AND target_id = ?
+		`, userID, targetType, targetID)
+	} else {
+		_, err = DB.Exec(`
+			INSERT INTO votes (user_id, target_type, target_id, value) VALUES (?, ?, ?, ?)
+			ON CONFLICT (user_id, target_type, target_id)
+			DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP
+			WHERE value != excluded.value
+		`, userID, targetType, targetID, value)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	table := "posts"
+	if targetType == models.VoteTargetComment {
+		table = "comments"
+	}
+	var score int
+	err = DB.QueryRow("SELECT score FROM "+table+" WHERE id = ?", targetID).Scan(&score)
+	return score, err
+}
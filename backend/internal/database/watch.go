@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+	"real-time-forum/backend/internal/models"
+)
+
+// autoWatch makes a user watch a post they wrote or commented on, if the
+// setting in autoColumn is on and they never unwatched it
+func autoWatch(tx *sql.Tx, userID, postID int, autoColumn string) error {
+	_, err := tx.Exec(`
+		INSERT OR IGNORE INTO post_watches (user_id, post_id, watching)
+		SELECT id, ?, 1 FROM users WHERE id = ? AND `+autoColumn+` = 1
+	`, postID, userID)
+	return err
+}
+
+// WatchPost starts or stops a user watching a post
+func WatchPost(userID, postID int, watching bool) error {
+	if err := CheckPostAccess(userID, postID, models.CategoryActionView); err != nil {
+		return err
+	}
+
+	_, err := DB.Exec(`
+		INSERT INTO post_watches (user_id, post_id, watching) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, post_id) DO UPDATE SET watching = excluded.watching
+	`, userID, postID, watching)
+	return err
+}
+
+// GetPostWatcherIDs retrieves the users watching a post
+func GetPostWatcherIDs(postID int) ([]int, error) {
+	return queryIDs("SELECT user_id FROM post_watches WHERE post_id = ? AND watching = 1 ORDER BY user_id", postID)
+}
+
+// GetWatchSettings retrieves which posts a user starts watching on their own
+func GetWatchSettings(userID int) (models.WatchSettings, error) {
+	var settings models.WatchSettings
+	err := DB.QueryRow("SELECT auto_watch_posts, auto_watch_comments FROM users WHERE id = ?", userID).
+		Scan(&settings.AutoWatchPosts, &settings.AutoWatchComments)
+	return settings, err
+}
+
+// SetWatchSettings changes which posts a user starts watching on their own
+func SetWatchSettings(userID int, settings models.WatchSettings) error {
+	_, err := DB.Exec("UPDATE users SET auto_watch_posts = ?, auto_watch_comments = ? WHERE id = ?",
+		settings.AutoWatchPosts, settings.AutoWatchComments, userID)
+	return err
+}
@@ -0,0 +1,186 @@
+// Package digest emails users a summary of the direct messages, replies and
+// mentions they missed while away.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"real-time-forum/backend/internal/database"
+	forummail "real-time-forum/backend/internal/mail"
+	"real-time-forum/backend/internal/models"
+	"strings"
+	"time"
+)
+
+// maxItems caps the notifications listed in one digest
+const maxItems = 50
+
+// Service finds users due a digest and sends it through a mail transport
+type Service struct {
+	transport forummail.Transport
+	from      string
+	baseURL   string
+}
+
+// NewService creates a digest service sending from the given address, with
+// links pointing at baseURL
+func NewService(transport forummail.Transport, from, baseURL string) *Service {
+	return &Service{
+		transport: transport,
+		from:      from,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Run sends the digests that are due every interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SendDue(time.Now())
+		}
+	}
+}
+
+// SendDue sends every digest due at now. Users with nothing new get no
+// email, but their next digest is still a full period away.
+func (s *Service) SendDue(now time.Time) {
+	recipients, err := database.GetDueDigestRecipients(now)
+	if err != nil {
+		log.Printf("Error finding digest recipients: %v", err)
+		return
+	}
+
+	sent := 0
+	for _, r := range recipients {
+		items, err := database.GetDigestItems(r.UserID, now, maxItems)
+		if err != nil {
+			log.Printf("Error collecting digest for user %d: %v", r.UserID, err)
+			continue
+		}
+		if len(items) > 0 {
+			if err := s.send(r, items); err != nil {
+				log.Printf("Error sending digest to user %d: %v", r.UserID, err)
+				continue
+			}
+			sent++
+		}
+		if err := database.MarkDigestSent(r.UserID, now); err != nil {
+			log.Printf("Error recording digest for user %d: %v", r.UserID, err)
+		}
+	}
+	if sent > 0 {
+		log.Printf("Sent %d email digests", sent)
+	}
+}
+
+// send renders and delivers one user's digest
+func (s *Service) send(r models.DigestRecipient, items []models.Notification) error {
+	unsubscribeURL := s.baseURL + "/api/digest/unsubscribe?token=" + url.QueryEscape(r.UnsubscribeToken)
+	data := newDigestData(r, items, s.baseURL, unsubscribeURL)
+
+	var text, html strings.Builder
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return err
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return err
+	}
+
+	return s.transport.Send(&forummail.Message{
+		From:    s.from,
+		To:      (&mail.Address{Name: r.Nickname, Address: r.Email}).String(),
+		Subject: data.Subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
+
+// digestSection is a titled group of digest lines; one and many name a
+// single line and several in the subject
+type digestSection struct {
+	Title     string
+	Lines     []digestLine
+	one, many string
+}
+
+// digestLine describes one notification
+type digestLine struct {
+	Summary string
+	Excerpt string
+	When    string
+}
+
+// digestData is what the digest templates render
+type digestData struct {
+	Subject        string
+	Nickname       string
+	Frequency      string
+	Sections       []digestSection
+	More           bool
+	ForumURL       string
+	UnsubscribeURL string
+}
+
+// newDigestData groups notifications into messages, replies and mentions
+func newDigestData(r models.DigestRecipient, items []models.Notification, forumURL, unsubscribeURL string) digestData {
+	sections := []digestSection{
+		{Title: "Messages", one: "message", many: "messages"},
+		{Title: "Replies", one: "reply", many: "replies"},
+		{Title: "Mentions", one: "mention", many: "mentions"},
+	}
+	for _, n := range items {
+		line := digestLine{Excerpt: n.Excerpt, When: n.CreatedAt.UTC().Format("Jan 2, 15:04 MST")}
+		switch n.Type {
+		case models.NotificationDirectMessage:
+			line.Summary = n.ActorName + " sent you a message"
+			sections[0].Lines = append(sections[0].Lines, line)
+		case models.NotificationPostReply:
+			line.Summary = n.ActorName + " replied to your post"
+			sections[1].Lines = append(sections[1].Lines, line)
+		case models.NotificationCommentReply:
+			line.Summary = n.ActorName + " replied to your comment"
+			sections[1].Lines = append(sections[1].Lines, line)
+		case models.NotificationWatchedPost:
+			line.Summary = n.ActorName + " commented on a post you watch"
+			sections[1].Lines = append(sections[1].Lines, line)
+		case models.NotificationMention:
+			line.Summary = n.ActorName + " mentioned you"
+			sections[2].Lines = append(sections[2].Lines, line)
+		}
+	}
+
+	data := digestData{
+		Nickname:       r.Nickname,
+		Frequency:      r.Frequency,
+		More:           len(items) >= maxItems,
+		ForumURL:       forumURL,
+		UnsubscribeURL: unsubscribeURL,
+	}
+	var counts []string
+	for _, section := range sections {
+		switch len(section.Lines) {
+		case 0:
+			continue
+		case 1:
+			counts = append(counts, "1 "+section.one)
+		default:
+			counts = append(counts, fmt.Sprintf("%d %s", len(section.Lines), section.many))
+		}
+		data.Sections = append(data.Sections, section)
+	}
+	data.Subject = "Your " + r.Frequency + " forum digest: " + strings.Join(counts, ", ")
+	return data
+}
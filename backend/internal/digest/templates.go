@@ -0,0 +1,47 @@
+package digest
+
+import (
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// textTemplate renders the plain text part of a digest
+var textTemplate = texttemplate.Must(texttemplate.New("digest.txt").Parse(`Hi {{.Nickname}},
+
+Here is what you missed on the forum.
+{{range .Sections}}
+{{.Title}}
+{{range .Lines}}
+- {{.Summary}} ({{.When}})
+  {{.Excerpt}}
+{{end}}{{end}}{{if .More}}
+There is more waiting for you on the forum.
+{{end}}
+Catch up: {{.ForumURL}}
+
+You get this {{.Frequency}} digest because you have unread activity. Change how often in the notification settings, or unsubscribe: {{.UnsubscribeURL}}
+`))
+
+// htmlTemplate renders the HTML part of a digest
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #1f2937; max-width: 600px; margin: 0 auto;">
+<p>Hi {{.Nickname}},</p>
+<p>Here is what you missed on the forum.</p>
+{{range .Sections}}
+<h2 style="font-size: 16px; border-bottom: 1px solid #e5e7eb; padding-bottom: 4px;">{{.Title}}</h2>
+<ul style="padding-left: 0; list-style: none;">
+{{range .Lines}}
+<li style="margin-bottom: 12px;">
+<strong>{{.Summary}}</strong> <span style="color: #6b7280;">{{.When}}</span><br>
+<span style="color: #4b5563;">{{.Excerpt}}</span>
+</li>
+{{end}}
+</ul>
+{{end}}
+{{if .More}}<p>There is more waiting for you on the forum.</p>{{end}}
+<p><a href="{{.ForumURL}}" style="color: #2563eb;">Catch up on the forum</a></p>
+<p style="font-size: 12px; color: #6b7280;">You get this {{.Frequency}} digest because you have unread activity. Change how often in the notification settings, or <a href="{{.UnsubscribeURL}}" style="color: #6b7280;">unsubscribe</a>.</p>
+</body>
+</html>
+`))
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// SMTPTransport sends messages through an SMTP server, using STARTTLS when
+// the server offers it. Username and Password are optional; without them
+// no authentication is attempted, which suits local sinks like MailHog.
+type SMTPTransport struct {
+	Addr     string
+	Username string
+	Password string
+}
+
+// Send delivers a message to the SMTP server
+func (t *SMTPTransport) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	from, _ := mail.ParseAddress(msg.From)
+	to, _ := mail.ParseAddress(msg.To)
+
+	var auth smtp.Auth
+	if t.Username != "" {
+		host, _, err := net.SplitHostPort(t.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address: %w", err)
+		}
+		auth = smtp.PlainAuth("", t.Username, t.Password, host)
+	}
+	return smtp.SendMail(t.Addr, auth, from.Address, []string{to.Address}, data)
+}
+
+// FileTransport writes each message to Dir as an .eml file instead of
+// sending it, for development
+type FileTransport struct {
+	Dir string
+}
+
+// NewFileTransport creates a file transport, creating its directory if needed
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileTransport{Dir: dir}, nil
+}
+
+// Send writes a message to a new file named after the time and a random ID
+func (t *FileTransport) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), uuid.Must(uuid.NewV4()))
+	return os.WriteFile(filepath.Join(t.Dir, name), data, 0644)
+}
@@ -0,0 +1,73 @@
+package markdown
+
+import (
+	"bytes"
+	stdhtml "html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// converter turns Markdown into HTML. Raw HTML in the source is left out
+// because goldmark is not given html.WithUnsafe.
+var converter = goldmark.New(
+	goldmark.WithExtensions(extension.Linkify, extension.Strikethrough, extension.Table),
+	goldmark.WithParserOptions(parser.WithInlineParsers(util.Prioritized(&mentionParser{}, 500))),
+	goldmark.WithRendererOptions(
+		html.WithHardWraps(),
+		renderer.WithNodeRenderers(util.Prioritized(&mentionRenderer{}, 500)),
+	),
+)
+
+// policy is the allow-list every rendered post and comment passes through
+var policy = newPolicy()
+
+// newPolicy builds the sanitizer policy: text formatting, headings, lists,
+// quotes, code blocks, tables and links. Links may be relative or use http,
+// https and mailto, and get rel="nofollow noopener" with external ones
+// opening in a new tab.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "hr", "strong", "em", "del", "code", "pre", "blockquote",
+		"ul", "ol", "li", "h1", "h2", "h3", "h4", "h5", "h6",
+		"table", "thead", "tbody", "tr", "th", "td")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^mention$`)).OnElements("a")
+	p.AllowAttrs("data-user-id").Matching(bluemonday.Integer).OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowRelativeURLs(true)
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// Render converts Markdown source to sanitized HTML. mentions maps the
+// lowercased names of mentioned users to their IDs; those mentions become
+// links and any others stay plain text.
+func Render(source string, mentions map[string]int) string {
+	src := []byte(source)
+	doc := converter.Parser().Parse(text.NewReader(src))
+	walkMentions(doc, func(n *Mention) {
+		n.UserID = mentions[strings.ToLower(n.Name)]
+	})
+
+	var buf bytes.Buffer
+	if err := converter.Renderer().Render(&buf, src, doc); err != nil {
+		// Fall back to the escaped source rather than losing the content
+		return "<p>" + stdhtml.EscapeString(source) + "</p>"
+	}
+	return policy.Sanitize(buf.String())
+}
@@ -0,0 +1,144 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "script tags are dropped",
+			source:   "hello <script>alert(1)</script>",
+			contains: []string{"hello"},
+			excludes: []string{"<script", "</script>"},
+		},
+		{
+			name:     "raw HTML blocks are dropped",
+			source:   "<div onclick=\"steal()\">hi</div>\n\ntext",
+			contains: []string{"<p>text</p>"},
+			excludes: []string{"<div", "onclick"},
+		},
+		{
+			name:     "inline raw HTML is dropped",
+			source:   "a <img src=x onerror=alert(1)> b",
+			excludes: []string{"<img", "onerror"},
+		},
+		{
+			name:     "javascript links are stripped",
+			source:   "[click](javascript:alert(1))",
+			contains: []string{"click"},
+			excludes: []string{"javascript:", "href"},
+		},
+		{
+			name:     "data links are stripped",
+			source:   "[click](data:text/html;base64,PHNjcmlwdD4=)",
+			excludes: []string{"data:", "href"},
+		},
+		{
+			name:     "external links get nofollow and a new tab",
+			source:   "[site](https://example.com)",
+			contains: []string{`href="https://example.com"`, `rel="nofollow noopener"`, `target="_blank"`},
+		},
+		{
+			name:     "bare URLs are linked with nofollow",
+			source:   "see https://example.com/page",
+			contains: []string{`href="https://example.com/page"`, `rel="nofollow`},
+		},
+		{
+			name:     "relative links stay in the tab",
+			source:   "[post](/posts/1)",
+			contains: []string{`href="/posts/1"`, `rel="nofollow"`},
+			excludes: []string{"target"},
+		},
+		{
+			name:     "fenced code blocks keep their language and escape HTML",
+			source:   "```go\nfmt.Println(\"<b>\")\n```",
+			contains: []string{`<pre><code class="language-go">`, "&lt;b&gt;"},
+			excludes: []string{"<b>"},
+		},
+		{
+			name:     "inline code is kept",
+			source:   "run `go test`",
+			contains: []string{"<code>go test</code>"},
+		},
+		{
+			name:     "quotes are kept",
+			source:   "> quoted\n> text",
+			contains: []string{"<blockquote>", "quoted", "</blockquote>"},
+		},
+		{
+			name:     "formatting and lists are kept",
+			source:   "**bold** _it_ ~~gone~~\n\n3. three\n4. four",
+			contains: []string{"<strong>bold</strong>", "<em>it</em>", "<del>gone</del>", `<ol start="3">`, "<li>three</li>"},
+		},
+		{
+			name:     "tables are kept",
+			source:   "| a | b |\n|:-:|---|\n| 1 | 2 |",
+			contains: []string{"<table>", "<th>a</th>", "<td>2</td>"},
+		},
+		{
+			name:     "images are not allowed",
+			source:   "![alt](https://example.com/x.png)",
+			excludes: []string{"<img"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.source, nil)
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Render(%q) = %q, want it to contain %q", tt.source, got, want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("Render(%q) = %q, want no %q", tt.source, got, unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderMentions(t *testing.T) {
+	got := Render("hi @Alice and @nobody, mail bob@example.com", map[string]int{"alice": 7})
+	if !strings.Contains(got, `<a href="#user-7" class="mention" data-user-id="7" rel="nofollow">@Alice</a>`) {
+		t.Errorf("Render() = %q, want a link for the known user", got)
+	}
+	if !strings.Contains(got, "@nobody,") || strings.Contains(got, "#user-0") {
+		t.Errorf("Render() = %q, want unknown mentions as plain text", got)
+	}
+}
+
+// The sanitizer also guards against HTML the converter should never produce
+func TestPolicySanitize(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{`<p>hi<script>alert(1)</script></p>`, `<p>hi</p>`},
+		{`<a href="javascript:alert(1)">x</a>`, `x`},
+		{`<a href="https://example.com" onclick="steal()">x</a>`, `<a href="https://example.com" rel="nofollow noopener" target="_blank">x</a>`},
+		{`<a href="#user-1" class="mention evil">x</a>`, `<a href="#user-1" rel="nofollow">x</a>`},
+		{`<code class="language-go" style="color:red">x</code>`, `<code class="language-go">x</code>`},
+		{`<iframe src="https://example.com"></iframe>`, ``},
+		{`<p style="position:fixed">x</p>`, `<p>x</p>`},
+	}
+	for _, tt := range tests {
+		if got := policy.Sanitize(tt.html); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestFindMentions(t *testing.T) {
+	got := FindMentions("@alice and @Bob. @alice again, mail carol@example.com, `@dave` [@erin](/u)")
+	if strings.Join(got, ",") != "alice,Bob" {
+		t.Errorf("FindMentions() = %q, want [alice Bob]", got)
+	}
+}
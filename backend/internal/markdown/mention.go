@@ -0,0 +1,126 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindMention is the node kind of an @mention
+var KindMention = ast.NewNodeKind("Mention")
+
+// Mention is an @name in the text. UserID is set once the name is known to
+// belong to a user; unknown names are rendered as plain text.
+type Mention struct {
+	ast.BaseInline
+	Name   string
+	UserID int
+}
+
+// Kind implements ast.Node
+func (n *Mention) Kind() ast.NodeKind {
+	return KindMention
+}
+
+// Dump implements ast.Node
+func (n *Mention) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Name": n.Name}, nil)
+}
+
+// isNameRune reports whether a rune may appear in a mentioned nickname
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+// mentionParser reads @name where the @ does not follow a word character,
+// so email addresses are left alone. Trailing dots and dashes are taken to
+// be punctuation.
+type mentionParser struct{}
+
+func (p *mentionParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (p *mentionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if before := block.PrecendingCharacter(); isNameRune(before) {
+		return nil
+	}
+
+	line, _ := block.PeekLine()
+	end := 1
+	for end < len(line) {
+		r, size := utf8.DecodeRune(line[end:])
+		if !isNameRune(r) {
+			break
+		}
+		end += size
+	}
+	name := strings.TrimRight(string(line[1:end]), ".-")
+	if name == "" {
+		return nil
+	}
+
+	block.Advance(1 + len(name))
+	return &Mention{Name: name}
+}
+
+// mentionRenderer links mentions of known users
+type mentionRenderer struct{}
+
+func (r *mentionRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMention, r.render)
+}
+
+func (r *mentionRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*Mention)
+	name := util.EscapeHTML([]byte(n.Name))
+	if n.UserID == 0 {
+		fmt.Fprintf(w, "@%s", name)
+		return ast.WalkContinue, nil
+	}
+	fmt.Fprintf(w, `<a href="#user-%d" class="mention" data-user-id="%d">@%s</a>`, n.UserID, n.UserID, name)
+	return ast.WalkContinue, nil
+}
+
+// walkMentions calls fn for every mention outside of links
+func walkMentions(doc ast.Node, fn func(*Mention)) {
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Link, *ast.AutoLink:
+			return ast.WalkSkipChildren, nil
+		case *Mention:
+			fn(n)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// FindMentions returns the distinct names mentioned in Markdown source, in
+// order of first appearance. Mentions in code and links do not count.
+func FindMentions(source string) []string {
+	doc := converter.Parser().Parse(text.NewReader([]byte(source)))
+
+	seen := make(map[string]bool)
+	var names []string
+	walkMentions(doc, func(n *Mention) {
+		key := strings.ToLower(n.Name)
+		if !seen[key] {
+			seen[key] = true
+			names = append(names, n.Name)
+		}
+	})
+	return names
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Attachment target types
+const (
+	AttachmentTargetPost    = "post"
+	AttachmentTargetComment = "comment"
+	AttachmentTargetMessage = "message"
+)
+
+// MaxAttachments caps the number of files on one post, comment or message
+const MaxAttachments = 10
+
+// Attachment is an uploaded file, unattached until it is sent with a post,
+// comment or message. URL and ThumbnailURL need a logged-in session.
+type Attachment struct {
+	ID           int       `json:"id" db:"id"`
+	UploaderID   int       `json:"uploaderId" db:"uploader_id"`
+	TargetType   string    `json:"targetType,omitempty" db:"target_type"`
+	TargetID     int       `json:"targetId,omitempty" db:"target_id"`
+	Filename     string    `json:"filename" db:"filename"`
+	ContentType  string    `json:"contentType" db:"content_type"`
+	Size         int64     `json:"size" db:"size"`
+	Width        int       `json:"width,omitempty" db:"width"`
+	Height       int       `json:"height,omitempty" db:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailUrl,omitempty"`
+	StorageKey   string    `json:"-" db:"storage_key"`
+	ThumbnailKey string    `json:"-" db:"thumbnail_key"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// validateAttachmentIDs checks the attachments listed in a create request
+func validateAttachmentIDs(ids []int) error {
+	if len(ids) > MaxAttachments {
+		return ErrTooManyAttachments
+	}
+	for _, id := range ids {
+		if id <= 0 {
+			return ErrInvalidAttachment
+		}
+	}
+	return nil
+}
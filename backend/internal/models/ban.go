@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Ban limits
+const (
+	// MaxBanReasonLength caps ban reasons, in characters
+	MaxBanReasonLength = 500
+	// MaxBanHours caps temporary bans at ten years
+	MaxBanHours = 10 * 365 * 24
+)
+
+// Ban keeps a user from logging in until it expires or is lifted; bans
+// without an expiry are permanent. HideContent hides the user's posts and
+// comments while the ban is active. Lifted bans stay as history.
+type Ban struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"userId"`
+	UserName     string     `json:"userName"`
+	BannedBy     int        `json:"bannedBy"`
+	BannedByName string     `json:"bannedByName"`
+	Reason       string     `json:"reason"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	HideContent  bool       `json:"hideContent"`
+	Active       bool       `json:"active"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	LiftedAt     *time.Time `json:"liftedAt,omitempty"`
+	LiftedBy     int        `json:"liftedBy,omitempty"`
+	LiftedByName string     `json:"liftedByName,omitempty"`
+}
+
+// Message tells the banned user why and for how long they are banned
+func (b *Ban) Message() string {
+	if b.ExpiresAt == nil {
+		return "Your account has been banned permanently: " + b.Reason
+	}
+	return "Your account has been banned until " + b.ExpiresAt.UTC().Format("2006-01-02 15:04 MST") + ": " + b.Reason
+}
+
+// BanRequest represents a moderator banning a user for a number of hours,
+// or permanently when Hours is 0
+type BanRequest struct {
+	UserID      int    `json:"userId"`
+	Reason      string `json:"reason"`
+	Hours       int    `json:"hours"`
+	HideContent bool   `json:"hideContent"`
+}
+
+// Validate validates ban data
+func (r *BanRequest) Validate() error {
+	if r.UserID <= 0 {
+		return ErrInvalidBanUser
+	}
+	r.Reason = strings.TrimSpace(r.Reason)
+	if r.Reason == "" || utf8.RuneCountInString(r.Reason) > MaxBanReasonLength {
+		return ErrInvalidBanReason
+	}
+	if r.Hours < 0 || r.Hours > MaxBanHours {
+		return ErrInvalidBanDuration
+	}
+	return nil
+}
+
+// BanListOptions controls which bans the ban history lists
+type BanListOptions struct {
+	// UserID limits the list to the bans of one user
+	UserID int
+	// ActiveOnly leaves out bans that expired or were lifted
+	ActiveOnly bool
+	Offset     int
+}
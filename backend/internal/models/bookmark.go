@@ -0,0 +1,99 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Bookmark target types
+const (
+	BookmarkTargetPost    = "post"
+	BookmarkTargetComment = "comment"
+)
+
+// Bookmark limits, in characters
+const (
+	MaxBookmarkNoteLength       = 500
+	MaxBookmarkFolderNameLength = 50
+)
+
+// Bookmark is a post or comment a user saved. PostID and PostTitle name the
+// post, which for a comment is the post it was made on; Excerpt is the
+// start of the bookmarked text.
+type Bookmark struct {
+	ID           int       `json:"id" db:"id"`
+	TargetType   string    `json:"targetType" db:"target_type"`
+	TargetID     int       `json:"targetId" db:"target_id"`
+	PostID       int       `json:"postId"`
+	PostTitle    string    `json:"postTitle"`
+	Excerpt      string    `json:"excerpt"`
+	AuthorID     int       `json:"authorId"`
+	AuthorName   string    `json:"authorName"`
+	AuthorAvatar string    `json:"authorAvatar"`
+	FolderID     int       `json:"folderId,omitempty" db:"folder_id"`
+	FolderName   string    `json:"folderName,omitempty"`
+	Note         string    `json:"note" db:"note"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+}
+
+// BookmarkRequest represents saving a bookmark, or moving or annotating an
+// existing one. A FolderID of 0 leaves it outside any folder.
+type BookmarkRequest struct {
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	FolderID   int    `json:"folderId"`
+	Note       string `json:"note"`
+}
+
+// Validate validates bookmark data
+func (r *BookmarkRequest) Validate() error {
+	if !IsValidBookmarkTarget(r.TargetType) || r.TargetID <= 0 {
+		return ErrInvalidBookmarkTarget
+	}
+	if r.FolderID < 0 {
+		return ErrInvalidBookmarkFolder
+	}
+	r.Note = strings.TrimSpace(r.Note)
+	if utf8.RuneCountInString(r.Note) > MaxBookmarkNoteLength {
+		return ErrBookmarkNoteTooLong
+	}
+	return nil
+}
+
+// IsValidBookmarkTarget reports whether posts or comments of this type can
+// be bookmarked
+func IsValidBookmarkTarget(targetType string) bool {
+	return targetType == BookmarkTargetPost || targetType == BookmarkTargetComment
+}
+
+// BookmarkListOptions controls which of a user's bookmarks are listed
+type BookmarkListOptions struct {
+	// FolderID limits the list to one folder
+	FolderID int
+	// TargetType limits the list to posts or comments
+	TargetType string
+	Offset     int
+}
+
+// BookmarkFolder is a user's named group of bookmarks
+type BookmarkFolder struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// BookmarkFolderRequest represents creating or renaming a folder
+type BookmarkFolderRequest struct {
+	Name string `json:"name"`
+}
+
+// Validate validates folder data
+func (r *BookmarkFolderRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || utf8.RuneCountInString(r.Name) > MaxBookmarkFolderNameLength {
+		return ErrInvalidBookmarkFolderName
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Category limits, in characters
+const (
+	MaxCategoryNameLength        = 50
+	MaxCategoryDescriptionLength = 300
+)
+
+// Category represents a post category. A category with a ParentID is a
+// subcategory; subcategories cannot have subcategories of their own.
+// PostCount counts the posts in the category itself, and LastActivityAt is
+// the time of its latest post or comment. CanPost and CanComment tell the
+// user a category list was made for what they may do in it.
+type Category struct {
+	ID             int        `json:"id" db:"id"`
+	Name           string     `json:"name" db:"name"`
+	Description    string     `json:"description" db:"description"`
+	ParentID       int        `json:"parentId,omitempty" db:"parent_id"`
+	Position       int        `json:"position" db:"position"`
+	Archived       bool       `json:"archived"`
+	PostCount      int        `json:"postCount"`
+	LastActivityAt *time.Time `json:"lastActivityAt"`
+	CanPost        bool       `json:"canPost"`
+	CanComment     bool       `json:"canComment"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreateCategoryRequest represents a new category, or a subcategory when
+// ParentID is set
+type CreateCategoryRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ParentID    int    `json:"parentId"`
+}
+
+// Validate validates category data
+func (r *CreateCategoryRequest) Validate() error {
+	if err := validateCategoryName(&r.Name); err != nil {
+		return err
+	}
+	if err := validateCategoryDescription(&r.Description); err != nil {
+		return err
+	}
+	if r.ParentID < 0 {
+		return ErrInvalidCategoryParent
+	}
+	return nil
+}
+
+// UpdateCategoryRequest represents changes to a category; fields left out
+// are unchanged. A ParentID of 0 makes a subcategory top-level again.
+type UpdateCategoryRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	ParentID    *int    `json:"parentId"`
+	Archived    *bool   `json:"archived"`
+}
+
+// Validate validates category changes
+func (r *UpdateCategoryRequest) Validate() error {
+	if r.Name == nil && r.Description == nil && r.ParentID == nil && r.Archived == nil {
+		return ErrNoCategoryChanges
+	}
+	if r.Name != nil {
+		if err := validateCategoryName(r.Name); err != nil {
+			return err
+		}
+	}
+	if r.Description != nil {
+		if err := validateCategoryDescription(r.Description); err != nil {
+			return err
+		}
+	}
+	if r.ParentID != nil && *r.ParentID < 0 {
+		return ErrInvalidCategoryParent
+	}
+	return nil
+}
+
+// CategoryOrderRequest lists the subcategories of a category, or the
+// top-level categories when ParentID is 0, in their new order
+type CategoryOrderRequest struct {
+	ParentID    int   `json:"parentId"`
+	CategoryIDs []int `json:"categoryIds"`
+}
+
+// Validate validates a new category order
+func (r *CategoryOrderRequest) Validate() error {
+	if r.ParentID < 0 || len(r.CategoryIDs) == 0 {
+		return ErrInvalidCategoryOrder
+	}
+	seen := make(map[int]bool, len(r.CategoryIDs))
+	for _, id := range r.CategoryIDs {
+		if id <= 0 || seen[id] {
+			return ErrInvalidCategoryOrder
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// validateCategoryName trims a category name and checks its length
+func validateCategoryName(name *string) error {
+	*name = strings.TrimSpace(*name)
+	if *name == "" || utf8.RuneCountInString(*name) > MaxCategoryNameLength {
+		return ErrInvalidCategoryName
+	}
+	return nil
+}
+
+// validateCategoryDescription trims a category description and checks its
+// length
+func validateCategoryDescription(description *string) error {
+	*description = strings.TrimSpace(*description)
+	if utf8.RuneCountInString(*description) > MaxCategoryDescriptionLength {
+		return ErrCategoryDescriptionTooLong
+	}
+	return nil
+}
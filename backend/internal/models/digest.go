@@ -0,0 +1,41 @@
+package models
+
+// Email digest frequencies
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestNotificationTypes are the notification types collected into email
+// digests; reactions are left out
+var DigestNotificationTypes = []string{
+	NotificationDirectMessage,
+	NotificationPostReply,
+	NotificationCommentReply,
+	NotificationWatchedPost,
+	NotificationMention,
+}
+
+// DigestSettings represents how often a user gets an email digest
+type DigestSettings struct {
+	Frequency string `json:"frequency"`
+}
+
+// Validate validates digest settings
+func (s *DigestSettings) Validate() error {
+	switch s.Frequency {
+	case DigestOff, DigestDaily, DigestWeekly:
+		return nil
+	}
+	return ErrInvalidDigestFrequency
+}
+
+// DigestRecipient is a user due an email digest
+type DigestRecipient struct {
+	UserID           int
+	Nickname         string
+	Email            string
+	Frequency        string
+	UnsubscribeToken string
+}
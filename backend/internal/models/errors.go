@@ -13,17 +13,97 @@ var (
 	ErrInvalidGender      = errors.New("invalid gender")
 	ErrInvalidPassword    = errors.New("invalid password: must be at least 8 characters")
 	ErrInvalidIdentifier  = errors.New("invalid identifier: email or nickname required")
+	ErrInvalidDMPrivacy   = errors.New("invalid message privacy: must be everyone, following or nobody")
+	ErrInvalidAvatarColor = errors.New("invalid avatar color")
 
 	// Post errors
 	ErrInvalidTitle       = errors.New("invalid title")
 	ErrInvalidContent     = errors.New("invalid content")
 	ErrInvalidCategory    = errors.New("invalid category")
 	ErrInvalidPostID      = errors.New("invalid post ID")
+	ErrInvalidParentID    = errors.New("invalid parent comment ID")
+	ErrInvalidSort        = errors.New("invalid sort: must be new, top or hot")
+	ErrInvalidRange       = errors.New("invalid range: must be day, week, month, year or all")
+
+	// Vote errors
+	ErrInvalidVoteTarget = errors.New("invalid vote target: must be post or comment")
+	ErrInvalidVoteValue  = errors.New("invalid vote: must be 1, -1 or 0")
 
 	// Message errors
 	ErrInvalidSenderID    = errors.New("invalid sender ID")
 	ErrInvalidRecipientID = errors.New("invalid recipient ID")
 	ErrSelfMessage        = errors.New("cannot send message to yourself")
+	ErrInvalidDeleteScope = errors.New("invalid delete scope: must be me or everyone")
+
+	// Block and mute errors
+	ErrInvalidUserID = errors.New("invalid user ID")
+	ErrSelfBlock     = errors.New("cannot block or mute yourself")
+	ErrSelfFollow    = errors.New("cannot follow yourself")
+
+	// Reaction errors
+	ErrInvalidReactionTarget = errors.New("invalid reaction target: must be post, comment or message")
+	ErrInvalidEmoji          = errors.New("invalid emoji")
+
+	// Attachment errors
+	ErrInvalidAttachment  = errors.New("invalid attachment ID")
+	ErrTooManyAttachments = errors.New("too many attachments: at most 10 per item")
+
+	// Conversation errors
+	ErrInvalidConversationID    = errors.New("invalid conversation ID")
+	ErrInvalidConversationTitle = errors.New("invalid conversation title: 1 to 100 characters required")
+	ErrInvalidParticipants      = errors.New("invalid participants")
+	ErrInvalidRequestAction     = errors.New("invalid action: must be accept or decline")
+
+	// Notification errors
+	ErrInvalidNotificationType = errors.New("invalid notification type")
+	ErrInvalidNotificationID   = errors.New("invalid notification ID")
+	ErrNoNotifications         = errors.New("no notifications given")
+	ErrInvalidDigestFrequency  = errors.New("invalid digest frequency: must be off, daily or weekly")
+
+	// Bookmark errors
+	ErrInvalidBookmarkTarget     = errors.New("invalid bookmark target: must be post or comment")
+	ErrInvalidBookmarkFolder     = errors.New("invalid bookmark folder ID")
+	ErrInvalidBookmarkFolderName = errors.New("invalid folder name: 1 to 50 characters required")
+	ErrBookmarkNoteTooLong       = errors.New("bookmark note too long: at most 500 characters")
+
+	// Tag errors
+	ErrInvalidTag        = errors.New("invalid tag: 1 to 30 letters or digits required")
+	ErrTooManyTags       = errors.New("too many tags: at most 5 per post")
+	ErrTooManyFilterTags = errors.New("too many tags to filter by: at most 10")
+	ErrInvalidTagMatch   = errors.New("invalid tag match: must be any or all")
+	ErrInvalidTagSynonym = errors.New("a tag cannot be a synonym of itself")
+
+	// Category errors
+	ErrInvalidCategoryName        = errors.New("invalid category name: 1 to 50 characters required")
+	ErrCategoryDescriptionTooLong = errors.New("category description too long: at most 300 characters")
+	ErrInvalidCategoryParent      = errors.New("invalid parent category")
+	ErrInvalidCategoryOrder       = errors.New("invalid category order: list each category once")
+	ErrNoCategoryChanges          = errors.New("no category changes given")
+
+	// Permission errors
+	ErrInvalidCategoryPermission  = errors.New("invalid permission: each rule needs a view, post or comment action and either a role or a group")
+	ErrTooManyCategoryPermissions = errors.New("too many permission rules: at most 50 per category")
+	ErrInvalidGroupName           = errors.New("invalid group name: 1 to 50 characters required")
+	ErrInvalidGroupMember         = errors.New("invalid group member: group and user IDs required")
+
+	// Post moderation errors
+	ErrInvalidPinScope    = errors.New("invalid pin: must be category, global or empty to unpin")
+	ErrNoPostStateChanges = errors.New("no post state changes given")
+
+	// Report errors
+	ErrInvalidReportTarget   = errors.New("invalid report target: a post, comment, message or user ID required")
+	ErrInvalidReportReason   = errors.New("invalid report reason: must be spam, harassment, hate, inappropriate or other")
+	ErrReportNoteTooLong     = errors.New("report note too long: at most 1000 characters")
+	ErrReportNoteRequired    = errors.New("please describe the problem when reporting for another reason")
+	ErrInvalidReportStatus   = errors.New("invalid report status: must be open, actioned or dismissed")
+	ErrInvalidReportAssignee = errors.New("invalid assignee")
+	ErrResolutionNoteTooLong = errors.New("resolution note too long: at most 1000 characters")
+	ErrNoReportChanges       = errors.New("no report changes given")
+
+	// Ban errors
+	ErrInvalidBanUser     = errors.New("invalid user to ban")
+	ErrInvalidBanReason   = errors.New("invalid ban reason: 1 to 500 characters required")
+	ErrInvalidBanDuration = errors.New("invalid ban duration: 0 for a permanent ban, or up to 87600 hours")
 
 	// Database errors
 	ErrUserNotFound       = errors.New("user not found")
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxGroupNameLength caps user group names, in characters
+const MaxGroupNameLength = 50
+
+// UserGroup is a named set of users that category permissions can be
+// granted to
+type UserGroup struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	MemberCount int       `json:"memberCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// UserGroupRequest represents a request to create or rename a user group
+type UserGroupRequest struct {
+	Name string `json:"name"`
+}
+
+// Validate validates a user group request
+func (r *UserGroupRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || utf8.RuneCountInString(r.Name) > MaxGroupNameLength {
+		return ErrInvalidGroupName
+	}
+	return nil
+}
+
+// GroupMemberRequest represents a request to add a user to a group
+type GroupMemberRequest struct {
+	GroupID int `json:"groupId"`
+	UserID  int `json:"userId"`
+}
+
+// Validate validates a group member request
+func (r *GroupMemberRequest) Validate() error {
+	if r.GroupID <= 0 || r.UserID <= 0 {
+		return ErrInvalidGroupMember
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Mention target types
+const (
+	MentionTargetPost    = "post"
+	MentionTargetComment = "comment"
+	MentionTargetMessage = "message"
+)
+
+// MaxMentions caps the number of users notified from one post, comment or message
+const MaxMentions = 20
+
+// Mention records that a user was @mentioned. PostID is set for posts and
+// comments and ConversationID for messages, so clients can open the right view.
+type Mention struct {
+	ID             int       `json:"id" db:"id"`
+	UserID         int       `json:"-" db:"user_id"`
+	TargetType     string    `json:"targetType" db:"target_type"`
+	TargetID       int       `json:"targetId" db:"target_id"`
+	PostID         int       `json:"postId,omitempty"`
+	ConversationID int       `json:"conversationId,omitempty"`
+	AuthorID       int       `json:"authorId" db:"author_id"`
+	AuthorName     string    `json:"authorName"`
+	AuthorAvatar   string    `json:"authorAvatar"`
+	Excerpt        string    `json:"excerpt"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+}
+
+// UserSummary is the public part of a user shown in lists and suggestions
+type UserSummary struct {
+	ID          int    `json:"id"`
+	Nickname    string `json:"nickname"`
+	AvatarColor string `json:"avatarColor"`
+	AvatarURL   string `json:"avatarUrl"`
+}
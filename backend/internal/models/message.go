@@ -1,34 +1,236 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
+// MaxConversationParticipants caps the size of group conversations
+const MaxConversationParticipants = 50
+
+// Message delete scopes
+const (
+	DeleteScopeMe       = "me"
+	DeleteScopeEveryone = "everyone"
+)
+
+// Participant statuses; a conversation the user has not accepted yet is a
+// message request
+const (
+	ParticipantAccepted = "accepted"
+	ParticipantPending  = "pending"
+	ParticipantDeclined = "declined"
+)
+
+// Message request actions
+const (
+	RequestActionAccept  = "accept"
+	RequestActionDecline = "decline"
+)
+
 // Message models for real-time communication
 type Message struct {
-	ID            int       `json:"id" db:"id"`
-	SenderID      int       `json:"senderId" db:"sender_id"`
-	RecipientID   int       `json:"recipientId" db:"recipient_id"`
-	Content       string    `json:"content" db:"content"`
-	IsRead        bool      `json:"isRead" db:"is_read"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	SenderName    string    `json:"senderName" db:"sender_name"`
-	RecipientName string    `json:"recipientName" db:"recipient_name"`
+	ID             int             `json:"id" db:"id"`
+	ConversationID int             `json:"conversationId" db:"conversation_id"`
+	SenderID       int             `json:"senderId" db:"sender_id"`
+	RecipientID    int             `json:"recipientId,omitempty" db:"recipient_id"`
+	Content        string          `json:"content" db:"content"`
+	IsRead         bool            `json:"isRead" db:"is_read"`
+	IsEdited       bool            `json:"isEdited"`
+	IsDeleted      bool            `json:"isDeleted"`
+	EditedAt       *time.Time      `json:"editedAt,omitempty" db:"edited_at"`
+	Reactions      []ReactionCount `json:"reactions,omitempty"`
+	Attachments    []Attachment    `json:"attachments,omitempty"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	SenderName     string          `json:"senderName" db:"sender_name"`
+	RecipientName  string          `json:"recipientName,omitempty" db:"recipient_name"`
+}
+
+// MessageRevision is an earlier version of an edited message
+type MessageRevision struct {
+	ID        int       `json:"id" db:"id"`
+	MessageID int       `json:"messageId" db:"message_id"`
+	Content   string    `json:"content" db:"content"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
-// CreateMessageRequest represents the data needed to create a new message
+// Conversation represents a one-to-one or group conversation
+type Conversation struct {
+	ID           int                       `json:"id" db:"id"`
+	Title        string                    `json:"title" db:"title"`
+	IsGroup      bool                      `json:"isGroup" db:"is_group"`
+	CreatedBy    int                       `json:"createdBy" db:"created_by"`
+	Participants []ConversationParticipant `json:"participants"`
+	LastMessage  *Message                  `json:"lastMessage,omitempty"`
+	UnreadCount  int                       `json:"unreadCount"`
+	Status       string                    `json:"status"`
+	CreatedAt    time.Time                 `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at" db:"updated_at"`
+}
+
+// ConversationParticipant represents an active member of a conversation
+type ConversationParticipant struct {
+	UserID            int       `json:"userId" db:"user_id"`
+	Nickname          string    `json:"nickname" db:"nickname"`
+	AvatarColor       string    `json:"avatarColor" db:"avatar_color"`
+	AvatarURL         string    `json:"avatarUrl"`
+	LastReadMessageID int       `json:"lastReadMessageId" db:"last_read_message_id"`
+	JoinedAt          time.Time `json:"joinedAt" db:"joined_at"`
+}
+
+// CreateMessageRequest represents the data needed to create a new message.
+// Either RecipientID (one-to-one) or ConversationID must be set, and the
+// content may only be empty when files are attached.
 type CreateMessageRequest struct {
-	RecipientID int    `json:"recipientId"`
-	Content     string `json:"content"`
+	RecipientID    int    `json:"recipientId"`
+	ConversationID int    `json:"conversationId"`
+	Content        string `json:"content"`
+	AttachmentIDs  []int  `json:"attachmentIds"`
+}
+
+// EditMessageRequest represents the data needed to edit a message
+type EditMessageRequest struct {
+	MessageID int    `json:"messageId"`
+	Content   string `json:"content"`
+}
+
+// DeleteMessageRequest represents the data needed to delete a message,
+// either for the requesting user only or for everyone
+type DeleteMessageRequest struct {
+	MessageID int    `json:"messageId"`
+	Scope     string `json:"scope"`
+}
+
+// CreateConversationRequest represents the data needed to start a group conversation
+type CreateConversationRequest struct {
+	Title          string `json:"title"`
+	ParticipantIDs []int  `json:"participantIds"`
+}
+
+// RenameConversationRequest represents the data needed to rename a group conversation
+type RenameConversationRequest struct {
+	ConversationID int    `json:"conversationId"`
+	Title          string `json:"title"`
+}
+
+// AddParticipantsRequest represents the data needed to add members to a group conversation
+type AddParticipantsRequest struct {
+	ConversationID int   `json:"conversationId"`
+	UserIDs        []int `json:"userIds"`
+}
+
+// MessageRequestAction represents accepting or declining a message request
+type MessageRequestAction struct {
+	ConversationID int    `json:"conversationId"`
+	Action         string `json:"action"`
+}
+
+// MarkReadRequest represents a participant's read position in a conversation
+type MarkReadRequest struct {
+	ConversationID int `json:"conversationId"`
+	MessageID      int `json:"messageId"`
 }
 
 // Validate validates message input data
 func (r *CreateMessageRequest) Validate() error {
-	if r.Content == "" {
+	if r.Content == "" && len(r.AttachmentIDs) == 0 {
 		return ErrInvalidContent
 	}
-	if r.RecipientID <= 0 {
+	if r.RecipientID <= 0 && r.ConversationID <= 0 {
 		return ErrInvalidRecipientID
 	}
+	return validateAttachmentIDs(r.AttachmentIDs)
+}
+
+// Validate validates message edit data
+func (r *EditMessageRequest) Validate() error {
+	if r.MessageID <= 0 {
+		return ErrMessageNotFound
+	}
+	if r.Content == "" {
+		return ErrInvalidContent
+	}
+	return nil
+}
+
+// Validate validates message delete data, defaulting to deleting for the
+// requesting user only
+func (r *DeleteMessageRequest) Validate() error {
+	if r.MessageID <= 0 {
+		return ErrMessageNotFound
+	}
+	if r.Scope == "" {
+		r.Scope = DeleteScopeMe
+	}
+	if r.Scope != DeleteScopeMe && r.Scope != DeleteScopeEveryone {
+		return ErrInvalidDeleteScope
+	}
+	return nil
+}
+
+// Validate validates conversation creation data
+func (r *CreateConversationRequest) Validate() error {
+	r.Title = strings.TrimSpace(r.Title)
+	if r.Title == "" || len(r.Title) > 100 {
+		return ErrInvalidConversationTitle
+	}
+	if len(r.ParticipantIDs) == 0 || len(r.ParticipantIDs) >= MaxConversationParticipants {
+		return ErrInvalidParticipants
+	}
+	for _, id := range r.ParticipantIDs {
+		if id <= 0 {
+			return ErrInvalidParticipants
+		}
+	}
+	return nil
+}
+
+// Validate validates conversation rename data
+func (r *RenameConversationRequest) Validate() error {
+	r.Title = strings.TrimSpace(r.Title)
+	if r.ConversationID <= 0 {
+		return ErrInvalidConversationID
+	}
+	if r.Title == "" || len(r.Title) > 100 {
+		return ErrInvalidConversationTitle
+	}
+	return nil
+}
+
+// Validate validates a reply to a message request
+func (r *MessageRequestAction) Validate() error {
+	if r.ConversationID <= 0 {
+		return ErrInvalidConversationID
+	}
+	if r.Action != RequestActionAccept && r.Action != RequestActionDecline {
+		return ErrInvalidRequestAction
+	}
+	return nil
+}
+
+// Validate validates add-member data
+func (r *AddParticipantsRequest) Validate() error {
+	if r.ConversationID <= 0 {
+		return ErrInvalidConversationID
+	}
+	if len(r.UserIDs) == 0 || len(r.UserIDs) >= MaxConversationParticipants {
+		return ErrInvalidParticipants
+	}
+	for _, id := range r.UserIDs {
+		if id <= 0 {
+			return ErrInvalidParticipants
+		}
+	}
+	return nil
+}
+
+// Validate validates mark-read data
+func (r *MarkReadRequest) Validate() error {
+	if r.ConversationID <= 0 {
+		return ErrInvalidConversationID
+	}
+	if r.MessageID <= 0 {
+		return ErrMessageNotFound
+	}
 	return nil
 }
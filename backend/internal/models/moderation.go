@@ -0,0 +1,37 @@
+package models
+
+// Pin scopes: a post pinned to its category stays at the top of that
+// category's listing, and one pinned globally at the top of the listing of
+// all posts as well
+const (
+	PinCategory = "category"
+	PinGlobal   = "global"
+)
+
+// PostState is the moderation state of a post
+type PostState struct {
+	PostID     int    `json:"postId"`
+	CategoryID int    `json:"categoryId"`
+	Pinned     string `json:"pinned,omitempty"`
+	Locked     bool   `json:"locked"`
+	Archived   bool   `json:"archived"`
+}
+
+// PostStateRequest represents moderator changes to a post's state; fields
+// left out are unchanged. An empty Pinned unpins the post.
+type PostStateRequest struct {
+	Pinned   *string `json:"pinned"`
+	Locked   *bool   `json:"locked"`
+	Archived *bool   `json:"archived"`
+}
+
+// Validate validates post state changes
+func (r *PostStateRequest) Validate() error {
+	if r.Pinned == nil && r.Locked == nil && r.Archived == nil {
+		return ErrNoPostStateChanges
+	}
+	if r.Pinned != nil && *r.Pinned != "" && *r.Pinned != PinCategory && *r.Pinned != PinGlobal {
+		return ErrInvalidPinScope
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+// Notification types
+const (
+	NotificationPostReply     = "post_reply"
+	NotificationCommentReply  = "comment_reply"
+	NotificationMention       = "mention"
+	NotificationReaction      = "reaction"
+	NotificationDirectMessage = "direct_message"
+	NotificationWatchedPost   = "watched_post"
+)
+
+// NotificationTypes lists every notification type, in the order preferences
+// are shown
+var NotificationTypes = []string{
+	NotificationPostReply,
+	NotificationCommentReply,
+	NotificationMention,
+	NotificationReaction,
+	NotificationDirectMessage,
+	NotificationWatchedPost,
+}
+
+// IsValidNotificationType reports whether t is a known notification type
+func IsValidNotificationType(t string) bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification tells a user that someone replied to them, mentioned them,
+// reacted to something of theirs, sent them a direct message or commented on
+// a post they watch. TargetType
+// and TargetID name what the actor created or reacted to; PostID is set for
+// posts and comments and ConversationID for messages. Detail holds the
+// emoji of a reaction.
+type Notification struct {
+	ID             int       `json:"id" db:"id"`
+	UserID         int       `json:"-" db:"user_id"`
+	Type           string    `json:"type" db:"type"`
+	ActorID        int       `json:"actorId" db:"actor_id"`
+	ActorName      string    `json:"actorName"`
+	ActorAvatar    string    `json:"actorAvatar"`
+	TargetType     string    `json:"targetType" db:"target_type"`
+	TargetID       int       `json:"targetId" db:"target_id"`
+	PostID         int       `json:"postId,omitempty"`
+	ConversationID int       `json:"conversationId,omitempty"`
+	Detail         string    `json:"detail,omitempty" db:"detail"`
+	Excerpt        string    `json:"excerpt"`
+	Read           bool      `json:"read"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+}
+
+// NotificationList is a page of notifications with the user's unread count
+type NotificationList struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unreadCount"`
+}
+
+// MarkNotificationsReadRequest represents the notifications to mark as read
+type MarkNotificationsReadRequest struct {
+	NotificationIDs []int `json:"notificationIds"`
+}
+
+// Validate validates the notifications to mark as read
+func (r *MarkNotificationsReadRequest) Validate() error {
+	if len(r.NotificationIDs) == 0 {
+		return ErrNoNotifications
+	}
+	for _, id := range r.NotificationIDs {
+		if id <= 0 {
+			return ErrInvalidNotificationID
+		}
+	}
+	return nil
+}
+
+// NotificationPreferences maps each notification type to whether it is
+// generated for the user
+type NotificationPreferences map[string]bool
+
+// Validate validates notification preferences; types left out are unchanged
+func (p NotificationPreferences) Validate() error {
+	for t := range p {
+		if !IsValidNotificationType(t) {
+			return ErrInvalidNotificationType
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package models
+
+// MaxCategoryPermissions caps the permission rules of one category
+const MaxCategoryPermissions = 50
+
+// Actions category permissions can restrict
+const (
+	CategoryActionView    = "view"
+	CategoryActionPost    = "post"
+	CategoryActionComment = "comment"
+)
+
+// roleRanks orders the roles; a permission granted to a role is granted to
+// the roles above it too
+var roleRanks = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// IsValidRole reports whether role is a known user role
+func IsValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// RoleAtLeast reports whether role is minimum or a role above it
+func RoleAtLeast(role, minimum string) bool {
+	return IsValidRole(role) && roleRanks[role] >= roleRanks[minimum]
+}
+
+// IsValidCategoryAction reports whether action is one permissions restrict
+func IsValidCategoryAction(action string) bool {
+	switch action {
+	case CategoryActionView, CategoryActionPost, CategoryActionComment:
+		return true
+	}
+	return false
+}
+
+// CategoryPermission grants an action in a category either to a role, and
+// the roles above it, or to the members of a group
+type CategoryPermission struct {
+	Action    string `json:"action"`
+	Role      string `json:"role,omitempty"`
+	GroupID   int    `json:"groupId,omitempty"`
+	GroupName string `json:"groupName,omitempty"`
+}
+
+// CategoryPermissionsRequest replaces the permission rules of a category
+type CategoryPermissionsRequest struct {
+	Permissions []CategoryPermission `json:"permissions"`
+}
+
+// Validate validates permission rules, dropping repeats
+func (r *CategoryPermissionsRequest) Validate() error {
+	if len(r.Permissions) > MaxCategoryPermissions {
+		return ErrTooManyCategoryPermissions
+	}
+	seen := make(map[CategoryPermission]bool, len(r.Permissions))
+	permissions := make([]CategoryPermission, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		p.GroupName = ""
+		if !IsValidCategoryAction(p.Action) {
+			return ErrInvalidCategoryPermission
+		}
+		if (p.Role == "") == (p.GroupID == 0) || (p.Role != "" && !IsValidRole(p.Role)) || p.GroupID < 0 {
+			return ErrInvalidCategoryPermission
+		}
+		if !seen[p] {
+			seen[p] = true
+			permissions = append(permissions, p)
+		}
+	}
+	r.Permissions = permissions
+	return nil
+}
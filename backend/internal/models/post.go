@@ -6,50 +6,105 @@ import (
 
 // Post represents a forum post
 type Post struct {
-	ID           int       `json:"id" db:"id"`
-	UserID       int       `json:"userId" db:"user_id"`
-	Title        string    `json:"title" db:"title"`
-	Content      string    `json:"content" db:"content"`
-	CategoryID   int       `json:"categoryId" db:"category_id"`
-	CategoryName string    `json:"category" db:"category_name"`
-	Author       string    `json:"author" db:"nickname"`
-	AuthorColor  string    `json:"authorColor" db:"avatar_color"`
-	ReplyCount   int       `json:"reply_count" db:"comment_count"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID           int             `json:"id" db:"id"`
+	UserID       int             `json:"userId" db:"user_id"`
+	Title        string          `json:"title" db:"title"`
+	Content      string          `json:"content" db:"content"`
+	ContentHTML  string          `json:"contentHtml" db:"content_html"`
+	CategoryID   int             `json:"categoryId" db:"category_id"`
+	CategoryName string          `json:"category" db:"category_name"`
+	Author       string          `json:"author" db:"nickname"`
+	AuthorColor  string          `json:"authorColor" db:"avatar_color"`
+	AuthorAvatar string          `json:"authorAvatar"`
+	ReplyCount   int             `json:"reply_count" db:"comment_count"`
+	Score        int             `json:"score" db:"score"`
+	UserVote     int             `json:"userVote"`
+	Watching     bool            `json:"watching"`
+	Bookmarked   bool            `json:"bookmarked"`
+	Tags         []string        `json:"tags"`
+	Reactions    []ReactionCount `json:"reactions"`
+	Attachments  []Attachment    `json:"attachments,omitempty"`
+	Pinned       string          `json:"pinned,omitempty" db:"pin_scope"`
+	Locked       bool            `json:"locked"`
+	Archived     bool            `json:"archived"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
 }
 
 // Comment represents a comment on a post
 type Comment struct {
-	ID          int       `json:"id" db:"id"`
-	PostID      int       `json:"postId" db:"post_id"`
-	UserID      int       `json:"userId" db:"user_id"`
-	Content     string    `json:"content" db:"content"`
-	Author      string    `json:"author" db:"nickname"`
-	AuthorColor string    `json:"authorColor" db:"avatar_color"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID           int             `json:"id" db:"id"`
+	PostID       int             `json:"postId" db:"post_id"`
+	ParentID     int             `json:"parentId,omitempty" db:"parent_id"`
+	UserID       int             `json:"userId" db:"user_id"`
+	Content      string          `json:"content" db:"content"`
+	ContentHTML  string          `json:"contentHtml" db:"content_html"`
+	Author       string          `json:"author" db:"nickname"`
+	AuthorColor  string          `json:"authorColor" db:"avatar_color"`
+	AuthorAvatar string          `json:"authorAvatar"`
+	Score        int             `json:"score" db:"score"`
+	UserVote     int             `json:"userVote"`
+	Bookmarked   bool            `json:"bookmarked"`
+	Reactions    []ReactionCount `json:"reactions"`
+	Attachments  []Attachment    `json:"attachments,omitempty"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
 }
 
-// Category represents a post category
-type Category struct {
-	ID          int       `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+// PostListOptions controls which posts GetPosts returns and in what order
+type PostListOptions struct {
+	// PostID limits the list to a single post
+	PostID     int
+	CategoryID int
+	Sort       string
+	Range      string
+	// Watching limits the list to posts the viewer watches
+	Watching bool
+	// Tags limits the list to posts with any or, when TagMatch is all, all
+	// of these tags
+	Tags     []string
+	TagMatch string
+	// IncludeArchived lists archived posts too; a single post is found
+	// either way
+	IncludeArchived bool
+	Offset          int
+}
+
+// PostsPerPage is how many posts GetPosts returns at a time
+const PostsPerPage = 20
+
+// Post sort orders
+const (
+	SortNew = "new"
+	SortTop = "top"
+	SortHot = "hot"
+)
+
+// TopRanges maps the time ranges accepted for top sorting to their length;
+// "all" has no limit
+var TopRanges = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+	"all":   0,
 }
 
 // CreatePostRequest represents the data needed to create a new post
 type CreatePostRequest struct {
-	Title      string `json:"title"`
-	Content    string `json:"content"`
-	CategoryID int    `json:"categoryId"`
+	Title         string   `json:"title"`
+	Content       string   `json:"content"`
+	CategoryID    int      `json:"categoryId"`
+	Tags          []string `json:"tags"`
+	AttachmentIDs []int    `json:"attachmentIds"`
 }
 
 // CreateCommentRequest represents the data needed to create a new comment
 type CreateCommentRequest struct {
-	PostID  int    `json:"postId"`
-	Content string `json:"content"`
+	PostID        int    `json:"postId"`
+	ParentID      int    `json:"parentId"`
+	Content       string `json:"content"`
+	AttachmentIDs []int  `json:"attachmentIds"`
 }
 
 // Validate validates post data
@@ -63,7 +118,43 @@ func (p *CreatePostRequest) Validate() error {
 	if p.CategoryID <= 0 {
 		return ErrInvalidCategory
 	}
-	return nil
+	tags, err := NormalizeTags(p.Tags, MaxPostTags)
+	if err != nil {
+		return err
+	}
+	p.Tags = tags
+	return validateAttachmentIDs(p.AttachmentIDs)
+}
+
+// Validate validates post listing options, defaulting to the newest posts,
+// to top posts of all time and to posts with any of the tags
+func (o *PostListOptions) Validate() error {
+	switch o.Sort {
+	case "":
+		o.Sort = SortNew
+	case SortNew, SortTop, SortHot:
+	default:
+		return ErrInvalidSort
+	}
+	if o.Range == "" {
+		o.Range = "all"
+	}
+	if _, ok := TopRanges[o.Range]; !ok {
+		return ErrInvalidRange
+	}
+	switch o.TagMatch {
+	case "":
+		o.TagMatch = TagMatchAny
+	case TagMatchAny, TagMatchAll:
+	default:
+		return ErrInvalidTagMatch
+	}
+	tags, err := NormalizeTags(o.Tags, MaxFilterTags)
+	if err == ErrTooManyTags {
+		return ErrTooManyFilterTags
+	}
+	o.Tags = tags
+	return err
 }
 
 // Validate validates comment data
@@ -74,5 +165,8 @@ func (c *CreateCommentRequest) Validate() error {
 	if c.PostID <= 0 {
 		return ErrInvalidPostID
 	}
-	return nil
-}
\ No newline at end of file
+	if c.ParentID < 0 {
+		return ErrInvalidParentID
+	}
+	return validateAttachmentIDs(c.AttachmentIDs)
+}
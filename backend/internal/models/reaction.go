@@ -0,0 +1,214 @@
+package models
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Reaction target types
+const (
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+	ReactionTargetMessage = "message"
+)
+
+// maxEmojiLength caps the stored size of a reaction, enough for ZWJ sequences
+const maxEmojiLength = 32
+
+// MaxReactionEmojis caps the number of different emoji on one post, comment or message
+const MaxReactionEmojis = 20
+
+// ReactionCount is the number of users who reacted to a target with an emoji.
+// Reacted tells whether the requesting user is one of them.
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted,omitempty"`
+}
+
+// ReactionRequest represents adding or removing a reaction
+type ReactionRequest struct {
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	Emoji      string `json:"emoji"`
+}
+
+// Validate validates reaction data
+func (r *ReactionRequest) Validate() error {
+	switch r.TargetType {
+	case ReactionTargetPost, ReactionTargetComment, ReactionTargetMessage:
+	default:
+		return ErrInvalidReactionTarget
+	}
+	if r.TargetID <= 0 {
+		return ErrInvalidReactionTarget
+	}
+	if !isEmoji(r.Emoji) {
+		return ErrInvalidEmoji
+	}
+	return nil
+}
+
+// emojiRanges holds the code points that are emoji on their own: the
+// pictographic symbols of Unicode's emoji data, leaving out unassigned ones.
+// Regional indicators, skin tones and keycap parts are handled by isEmoji.
+var emojiRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00a9, Hi: 0x00a9, Stride: 1},
+		{Lo: 0x00ae, Hi: 0x00ae, Stride: 1},
+		{Lo: 0x203c, Hi: 0x203c, Stride: 1},
+		{Lo: 0x2049, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2122, Hi: 0x2122, Stride: 1},
+		{Lo: 0x2139, Hi: 0x2139, Stride: 1},
+		{Lo: 0x2194, Hi: 0x2199, Stride: 1},
+		{Lo: 0x21a9, Hi: 0x21aa, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2328, Hi: 0x2328, Stride: 1},
+		{Lo: 0x23cf, Hi: 0x23cf, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23f3, Stride: 1},
+		{Lo: 0x23f8, Hi: 0x23fa, Stride: 1},
+		{Lo: 0x24c2, Hi: 0x24c2, Stride: 1},
+		{Lo: 0x25aa, Hi: 0x25ab, Stride: 1},
+		{Lo: 0x25b6, Hi: 0x25b6, Stride: 1},
+		{Lo: 0x25c0, Hi: 0x25c0, Stride: 1},
+		{Lo: 0x25fb, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2600, Hi: 0x2605, Stride: 1},
+		{Lo: 0x2607, Hi: 0x2612, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2685, Stride: 1},
+		{Lo: 0x2690, Hi: 0x2705, Stride: 1},
+		{Lo: 0x2708, Hi: 0x2712, Stride: 1},
+		{Lo: 0x2714, Hi: 0x2714, Stride: 1},
+		{Lo: 0x2716, Hi: 0x2716, Stride: 1},
+		{Lo: 0x271d, Hi: 0x271d, Stride: 1},
+		{Lo: 0x2721, Hi: 0x2721, Stride: 1},
+		{Lo: 0x2728, Hi: 0x2728, Stride: 1},
+		{Lo: 0x2733, Hi: 0x2734, Stride: 1},
+		{Lo: 0x2744, Hi: 0x2744, Stride: 1},
+		{Lo: 0x2747, Hi: 0x2747, Stride: 1},
+		{Lo: 0x274c, Hi: 0x274c, Stride: 1},
+		{Lo: 0x274e, Hi: 0x274e, Stride: 1},
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1},
+		{Lo: 0x2757, Hi: 0x2757, Stride: 1},
+		{Lo: 0x2763, Hi: 0x2767, Stride: 1},
+		{Lo: 0x2795, Hi: 0x2797, Stride: 1},
+		{Lo: 0x27a1, Hi: 0x27a1, Stride: 1},
+		{Lo: 0x27b0, Hi: 0x27b0, Stride: 1},
+		{Lo: 0x27bf, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2b05, Hi: 0x2b07, Stride: 1},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b50, Stride: 1},
+		{Lo: 0x2b55, Hi: 0x2b55, Stride: 1},
+		{Lo: 0x3030, Hi: 0x3030, Stride: 1},
+		{Lo: 0x303d, Hi: 0x303d, Stride: 1},
+		{Lo: 0x3297, Hi: 0x3297, Stride: 1},
+		{Lo: 0x3299, Hi: 0x3299, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f004, Hi: 0x1f004, Stride: 1},
+		{Lo: 0x1f0cf, Hi: 0x1f0cf, Stride: 1},
+		{Lo: 0x1f170, Hi: 0x1f171, Stride: 1},
+		{Lo: 0x1f17e, Hi: 0x1f17f, Stride: 1},
+		{Lo: 0x1f18e, Hi: 0x1f18e, Stride: 1},
+		{Lo: 0x1f191, Hi: 0x1f19a, Stride: 1},
+		{Lo: 0x1f201, Hi: 0x1f202, Stride: 1},
+		{Lo: 0x1f21a, Hi: 0x1f21a, Stride: 1},
+		{Lo: 0x1f22f, Hi: 0x1f22f, Stride: 1},
+		{Lo: 0x1f232, Hi: 0x1f23a, Stride: 1},
+		{Lo: 0x1f250, Hi: 0x1f251, Stride: 1},
+		{Lo: 0x1f300, Hi: 0x1f3fa, Stride: 1},
+		{Lo: 0x1f400, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1},
+		{Lo: 0x1f7e0, Hi: 0x1f7f0, Stride: 1},
+		{Lo: 0x1f90c, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x1fa70, Hi: 0x1faff, Stride: 1},
+	},
+	LatinOffset: 2,
+}
+
+// Emoji sequence parts
+const (
+	zeroWidthJoiner   = '\u200d'
+	variationSelector = '\ufe0f'
+	textSelector      = '\ufe0e'
+	keycapMark        = '\u20e3'
+	blackFlag         = '\U0001f3f4'
+	cancelTag         = '\U000e007f'
+)
+
+// isEmoji reports whether s is a single emoji: a pictograph with an optional
+// variation selector and skin tone, a keycap, a flag, or such emoji joined
+// into one with zero-width joiners
+func isEmoji(s string) bool {
+	if s == "" || len(s) > maxEmojiLength || !utf8.ValidString(s) {
+		return false
+	}
+	runes := []rune(s)
+	for i := 0; ; i++ {
+		n := emojiElement(runes[i:])
+		if n == 0 {
+			return false
+		}
+		i += n
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != zeroWidthJoiner {
+			return false
+		}
+	}
+}
+
+// emojiElement returns the number of runes the emoji at the start of runes
+// takes up, or 0 if it does not start with one
+func emojiElement(runes []rune) int {
+	if len(runes) == 0 {
+		return 0
+	}
+	r := runes[0]
+	switch {
+	case r >= '0' && r <= '9' || r == '#' || r == '*':
+		// Keycap: digit, '#' or '*', an optional variation selector and the keycap mark
+		n := 1
+		if n < len(runes) && runes[n] == variationSelector {
+			n++
+		}
+		if n < len(runes) && runes[n] == keycapMark {
+			return n + 1
+		}
+		return 0
+	case isRegionalIndicator(r):
+		// Country flag: a pair of regional indicators
+		if len(runes) > 1 && isRegionalIndicator(runes[1]) {
+			return 2
+		}
+		return 0
+	case r == blackFlag && len(runes) > 1 && isTag(runes[1]):
+		// Subdivision flag: black flag, tag letters and a cancel tag
+		for n := 1; n < len(runes); n++ {
+			if runes[n] == cancelTag {
+				return n + 1
+			}
+			if !isTag(runes[n]) {
+				return 0
+			}
+		}
+		return 0
+	case unicode.Is(emojiRanges, r):
+		n := 1
+		if n < len(runes) && (runes[n] == variationSelector || runes[n] == textSelector) {
+			n++
+		}
+		if n < len(runes) && isSkinTone(runes[n]) {
+			n++
+		}
+		return n
+	}
+	return 0
+}
+
+func isRegionalIndicator(r rune) bool { return r >= 0x1f1e6 && r <= 0x1f1ff }
+
+func isSkinTone(r rune) bool { return r >= 0x1f3fb && r <= 0x1f3ff }
+
+func isTag(r rune) bool { return r >= 0xe0020 && r <= 0xe007e }
@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestIsEmoji(t *testing.T) {
+	tests := []struct {
+		name  string
+		emoji string
+		want  bool
+	}{
+		{"pictograph", "👍", true},
+		{"symbol with variation selector", "❤️", true},
+		{"symbol without variation selector", "❤", true},
+		{"skin tone", "👍🏽", true},
+		{"keycap", "1️⃣", true},
+		{"keycap without variation selector", "#⃣", true},
+		{"country flag", "🇫🇷", true},
+		{"subdivision flag", "🏴󠁧󠁢󠁳󠁣󠁴󠁿", true},
+		{"ZWJ family", "👨‍👩‍👧", true},
+		{"ZWJ with skin tone", "👩🏾‍💻", true},
+		{"rainbow flag", "🏳️‍🌈", true},
+		{"copyright", "©️", true},
+		{"empty", "", false},
+		{"ASCII", "ok", false},
+		{"digit without keycap", "1", false},
+		{"CJK", "好", false},
+		{"kana", "ア", false},
+		{"hangul", "한", false},
+		{"latin letter", "é", false},
+		{"two emoji", "👍👍", false},
+		{"emoji and text", "👍a", false},
+		{"lone skin tone", "🏽", false},
+		{"lone regional indicator", "🇫", false},
+		{"lone variation selector", "️", false},
+		{"dangling joiner", "👍‍", false},
+		{"leading joiner", "‍👍", false},
+		{"joined with text", "👍‍好", false},
+		{"unterminated tag sequence", "🏴󠁧󠁢", false},
+		{"non-emoji symbol", "✓", false},
+		{"invalid UTF-8", "\xff", false},
+		{"too long", "👨‍👩‍👧‍👦👨‍👩‍👧‍👦", false},
+	}
+	for _, tt := range tests {
+		if got := isEmoji(tt.emoji); got != tt.want {
+			t.Errorf("isEmoji(%q) [%s] = %v, want %v", tt.emoji, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Report target types
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+	ReportTargetMessage = "message"
+	ReportTargetUser    = "user"
+)
+
+// Report reasons
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonHate          = "hate"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonOther         = "other"
+)
+
+// ReportReasons lists every report reason
+var ReportReasons = []string{
+	ReportReasonSpam,
+	ReportReasonHarassment,
+	ReportReasonHate,
+	ReportReasonInappropriate,
+	ReportReasonOther,
+}
+
+// Report statuses: a report stays open until a moderator either acted on it
+// or dismissed it
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusActioned  = "actioned"
+	ReportStatusDismissed = "dismissed"
+)
+
+// Report limits, in characters
+const (
+	MaxReportNoteLength     = 1000
+	MaxResolutionNoteLength = 1000
+)
+
+// Report is a user's report of a post, comment, message or user.
+// ReportedUserID is the user reported or the author of the reported
+// content. PostID and ConversationID name where the content was, and Link
+// where moderators can find it; messages are private, so they have no
+// link and moderators go by the Excerpt, taken when the report was made.
+type Report struct {
+	ID               int        `json:"id"`
+	ReporterID       int        `json:"reporterId"`
+	ReporterName     string     `json:"reporterName"`
+	TargetType       string     `json:"targetType"`
+	TargetID         int        `json:"targetId"`
+	ReportedUserID   int        `json:"reportedUserId"`
+	ReportedUserName string     `json:"reportedUserName"`
+	PostID           int        `json:"postId,omitempty"`
+	ConversationID   int        `json:"conversationId,omitempty"`
+	Link             string     `json:"link,omitempty"`
+	Excerpt          string     `json:"excerpt"`
+	Reason           string     `json:"reason"`
+	Note             string     `json:"note"`
+	Status           string     `json:"status"`
+	AssigneeID       int        `json:"assigneeId,omitempty"`
+	AssigneeName     string     `json:"assigneeName,omitempty"`
+	ResolutionNote   string     `json:"resolutionNote"`
+	ResolvedBy       int        `json:"resolvedBy,omitempty"`
+	ResolvedAt       *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+}
+
+// ReportLink returns where the reported content of a report can be found:
+// the post for posts and comments, and nothing for messages and users
+func ReportLink(targetType string, postID int) string {
+	switch targetType {
+	case ReportTargetPost:
+		return "/api/posts?post_id=" + strconv.Itoa(postID)
+	case ReportTargetComment:
+		return "/api/comments?post_id=" + strconv.Itoa(postID)
+	}
+	return ""
+}
+
+// ReportList is a page of the moderation queue with the number of open
+// reports in it
+type ReportList struct {
+	Reports   []Report `json:"reports"`
+	OpenCount int      `json:"openCount"`
+}
+
+// ReportListOptions controls which reports the moderation queue lists
+type ReportListOptions struct {
+	// Status limits the list to open, actioned or dismissed reports
+	Status string
+	// AssigneeID limits the list to the reports assigned to one moderator
+	AssigneeID int
+	// TargetType limits the list to reports of posts, comments, messages
+	// or users
+	TargetType string
+	Offset     int
+}
+
+// ReportRequest represents a user reporting a post, comment, message or
+// user
+type ReportRequest struct {
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	Reason     string `json:"reason"`
+	Note       string `json:"note"`
+}
+
+// Validate validates report data. A report for another reason must say
+// what is wrong.
+func (r *ReportRequest) Validate() error {
+	if !IsValidReportTarget(r.TargetType) || r.TargetID <= 0 {
+		return ErrInvalidReportTarget
+	}
+	if !IsValidReportReason(r.Reason) {
+		return ErrInvalidReportReason
+	}
+	r.Note = strings.TrimSpace(r.Note)
+	if utf8.RuneCountInString(r.Note) > MaxReportNoteLength {
+		return ErrReportNoteTooLong
+	}
+	if r.Reason == ReportReasonOther && r.Note == "" {
+		return ErrReportNoteRequired
+	}
+	return nil
+}
+
+// UpdateReportRequest represents a moderator working on a report; fields
+// left out are unchanged. An AssigneeID of 0 unassigns the report.
+type UpdateReportRequest struct {
+	Status         *string `json:"status"`
+	AssigneeID     *int    `json:"assigneeId"`
+	ResolutionNote *string `json:"resolutionNote"`
+}
+
+// Validate validates report changes
+func (r *UpdateReportRequest) Validate() error {
+	if r.Status == nil && r.AssigneeID == nil && r.ResolutionNote == nil {
+		return ErrNoReportChanges
+	}
+	if r.Status != nil && !IsValidReportStatus(*r.Status) {
+		return ErrInvalidReportStatus
+	}
+	if r.AssigneeID != nil && *r.AssigneeID < 0 {
+		return ErrInvalidReportAssignee
+	}
+	if r.ResolutionNote != nil {
+		note := strings.TrimSpace(*r.ResolutionNote)
+		if utf8.RuneCountInString(note) > MaxResolutionNoteLength {
+			return ErrResolutionNoteTooLong
+		}
+		r.ResolutionNote = &note
+	}
+	return nil
+}
+
+// IsValidReportTarget reports whether things of this type can be reported
+func IsValidReportTarget(targetType string) bool {
+	switch targetType {
+	case ReportTargetPost, ReportTargetComment, ReportTargetMessage, ReportTargetUser:
+		return true
+	}
+	return false
+}
+
+// IsValidReportReason reports whether reason is a known report reason
+func IsValidReportReason(reason string) bool {
+	for _, known := range ReportReasons {
+		if reason == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidReportStatus reports whether status is a known report status
+func IsValidReportStatus(status string) bool {
+	return status == ReportStatusOpen || status == ReportStatusActioned || status == ReportStatusDismissed
+}
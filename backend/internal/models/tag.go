@@ -0,0 +1,128 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tag limits
+const (
+	// MaxPostTags caps the tags on one post
+	MaxPostTags = 5
+	// MaxFilterTags caps the tags a post list can be filtered by
+	MaxFilterTags = 10
+	// MaxTagLength caps the length of a tag, in characters
+	MaxTagLength = 30
+)
+
+// Tag filter modes: posts with any or with all of the given tags
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// Tag directory orders
+const (
+	TagSortPopular = "popular"
+	TagSortName    = "name"
+)
+
+// Tag is a tag with the number of posts carrying it and the other names
+// that stand for it
+type Tag struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	PostCount int       `json:"postCount"`
+	Synonyms  []string  `json:"synonyms"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// TagListOptions controls which tags the tag directory lists
+type TagListOptions struct {
+	// Prefix limits the list to tags, or tags with a synonym, starting with it
+	Prefix string
+	Sort   string
+	Offset int
+}
+
+// Validate validates tag directory options, defaulting to the most used tags
+func (o *TagListOptions) Validate() error {
+	switch o.Sort {
+	case "":
+		o.Sort = TagSortPopular
+	case TagSortPopular, TagSortName:
+	default:
+		return ErrInvalidSort
+	}
+	o.Prefix = NormalizeTag(o.Prefix)
+	return nil
+}
+
+// TagSynonymRequest represents making Synonym another name for Tag
+type TagSynonymRequest struct {
+	Synonym string `json:"synonym"`
+	Tag     string `json:"tag"`
+}
+
+// Validate normalizes both names and checks they differ
+func (r *TagSynonymRequest) Validate() error {
+	r.Synonym = NormalizeTag(r.Synonym)
+	r.Tag = NormalizeTag(r.Tag)
+	if !IsValidTag(r.Synonym) || !IsValidTag(r.Tag) {
+		return ErrInvalidTag
+	}
+	if r.Synonym == r.Tag {
+		return ErrInvalidTagSynonym
+	}
+	return nil
+}
+
+// NormalizeTag turns free-form text into a tag name: lower case, without a
+// leading #, with runs of spaces, underscores, slashes and dashes turned into
+// one dash, and with anything but letters, digits and the + . # of names like
+// c++, node.js and c# dropped
+func NormalizeTag(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimLeft(strings.ToLower(strings.TrimSpace(text)), "#") {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '.' || r == '#':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_' || r == '/':
+			dash = true
+		}
+	}
+	return strings.Trim(b.String(), ".")
+}
+
+// IsValidTag reports whether a normalized tag name can be stored
+func IsValidTag(name string) bool {
+	return name != "" && utf8.RuneCountInString(name) <= MaxTagLength
+}
+
+// NormalizeTags normalizes tags and drops repeats, keeping their order. It
+// fails if any tag is left empty or too long, or if more than max remain.
+func NormalizeTags(tags []string, max int) ([]string, error) {
+	names := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		name := NormalizeTag(tag)
+		if !IsValidTag(name) {
+			return nil, ErrInvalidTag
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) > max {
+		return nil, ErrTooManyTags
+	}
+	return names, nil
+}
@@ -1,9 +1,38 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
+// Direct message privacy settings: who may start a conversation with a user
+const (
+	DMPrivacyEveryone  = "everyone"
+	DMPrivacyFollowing = "following"
+	DMPrivacyNobody    = "nobody"
+)
+
+// User roles. Moderators look after content; admins can also manage the
+// forum itself, and can do anything moderators can.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// DefaultAvatarColor is given to users who do not pick a color
+const DefaultAvatarColor = "blue-500"
+
+// AvatarColors maps the accepted avatar color classes to the colors used
+// for generated avatars
+var AvatarColors = map[string]string{
+	"blue-500":   "#3b82f6",
+	"green-500":  "#22c55e",
+	"purple-500": "#a855f7",
+	"red-500":    "#ef4444",
+	"yellow-500": "#eab308",
+}
+
 // User data structures, validation, and business logic
 type User struct {
 	ID           int       `json:"id" db:"id"`
@@ -15,6 +44,10 @@ type User struct {
 	Gender       string    `json:"gender" db:"gender"`
 	PasswordHash string    `json:"-" db:"password_hash"`
 	AvatarColor  string    `json:"avatarColor" db:"avatar_color"`
+	AvatarKey    string    `json:"-" db:"avatar_key"`
+	AvatarURL    string    `json:"avatarUrl"`
+	DMPrivacy    string    `json:"dmPrivacy" db:"dm_privacy"`
+	Role         string    `json:"role" db:"role"`
 	IsOnline     bool      `json:"isOnline" db:"is_online"`
 	LastSeen     time.Time `json:"lastSeen" db:"last_seen"`
 	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
@@ -59,6 +92,12 @@ func (u *User) ValidateUser() error {
 	if u.Gender == "" {
 		return ErrInvalidGender
 	}
+	if u.AvatarColor == "" {
+		u.AvatarColor = DefaultAvatarColor
+	}
+	if !IsValidAvatarColor(u.AvatarColor) {
+		return ErrInvalidAvatarColor
+	}
 	return nil
 }
 
@@ -73,6 +112,32 @@ func IsValidGender(gender string) bool {
 	return false
 }
 
+// IsValidDMPrivacy checks if the direct message privacy value is valid
+func IsValidDMPrivacy(privacy string) bool {
+	switch privacy {
+	case DMPrivacyEveryone, DMPrivacyFollowing, DMPrivacyNobody:
+		return true
+	}
+	return false
+}
+
+// IsValidAvatarColor checks if the avatar color is one of AvatarColors
+func IsValidAvatarColor(color string) bool {
+	_, ok := AvatarColors[color]
+	return ok
+}
+
+// AvatarURL returns the URL of a user's avatar. Uploaded avatars carry
+// their key so a new upload changes the URL; users without one get a
+// generated avatar from the same endpoint.
+func AvatarURL(userID int, avatarKey string) string {
+	url := fmt.Sprintf("/api/avatars?user_id=%d", userID)
+	if avatarKey != "" {
+		url += "&v=" + avatarKey
+	}
+	return url
+}
+
 // ValidateRegisterRequest validates registration data
 func (r *RegisterRequest) Validate() error {
 	if r.FirstName == "" {
@@ -96,6 +161,12 @@ func (r *RegisterRequest) Validate() error {
 	if len(r.Password) < 8 {
 		return ErrInvalidPassword
 	}
+	if r.AvatarColor == "" {
+		r.AvatarColor = DefaultAvatarColor
+	}
+	if !IsValidAvatarColor(r.AvatarColor) {
+		return ErrInvalidAvatarColor
+	}
 	return nil
 }
 
@@ -109,3 +180,44 @@ func (r *LoginRequest) Validate() error {
 	}
 	return nil
 }
+
+// RestrictedUser is an entry in a user's block, mute or follow list
+type RestrictedUser struct {
+	ID          int       `json:"id"`
+	Nickname    string    `json:"nickname"`
+	AvatarColor string    `json:"avatarColor"`
+	AvatarURL   string    `json:"avatarUrl"`
+	Since       time.Time `json:"since"`
+}
+
+// RestrictUserRequest represents blocking or muting a user
+type RestrictUserRequest struct {
+	UserID int `json:"userId"`
+}
+
+// Validate validates block and mute data for the requesting user
+func (r *RestrictUserRequest) Validate(requesterID int) error {
+	if r.UserID <= 0 {
+		return ErrInvalidUserID
+	}
+	if r.UserID == requesterID {
+		return ErrSelfBlock
+	}
+	return nil
+}
+
+// FollowRequest represents following or unfollowing a user
+type FollowRequest struct {
+	UserID int `json:"userId"`
+}
+
+// Validate validates follow data for the requesting user
+func (r *FollowRequest) Validate(requesterID int) error {
+	if r.UserID <= 0 {
+		return ErrInvalidUserID
+	}
+	if r.UserID == requesterID {
+		return ErrSelfFollow
+	}
+	return nil
+}
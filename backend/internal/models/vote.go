@@ -0,0 +1,36 @@
+package models
+
+// Vote target types
+const (
+	VoteTargetPost    = "post"
+	VoteTargetComment = "comment"
+)
+
+// VoteRequest represents casting, changing or clearing (Value 0) a vote
+type VoteRequest struct {
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	Value      int    `json:"value"`
+}
+
+// VoteResult is a target's score after a vote, with the voter's current vote
+type VoteResult struct {
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	Score      int    `json:"score"`
+	UserVote   int    `json:"userVote"`
+}
+
+// Validate validates vote data
+func (r *VoteRequest) Validate() error {
+	if r.TargetType != VoteTargetPost && r.TargetType != VoteTargetComment {
+		return ErrInvalidVoteTarget
+	}
+	if r.TargetID <= 0 {
+		return ErrInvalidVoteTarget
+	}
+	if r.Value < -1 || r.Value > 1 {
+		return ErrInvalidVoteValue
+	}
+	return nil
+}
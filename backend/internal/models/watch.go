@@ -0,0 +1,20 @@
+package models
+
+// WatchRequest represents a post to watch
+type WatchRequest struct {
+	PostID int `json:"postId"`
+}
+
+// Validate validates a watch request
+func (r *WatchRequest) Validate() error {
+	if r.PostID <= 0 {
+		return ErrInvalidPostID
+	}
+	return nil
+}
+
+// WatchSettings controls which posts a user starts watching on their own
+type WatchSettings struct {
+	AutoWatchPosts    bool `json:"autoWatchPosts"`
+	AutoWatchComments bool `json:"autoWatchComments"`
+}
@@ -0,0 +1,177 @@
+package uploads
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+	"golang.org/x/image/draw"
+)
+
+// AvatarSizes lists the square sizes, in pixels, every avatar is stored in
+var AvatarSizes = []int{32, 64, 128, 256}
+
+// AvatarKey returns the storage key of one size of an avatar
+func AvatarKey(avatarKey string, size int) string {
+	return fmt.Sprintf("avatars/%s_%d.png", avatarKey, size)
+}
+
+// AvatarSize picks the smallest stored size at least as large as the
+// requested one, or the largest size for bigger requests
+func AvatarSize(requested int) int {
+	for _, size := range AvatarSizes {
+		if size >= requested {
+			return size
+		}
+	}
+	return AvatarSizes[len(AvatarSizes)-1]
+}
+
+// SaveAvatar checks an uploaded image, crops it to a square and stores it
+// in every avatar size. crop selects the square in image pixels; an empty
+// rectangle takes the largest centered square. It returns the new avatar key.
+func (s *Service) SaveAvatar(r io.Reader, crop image.Rectangle) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.Limits.MaxFileSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) == 0 {
+		return "", ErrEmptyFile
+	}
+	if int64(len(data)) > s.Limits.MaxFileSize {
+		return "", ErrFileTooLarge
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil || !IsImage(contentType) {
+		return "", ErrUnsupportedType
+	}
+	img, err := s.decodeImage(data)
+	if err != nil {
+		return "", err
+	}
+
+	square, err := cropSquare(img.Bounds(), crop)
+	if err != nil {
+		return "", err
+	}
+	cropped := image.NewRGBA(image.Rect(0, 0, square.Dx(), square.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, square.Min, draw.Src)
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	key := id.String()
+
+	for _, size := range AvatarSizes {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, Resize(cropped, size, size)); err != nil {
+			s.RemoveAvatar(key)
+			return "", err
+		}
+		if err := s.Store.Save(AvatarKey(key, size), &buf); err != nil {
+			s.RemoveAvatar(key)
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// RemoveAvatar deletes every stored size of an avatar
+func (s *Service) RemoveAvatar(avatarKey string) error {
+	for _, size := range AvatarSizes {
+		if err := s.Store.Delete(AvatarKey(avatarKey, size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cropSquare checks a requested crop against the image bounds, making it
+// square, or picks the largest centered square when none was requested
+func cropSquare(bounds, crop image.Rectangle) (image.Rectangle, error) {
+	if crop.Empty() {
+		side := min(bounds.Dx(), bounds.Dy())
+		x := bounds.Min.X + (bounds.Dx()-side)/2
+		y := bounds.Min.Y + (bounds.Dy()-side)/2
+		return image.Rect(x, y, x+side, y+side), nil
+	}
+
+	crop = crop.Add(bounds.Min)
+	if !crop.In(bounds) {
+		return image.Rectangle{}, ErrInvalidCrop
+	}
+	side := min(crop.Dx(), crop.Dy())
+	return image.Rect(crop.Min.X, crop.Min.Y, crop.Min.X+side, crop.Min.Y+side), nil
+}
+
+// Initials returns up to two uppercase initials for a name: the first
+// letters of its first two words, or its first letter
+func Initials(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var initials []rune
+	for _, word := range words {
+		initials = append(initials, unicode.ToUpper([]rune(word)[0]))
+		if len(initials) == 2 {
+			break
+		}
+	}
+	if len(initials) == 0 {
+		return "?"
+	}
+	return string(initials)
+}
+
+// InitialsAvatar renders a square SVG avatar with a name's initials on a
+// background color given as "#rrggbb"
+func InitialsAvatar(name, background string, size int) []byte {
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="%[1]d" viewBox="0 0 100 100">`+
+		`<rect width="100" height="100" fill="%[2]s"/>`+
+		`<text x="50" y="50" dy=".35em" text-anchor="middle" font-family="sans-serif" font-size="42" fill="#ffffff">%[3]s</text>`+
+		`</svg>`, size, html.EscapeString(background), html.EscapeString(Initials(name))))
+}
+
+// Identicon renders a square PNG avatar with a symmetric 5x5 pattern
+// derived from a seed, so every user gets a stable, distinct image
+func Identicon(seed string, size int) ([]byte, error) {
+	sum := sha256.Sum256([]byte(seed))
+	fg := color.RGBA{R: sum[0]/2 + 64, G: sum[1]/2 + 64, B: sum[2]/2 + 64, A: 255}
+	bg := color.RGBA{R: 240, G: 240, B: 240, A: 255}
+
+	const cells = 5
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			row, col := y*cells/size, x*cells/size
+			// Mirror the left three columns onto the right two
+			if col > cells/2 {
+				col = cells - 1 - col
+			}
+			bit := row*3 + col
+			if sum[3+bit/8]>>(bit%8)&1 == 1 {
+				img.Set(x, y, fg)
+			} else {
+				img.Set(x, y, bg)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
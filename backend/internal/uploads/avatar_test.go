@@ -0,0 +1,91 @@
+package uploads
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSaveAvatar(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		crop    image.Rectangle
+		wantErr error
+	}{
+		{"centered square", "photo.jpg", image.Rectangle{}, nil},
+		{"requested crop", "pixels.png", image.Rect(1, 0, 3, 2), nil},
+		{"crop outside the image", "pixels.png", image.Rect(3, 0, 6, 2), ErrInvalidCrop},
+		{"PNG named .exe", "pixels.exe", image.Rectangle{}, nil},
+		{"not an image", "notes.txt", image.Rectangle{}, ErrUnsupportedType},
+		{"over 40 MP", "huge-area.png", image.Rectangle{}, ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			key, err := s.SaveAvatar(fixture(t, tt.fixture), tt.crop)
+			if err != tt.wantErr {
+				t.Fatalf("SaveAvatar() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for _, size := range AvatarSizes {
+				if got := storedImage(t, s, AvatarKey(key, size)); got != image.Pt(size, size) {
+					t.Errorf("avatar size %d stored as %v", size, got)
+				}
+			}
+
+			if err := s.RemoveAvatar(key); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Store.Open(AvatarKey(key, AvatarSizes[0])); err == nil {
+				t.Error("avatar still stored after RemoveAvatar")
+			}
+		})
+	}
+}
+
+func TestCropSquare(t *testing.T) {
+	bounds := image.Rect(0, 0, 6, 3)
+	tests := []struct {
+		name    string
+		crop    image.Rectangle
+		want    image.Rectangle
+		wantErr error
+	}{
+		{"largest centered square", image.Rectangle{}, image.Rect(1, 0, 4, 3), nil},
+		{"square crop", image.Rect(2, 0, 5, 3), image.Rect(2, 0, 5, 3), nil},
+		{"made square", image.Rect(0, 0, 3, 2), image.Rect(0, 0, 2, 2), nil},
+		{"outside", image.Rect(4, 0, 7, 3), image.Rectangle{}, ErrInvalidCrop},
+	}
+	for _, tt := range tests {
+		got, err := cropSquare(bounds, tt.crop)
+		if err != tt.wantErr || got != tt.want {
+			t.Errorf("%s: cropSquare() = %v, %v; want %v, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAvatarSize(t *testing.T) {
+	for requested, want := range map[int]int{1: 32, 32: 32, 33: 64, 200: 256, 1000: 256} {
+		if got := AvatarSize(requested); got != want {
+			t.Errorf("AvatarSize(%d) = %d, want %d", requested, got, want)
+		}
+	}
+}
+
+func TestInitials(t *testing.T) {
+	tests := map[string]string{
+		"alice":        "A",
+		"bob smith":    "BS",
+		"jean-luc pic": "JL",
+		"élodie":       "É",
+		"__":           "?",
+		"":             "?",
+	}
+	for name, want := range tests {
+		if got := Initials(name); got != want {
+			t.Errorf("Initials(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package uploads
+
+import (
+	"context"
+	"log"
+	"real-time-forum/backend/internal/database"
+	"time"
+)
+
+// RunCleanup periodically deletes uploads that were never attached within
+// maxAge, and the files of deleted messages, posts and comments, until ctx
+// is done
+func (s *Service) RunCleanup(ctx context.Context, interval, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.cleanup(maxAge)
+		}
+	}
+}
+
+// cleanup removes the orphaned attachments found in one pass
+func (s *Service) cleanup(maxAge time.Duration) {
+	attachments, err := database.GetOrphanedAttachments(time.Now().Add(-maxAge))
+	if err != nil {
+		log.Printf("Error finding orphaned attachments: %v", err)
+		return
+	}
+
+	for _, a := range attachments {
+		if err := s.Remove(a.StorageKey, a.ThumbnailKey); err != nil {
+			log.Printf("Error removing files of attachment %d: %v", a.ID, err)
+			continue
+		}
+		if err := database.DeleteAttachmentRecord(a.ID); err != nil {
+			log.Printf("Error deleting attachment %d: %v", a.ID, err)
+		}
+	}
+	if len(attachments) > 0 {
+		log.Printf("Removed %d orphaned attachments", len(attachments))
+	}
+}
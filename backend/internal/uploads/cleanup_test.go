@@ -0,0 +1,108 @@
+package uploads
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"testing"
+	"time"
+)
+
+// TestMain runs the tests against a fresh database in a temporary directory,
+// migrated from the repository's migrations folder
+func TestMain(m *testing.M) {
+	migrations, err := filepath.Abs("../../../migrations")
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir, err := os.MkdirTemp("", "forum-uploads-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Symlink(migrations, filepath.Join(dir, "migrations")); err != nil {
+		log.Fatal(err)
+	}
+
+	// Fixtures are read relative to the package, the database from dir
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatal(err)
+	}
+	if err := database.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(wd); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	database.CloseDB()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestCleanupRemovesOrphanedAttachments(t *testing.T) {
+	var uploaderID int
+	err := database.DB.QueryRow(`
+		INSERT INTO users (nickname, email, password_hash, first_name, last_name, age, gender)
+		VALUES ('uploader', 'uploader@example.com', '', 'Test', 'User', 30, 'other')
+		RETURNING id
+	`).Scan(&uploaderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService(t)
+	upload := func() *models.Attachment {
+		t.Helper()
+		file, err := s.Save(fixture(t, "pixels.png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := &models.Attachment{UploaderID: uploaderID, Filename: "pixels.png", ContentType: file.ContentType,
+			StorageKey: file.StorageKey, ThumbnailKey: file.ThumbnailKey}
+		if err := database.CreateAttachment(a); err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	stale, fresh, ofDeletedPost := upload(), upload(), upload()
+	if _, err := database.DB.Exec("UPDATE attachments SET created_at = datetime('now', '-2 hours') WHERE id = ?", stale.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.DB.Exec("UPDATE attachments SET target_type = 'post', target_id = 999999 WHERE id = ?", ofDeletedPost.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	s.cleanup(time.Hour)
+
+	tests := []struct {
+		name       string
+		attachment *models.Attachment
+		kept       bool
+	}{
+		{"unused upload past the cutoff", stale, false},
+		{"recent unused upload", fresh, true},
+		{"attachment of a deleted post", ofDeletedPost, false},
+	}
+	for _, tt := range tests {
+		_, err := database.GetAttachment(tt.attachment.ID)
+		if kept := err == nil; kept != tt.kept {
+			t.Errorf("%s: record kept = %v (%v), want %v", tt.name, kept, err, tt.kept)
+		}
+		for _, key := range []string{tt.attachment.StorageKey, tt.attachment.ThumbnailKey} {
+			f, err := s.Store.Open(key)
+			if err == nil {
+				f.Close()
+			}
+			if kept := err == nil; kept != tt.kept {
+				t.Errorf("%s: file %s kept = %v, want %v", tt.name, key, kept, tt.kept)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package uploads
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorageRejectsKeysOutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStorage(filepath.Join(dir, "root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "../escape", "files/../../escape", "/etc/passwd", "files/..", ".."} {
+		if err := store.Save(key, strings.NewReader("x")); err != ErrInvalidKey {
+			t.Errorf("Save(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+		if _, err := store.Open(key); err != ErrInvalidKey {
+			t.Errorf("Open(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+		if err := store.Delete(key); err != ErrInvalidKey {
+			t.Errorf("Delete(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape")); !os.IsNotExist(err) {
+		t.Error("a file was written outside the storage root")
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"first", "second"} {
+		if err := store.Save("files/a/b", strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+		f, err := store.Open("files/a/b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(f)
+		f.Close()
+		if err != nil || string(got) != content {
+			t.Errorf("Open() read %q, %v; want %q", got, err, content)
+		}
+	}
+
+	if err := store.Delete("files/a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open("files/a/b"); !os.IsNotExist(err) {
+		t.Errorf("Open() error = %v after Delete, want not exist", err)
+	}
+	if err := store.Delete("files/a/b"); err != nil {
+		t.Errorf("Delete() of a missing file error = %v, want none", err)
+	}
+}
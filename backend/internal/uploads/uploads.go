@@ -0,0 +1,246 @@
+package uploads
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+	"golang.org/x/image/draw"
+
+	// Register the decoders for the other accepted image formats
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Upload errors
+var (
+	ErrEmptyFile       = errors.New("file is empty")
+	ErrFileTooLarge    = errors.New("file is too large")
+	ErrUnsupportedType = errors.New("unsupported file type")
+	ErrInvalidImage    = errors.New("image could not be decoded")
+	ErrImageTooLarge   = errors.New("image dimensions are too large")
+	ErrInvalidCrop     = errors.New("crop area is outside the image")
+)
+
+// allowedTypes lists the sniffed content types accepted for upload
+var allowedTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// Limits controls which uploads are accepted and how thumbnails are made
+type Limits struct {
+	// MaxFileSize is the largest accepted file in bytes
+	MaxFileSize int64
+	// MaxDimension caps the width and height of images, and MaxPixels their
+	// area, so small files cannot decode into huge bitmaps
+	MaxDimension int
+	MaxPixels    int
+	// ThumbnailSize is the longest side of generated thumbnails
+	ThumbnailSize int
+}
+
+// DefaultLimits returns the limits used by NewService
+func DefaultLimits() Limits {
+	return Limits{
+		MaxFileSize:   10 << 20,
+		MaxDimension:  8000,
+		MaxPixels:     40_000_000,
+		ThumbnailSize: 320,
+	}
+}
+
+// Service checks uploaded files and keeps them in a Storage
+type Service struct {
+	Store  Storage
+	Limits Limits
+}
+
+// NewService creates an upload service with the default limits
+func NewService(store Storage) *Service {
+	return &Service{Store: store, Limits: DefaultLimits()}
+}
+
+// File is an accepted and stored upload
+type File struct {
+	StorageKey   string
+	ThumbnailKey string
+	ContentType  string
+	Size         int64
+	Width        int
+	Height       int
+}
+
+// IsImage reports whether a content type is one of the accepted image formats
+func IsImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") && allowedTypes[contentType]
+}
+
+// Save checks an upload and stores it with a thumbnail for images. The
+// content type comes from the file's bytes, never from the client.
+func (s *Service) Save(r io.Reader) (*File, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.Limits.MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrEmptyFile
+	}
+	if int64(len(data)) > s.Limits.MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil || !allowedTypes[contentType] {
+		return nil, ErrUnsupportedType
+	}
+	if contentType == "text/plain" && !utf8.Valid(data) {
+		return nil, ErrUnsupportedType
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	file := &File{
+		StorageKey:  "files/" + id.String(),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	if IsImage(contentType) {
+		img, err := s.decodeImage(data)
+		if err != nil {
+			return nil, err
+		}
+		file.Width, file.Height = img.Bounds().Dx(), img.Bounds().Dy()
+
+		thumb, ext, err := encodeThumbnail(img, contentType, s.Limits.ThumbnailSize)
+		if err != nil {
+			return nil, err
+		}
+		file.ThumbnailKey = "thumbs/" + id.String() + ext
+		if err := s.Store.Save(file.ThumbnailKey, thumb); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.Store.Save(file.StorageKey, bytes.NewReader(data)); err != nil {
+		if file.ThumbnailKey != "" {
+			s.Store.Delete(file.ThumbnailKey)
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Remove deletes a stored upload and its thumbnail
+func (s *Service) Remove(storageKey, thumbnailKey string) error {
+	if thumbnailKey != "" {
+		if err := s.Store.Delete(thumbnailKey); err != nil {
+			return err
+		}
+	}
+	return s.Store.Delete(storageKey)
+}
+
+// decodeImage checks an image's dimensions from its header before decoding it
+func (s *Service) decodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if config.Width > s.Limits.MaxDimension || config.Height > s.Limits.MaxDimension ||
+		config.Width*config.Height > s.Limits.MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	return img, nil
+}
+
+// Resize scales an image to the given size
+func Resize(img image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+	return dst
+}
+
+// encodeThumbnail shrinks an image to fit within size pixels on its longest
+// side, never enlarging it. JPEG photos stay JPEG; everything else becomes
+// PNG to keep transparency.
+func encodeThumbnail(img image.Image, contentType string, size int) (io.Reader, string, error) {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+	thumb := Resize(img, width, height)
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+			return nil, "", err
+		}
+		return &buf, ".jpg", nil
+	}
+	if err := png.Encode(&buf, thumb); err != nil {
+		return nil, "", err
+	}
+	return &buf, ".png", nil
+}
+
+// CleanFilename reduces a client-supplied file name to a safe display name
+func CleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		name = "file"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// ContentDisposition returns the Content-Disposition header for serving a
+// file: images are shown inline, anything else is downloaded
+func ContentDisposition(contentType, filename string) string {
+	kind := "attachment"
+	if IsImage(contentType) {
+		kind = "inline"
+	}
+	if header := mime.FormatMediaType(kind, map[string]string{"filename": filename}); header != "" {
+		return header
+	}
+	return kind
+}
@@ -0,0 +1,186 @@
+package uploads
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// Register the decoders the thumbnails are read back with
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// newTestService creates a service storing files in a temporary directory
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	store, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(store)
+}
+
+// fixture opens a file from testdata
+func fixture(t *testing.T, name string) io.Reader {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(data)
+}
+
+// storedImage decodes a stored image and returns its size
+func storedImage(t *testing.T, s *Service, key string) image.Point {
+	t.Helper()
+	f, err := s.Store.Open(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatalf("stored image %s: %v", key, err)
+	}
+	return img.Bounds().Size()
+}
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      func(t *testing.T) io.Reader
+		wantErr   error
+		wantType  string
+		wantSize  image.Point
+		wantThumb string
+	}{
+		{"PNG", func(t *testing.T) io.Reader { return fixture(t, "pixels.png") }, nil, "image/png", image.Pt(4, 2), ".png"},
+		{"PNG named .exe", func(t *testing.T) io.Reader { return fixture(t, "pixels.exe") }, nil, "image/png", image.Pt(4, 2), ".png"},
+		{"JPEG", func(t *testing.T) io.Reader { return fixture(t, "photo.jpg") }, nil, "image/jpeg", image.Pt(6, 3), ".jpg"},
+		{"plain text", func(t *testing.T) io.Reader { return fixture(t, "notes.txt") }, nil, "text/plain", image.Point{}, ""},
+		{"text that is not UTF-8", func(t *testing.T) io.Reader { return fixture(t, "latin1.txt") }, ErrUnsupportedType, "", image.Point{}, ""},
+		{"HTML", func(t *testing.T) io.Reader { return fixture(t, "page.html") }, ErrUnsupportedType, "", image.Point{}, ""},
+		{"wider than 8000 px", func(t *testing.T) io.Reader { return fixture(t, "wide.png") }, ErrImageTooLarge, "", image.Point{}, ""},
+		{"over 40 MP", func(t *testing.T) io.Reader { return fixture(t, "huge-area.png") }, ErrImageTooLarge, "", image.Point{}, ""},
+		{"empty", func(t *testing.T) io.Reader { return strings.NewReader("") }, ErrEmptyFile, "", image.Point{}, ""},
+		{"over 10 MB", func(t *testing.T) io.Reader {
+			return io.LimitReader(repeatReader('a'), DefaultLimits().MaxFileSize+1)
+		}, ErrFileTooLarge, "", image.Point{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			file, err := s.Save(tt.file(t))
+			if err != tt.wantErr {
+				t.Fatalf("Save() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if file.ContentType != tt.wantType {
+				t.Errorf("ContentType = %q, want %q", file.ContentType, tt.wantType)
+			}
+			if got := image.Pt(file.Width, file.Height); got != tt.wantSize {
+				t.Errorf("size = %v, want %v", got, tt.wantSize)
+			}
+			if !strings.HasSuffix(file.ThumbnailKey, tt.wantThumb) || (tt.wantThumb == "") != (file.ThumbnailKey == "") {
+				t.Errorf("ThumbnailKey = %q, want one ending in %q", file.ThumbnailKey, tt.wantThumb)
+			}
+			if _, err := s.Store.Open(file.StorageKey); err != nil {
+				t.Errorf("stored file: %v", err)
+			}
+		})
+	}
+}
+
+// repeatReader endlessly returns the same byte
+type repeatReader byte
+
+func (r repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+func TestSaveThumbnails(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		size      int
+		wantThumb image.Point
+	}{
+		{"pixels.png", 2, image.Pt(2, 1)},
+		{"photo.jpg", 2, image.Pt(2, 1)},
+		{"photo.jpg", 3, image.Pt(3, 1)},
+		// Small images are never enlarged
+		{"pixels.png", 320, image.Pt(4, 2)},
+	}
+	for _, tt := range tests {
+		s := newTestService(t)
+		s.Limits.ThumbnailSize = tt.size
+		file, err := s.Save(fixture(t, tt.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := storedImage(t, s, file.ThumbnailKey); got != tt.wantThumb {
+			t.Errorf("thumbnail of %s at %d px = %v, want %v", tt.fixture, tt.size, got, tt.wantThumb)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	s := newTestService(t)
+	file, err := s.Save(fixture(t, "pixels.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove(file.StorageKey, file.ThumbnailKey); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{file.StorageKey, file.ThumbnailKey} {
+		if _, err := s.Store.Open(key); !os.IsNotExist(err) {
+			t.Errorf("Open(%q) error = %v after Remove, want not exist", key, err)
+		}
+	}
+}
+
+func TestCleanFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"photo.png", "photo.png"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\report.pdf`, "report.pdf"},
+		{"quote\".txt", "quote.txt"},
+		{"line\nbreak.txt", "linebreak.txt"},
+		{"", "file"},
+		{"/", "file"},
+		{strings.Repeat("é", 200), strings.Repeat("é", 127)},
+	}
+	for _, tt := range tests {
+		if got := CleanFilename(tt.name); got != tt.want {
+			t.Errorf("CleanFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"image/png", `inline; filename=a.png`},
+		{"application/pdf", `attachment; filename=a.png`},
+		{"text/plain", `attachment; filename=a.png`},
+	}
+	for _, tt := range tests {
+		if got := ContentDisposition(tt.contentType, "a.png"); got != tt.want {
+			t.Errorf("ContentDisposition(%q) = %q, want %q", tt.contentType, got, tt.want)
+		}
+	}
+}
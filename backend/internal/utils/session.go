@@ -26,7 +26,8 @@ func CreateSession(userID int) (string, error) {
 	return sessionID, nil
 }
 
-// GetUserIDFromSession retrieves user ID from session cookie
+// GetUserIDFromSession retrieves user ID from session cookie. Banned users
+// have no valid session.
 func GetUserIDFromSession(r *http.Request) (int, error) {
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
@@ -37,6 +38,7 @@ func GetUserIDFromSession(r *http.Request) (int, error) {
 	err = database.DB.QueryRow(`
 		SELECT user_id FROM sessions 
 		WHERE id = ? AND expires_at > CURRENT_TIMESTAMP
+			AND user_id NOT IN (`+database.BannedUsersQuery+`)
 	`, cookie.Value).Scan(&userID)
 
 	if err != nil {
@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"real-time-forum/backend/internal/database"
+	"sync"
+	"time"
+)
+
+// userFilter holds who a user should not see or hear from
+type userFilter struct {
+	// blocked holds users with a block in either direction; they see neither
+	// each other's presence nor typing and cannot message each other
+	blocked map[int]bool
+	// hidden holds users whose posts and comments this user blocked or muted
+	hidden map[int]bool
+}
+
+// filterCache caches block and mute lists of connected users, and the
+// users whose content a ban hides from everyone
+type filterCache struct {
+	mu    sync.Mutex
+	users map[int]*userFilter
+	// banned is reloaded once bannedUntil, when the first temporary ban in
+	// it runs out, has passed; nil means it needs loading
+	banned      map[int]bool
+	bannedUntil time.Time
+}
+
+// newFilterCache creates an empty cache
+func newFilterCache() *filterCache {
+	return &filterCache{users: make(map[int]*userFilter)}
+}
+
+// userFilter returns the cached filter of a user, loading it on first use
+func (h *Hub) userFilter(userID int) *userFilter {
+	h.filters.mu.Lock()
+	filter, ok := h.filters.users[userID]
+	h.filters.mu.Unlock()
+	if ok {
+		return filter
+	}
+
+	filter = &userFilter{blocked: make(map[int]bool), hidden: make(map[int]bool)}
+	blocked, err := database.GetBlockRelations(userID)
+	if err != nil {
+		log.Printf("Error loading blocks of user %d: %v", userID, err)
+		return filter
+	}
+	hidden, err := database.GetHiddenAuthors(userID)
+	if err != nil {
+		log.Printf("Error loading hidden authors of user %d: %v", userID, err)
+		return filter
+	}
+	for _, id := range blocked {
+		filter.blocked[id] = true
+	}
+	for _, id := range hidden {
+		filter.hidden[id] = true
+	}
+
+	h.filters.mu.Lock()
+	h.filters.users[userID] = filter
+	h.filters.mu.Unlock()
+	return filter
+}
+
+// forgetFilters drops cached filters so they are reloaded on next use
+func (h *Hub) forgetFilters(userIDs ...int) {
+	h.filters.mu.Lock()
+	for _, id := range userIDs {
+		delete(h.filters.users, id)
+	}
+	h.filters.mu.Unlock()
+}
+
+// contentBanned reports whether a ban hides a user's content from
+// everyone, reloading the banned users when a temporary ban has run out
+func (h *Hub) contentBanned(userID int) bool {
+	h.filters.mu.Lock()
+	defer h.filters.mu.Unlock()
+
+	expired := !h.filters.bannedUntil.IsZero() && !time.Now().Before(h.filters.bannedUntil)
+	if h.filters.banned == nil || expired {
+		banned, until, err := database.GetContentHidingBans()
+		if err != nil {
+			log.Printf("Error loading banned users: %v", err)
+			return false
+		}
+		h.filters.banned, h.filters.bannedUntil = banned, until
+	}
+	return h.filters.banned[userID]
+}
+
+// forgetBans drops the cached banned users after a ban or unban
+func (h *Hub) forgetBans() {
+	h.filters.mu.Lock()
+	h.filters.banned = nil
+	h.filters.mu.Unlock()
+}
+
+// isBlocked reports whether either user has blocked the other
+func (h *Hub) isBlocked(userID, otherUserID int) bool {
+	return h.userFilter(userID).blocked[otherUserID]
+}
+
+// hidesAuthor reports whether viewerID blocked or muted authorID, or a ban
+// hides authorID's content
+func (h *Hub) hidesAuthor(viewerID, authorID int) bool {
+	return h.userFilter(viewerID).hidden[authorID] || h.contentBanned(authorID)
+}
+
+// sendFiltered sends a message to the given clients, skipping those for which skip is true
+func (h *Hub) sendFiltered(clients []*Client, message interface{}, skip func(*Client) bool) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	for _, client := range clients {
+		if !skip(client) {
+			client.SendRaw(data)
+		}
+	}
+}
+
+// SetBlocked blocks or unblocks a user and refreshes the presence both
+// users see
+func (h *Hub) SetBlocked(userID, targetID int, blocked bool) error {
+	var err error
+	if blocked {
+		err = database.BlockUser(userID, targetID)
+	} else {
+		err = database.UnblockUser(userID, targetID)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.forgetFilters(userID, targetID)
+	h.broadcastOnlineUsers()
+	h.refreshRoomPresence(userID, targetID)
+	return nil
+}
+
+// NotifyProfileUpdated refreshes the presence lists that show a user after
+// their nickname, color or avatar changed
+func (h *Hub) NotifyProfileUpdated(userID int) {
+	h.broadcastOnlineUsers()
+	h.refreshRoomPresence(userID)
+}
+
+// SetMuted mutes or unmutes a user. The muted user is not told.
+func (h *Hub) SetMuted(userID, targetID int, muted bool) error {
+	var err error
+	if muted {
+		err = database.MuteUser(userID, targetID)
+	} else {
+		err = database.UnmuteUser(userID, targetID)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.forgetFilters(userID)
+	return nil
+}
+
+// refreshRoomPresence resends the viewer lists of every room the given users are in
+func (h *Hub) refreshRoomPresence(userIDs ...int) {
+	users := make(map[int]bool)
+	for _, id := range userIDs {
+		users[id] = true
+	}
+
+	h.mu.RLock()
+	rooms := make(map[string]bool)
+	for client := range h.clients {
+		if users[client.userID] {
+			for name := range client.rooms {
+				rooms[name] = true
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for name := range rooms {
+		h.broadcastRoomPresence(name)
+	}
+}
@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+)
+
+// NotifyCategoriesUpdated sends every connected user the category list as
+// they see it after categories, their permissions or user groups changed,
+// and takes them out of the rooms of posts they may no longer view
+func (h *Hub) NotifyCategoriesUpdated() {
+	h.leaveHiddenRooms()
+
+	messages := make(map[int][]byte)
+	for _, client := range h.clientSnapshot() {
+		data, ok := messages[client.userID]
+		if !ok {
+			categories, err := database.GetCategories(client.userID, false)
+			if err != nil {
+				log.Printf("Error getting categories for user %d: %v", client.userID, err)
+				continue
+			}
+			data, err = json.Marshal(WebSocketMessage{
+				Type: EventTypeCategoriesUpdated,
+				Data: CategoriesUpdatedEvent{Categories: categories},
+			})
+			if err != nil {
+				log.Printf("Error marshaling categories event: %v", err)
+				continue
+			}
+			messages[client.userID] = data
+		}
+		client.SendRaw(data)
+	}
+}
+
+// leaveHiddenRooms removes clients from the rooms they would no longer be
+// allowed to join
+func (h *Hub) leaveHiddenRooms() {
+	h.mu.RLock()
+	members := make(map[string][]*Client, len(h.rooms))
+	for name, room := range h.rooms {
+		for client := range room.clients {
+			members[name] = append(members[name], client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for name, clients := range members {
+		for _, client := range clients {
+			if !h.canJoinRoom(client, name) {
+				h.LeaveRoom(client, name)
+			}
+		}
+	}
+}
+
+// hidesPost returns a skip function for sendFiltered that leaves out
+// clients whose user may not view a post, checking each user once
+func (h *Hub) hidesPost(postID int) func(*Client) bool {
+	hidden := make(map[int]bool)
+	return func(client *Client) bool {
+		hide, checked := hidden[client.userID]
+		if !checked {
+			hide = !h.canViewPost(client.userID, postID)
+			hidden[client.userID] = hide
+		}
+		return hide
+	}
+}
+
+// canViewPost reports whether a user may view a post, given the
+// permissions of its category
+func (h *Hub) canViewPost(userID, postID int) bool {
+	err := database.CheckPostAccess(userID, postID, models.CategoryActionView)
+	if err != nil && err != database.ErrPostNotFound {
+		log.Printf("Error checking access of user %d to post %d: %v", userID, postID, err)
+	}
+	return err == nil
+}
+
+// canViewTarget reports whether a user may view a post, comment or message
+// target; only posts and comments are restricted by category
+func (h *Hub) canViewTarget(userID int, targetType string, targetID int) bool {
+	postID := targetID
+	switch targetType {
+	case models.MentionTargetPost:
+	case models.MentionTargetComment:
+		id, err := database.GetCommentPostID(targetID)
+		if err != nil {
+			log.Printf("Error getting post of comment %d: %v", targetID, err)
+			return false
+		}
+		postID = id
+	default:
+		return true
+	}
+	return h.canViewPost(userID, postID)
+}
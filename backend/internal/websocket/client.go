@@ -3,8 +3,11 @@ package websocket
 import (
 	"encoding/json"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -20,30 +23,77 @@ const (
 
 	// Maximum message size allowed from peer
 	maxMessageSize = 512
+
+	// Number of outbound frames buffered per client
+	sendBufferSize = 256
 )
 
-// Client represents an individual WebSocket client with read/write pumps and heartbeat
+// Client represents an individual WebSocket client with read/write pumps and heartbeat.
+// SSE and long-poll clients use the same type with a nil conn; their HTTP
+// handlers drain the send queue instead of writePump.
+//
+// The send channel is owned by the hub: only Hub.unregisterClient closes it,
+// through closeSend. Everyone else enqueues frames via SendMessage, SendRaw or
+// SendPresence, which never block and never close the channel.
 type Client struct {
-	hub    *Hub
-	conn   *websocket.Conn
-	userID int
-	send   chan []byte
+	hub       *Hub
+	conn      *websocket.Conn
+	id        string
+	transport string
+	userID    int
+	send      chan []byte
+	rooms     map[string]bool
+	poll      *longPollState
+	limits    clientLimits
+
+	mu       sync.Mutex
+	closed   bool
+	kicked   bool
+	kickCode int
+	kickMsg  string
+	closeMsg []byte
+	policy   DropPolicy
+	presence map[string][]byte
+	wake     chan struct{}
+	dropped  atomic.Uint64
 }
 
-// NewClient creates a new WebSocket client
+// NewClient creates a new WebSocket client using the hub's default drop policy
 func NewClient(hub *Hub, conn *websocket.Conn, userID int) *Client {
+	hub.mu.RLock()
+	policy := hub.defaultDropPolicy
+	hub.mu.RUnlock()
+
 	return &Client{
-		hub:    hub,
-		conn:   conn,
-		userID: userID,
-		send:   make(chan []byte, 256),
+		hub:       hub,
+		conn:      conn,
+		id:        uuid.Must(uuid.NewV4()).String(),
+		transport: TransportWebSocket,
+		userID:    userID,
+		send:      make(chan []byte, sendBufferSize),
+		rooms:     make(map[string]bool),
+		policy:    policy,
+		presence:  make(map[string][]byte),
+		wake:      make(chan struct{}, 1),
 	}
 }
 
+// SetDropPolicy changes what happens when the client's send queue is full
+func (c *Client) SetDropPolicy(policy DropPolicy) {
+	c.mu.Lock()
+	c.policy = policy
+	c.mu.Unlock()
+}
+
+// DroppedFrames returns the number of frames dropped for this client
+func (c *Client) DroppedFrames() uint64 {
+	return c.dropped.Load()
+}
+
 // readPump pumps messages from the websocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.UnregisterClient(c)
 		c.conn.Close()
 	}()
 
@@ -75,32 +125,26 @@ func (c *Client) writePump() {
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		c.hub.pumps.Done()
 	}()
 
 	for {
 		select {
 		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				c.conn.WriteMessage(websocket.CloseMessage, c.closeFrame())
 				return
 			}
-
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+			if err := c.writeFrame(message); err != nil {
 				return
 			}
-			w.Write(message)
 
-			// Add queued chat messages to the current websocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
-				return
+		case <-c.wake:
+			for _, frame := range c.takePresence() {
+				if err := c.writeFrame(frame); err != nil {
+					return
+				}
 			}
 
 		case <-ticker.C:
@@ -112,6 +156,12 @@ func (c *Client) writePump() {
 	}
 }
 
+// writeFrame writes a single text frame to the connection
+func (c *Client) writeFrame(data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
 // handleMessage processes incoming WebSocket messages
 func (c *Client) handleMessage(msg map[string]interface{}) {
 	msgType, ok := msg["type"].(string)
@@ -119,13 +169,45 @@ func (c *Client) handleMessage(msg map[string]interface{}) {
 		return
 	}
 
-	switch EventType(msgType) {
+	eventType := EventType(msgType)
+	if c.isKicked() {
+		return
+	}
+	if !c.hub.allowEvent(c, eventType) {
+		c.hub.rejectEvent(c, eventType)
+		return
+	}
+
+	// The frontend nests event fields under "data"; lift them to the top level
+	if data, ok := msg["data"].(map[string]interface{}); ok {
+		for key, value := range data {
+			if _, exists := msg[key]; !exists {
+				msg[key] = value
+			}
+		}
+	}
+
+	switch eventType {
 	case EventTypePrivateMessage:
 		c.hub.HandlePrivateMessage(c, msg)
 	case EventTypeTyping:
 		c.hub.HandleTyping(c, msg)
 	case EventTypeStopTyping:
 		c.hub.HandleStopTyping(c, msg)
+	case EventTypeMarkRead:
+		c.hub.HandleMarkRead(c, msg)
+	case EventTypeEditMessage:
+		c.hub.HandleEditMessage(c, msg)
+	case EventTypeDeleteMessage:
+		c.hub.HandleDeleteMessage(c, msg)
+	case EventTypeJoinRoom:
+		c.hub.HandleJoinRoom(c, msg)
+	case EventTypeLeaveRoom:
+		c.hub.HandleLeaveRoom(c, msg)
+	case EventTypeRoomTyping:
+		c.hub.HandleRoomTyping(c, msg)
+	case EventTypeRoomStopTyping:
+		c.hub.HandleRoomStopTyping(c, msg)
 	}
 }
 
@@ -136,17 +218,162 @@ func (c *Client) SendMessage(message interface{}) error {
 		return err
 	}
 
+	return c.SendRaw(data)
+}
+
+// SendRaw queues an already encoded message for the client without blocking.
+// When the queue is full the client's drop policy decides what happens.
+func (c *Client) SendRaw(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClientDisconnected
+	}
+
+	for {
+		select {
+		case c.send <- data:
+			return nil
+		default:
+		}
+
+		if c.policy != DropPolicyDropOldest {
+			c.recordDrop()
+			if !c.kicked {
+				c.hub.metrics.slowDisconnects.Add(1)
+				log.Printf("Disconnecting slow client: user %d (%d frames dropped)", c.userID, c.dropped.Load())
+			}
+			c.kickLocked(websocket.CloseTryAgainLater, "send queue overflow")
+			return ErrSendQueueFull
+		}
+
+		// Make room by discarding the oldest queued frame
+		select {
+		case <-c.send:
+			c.recordDrop()
+		default:
+		}
+	}
+}
+
+// SendPresence queues a presence update identified by key. Under the
+// coalesce policy only the latest pending update per key is kept, so presence
+// churn never fills the send queue; otherwise it behaves like SendRaw.
+func (c *Client) SendPresence(key string, data []byte) error {
+	c.mu.Lock()
+	if c.policy != DropPolicyCoalescePresence {
+		c.mu.Unlock()
+		return c.SendRaw(data)
+	}
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClientDisconnected
+	}
+	if _, pending := c.presence[key]; pending {
+		c.hub.metrics.framesCoalesced.Add(1)
+	}
+	c.presence[key] = data
+	c.mu.Unlock()
+
 	select {
-	case c.send <- data:
-		return nil
+	case c.wake <- struct{}{}:
 	default:
-		close(c.send)
-		return ErrClientDisconnected
 	}
+	return nil
+}
+
+// takePresence removes and returns the pending coalesced presence frames
+func (c *Client) takePresence() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	frames := make([][]byte, 0, len(c.presence))
+	for key, frame := range c.presence {
+		frames = append(frames, frame)
+		delete(c.presence, key)
+	}
+	return frames
+}
+
+// recordDrop counts a dropped frame for the client and the hub
+func (c *Client) recordDrop() {
+	c.dropped.Add(1)
+	c.hub.metrics.framesDropped.Add(1)
+}
+
+// kick asks the hub to disconnect the client with the given close code
+func (c *Client) kick(code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kickLocked(code, reason)
+}
+
+// kickLocked asks the hub to disconnect the client with the given close
+// code. The caller must hold c.mu.
+func (c *Client) kickLocked(code int, reason string) {
+	if c.kicked {
+		return
+	}
+	c.kicked = true
+	c.kickCode = code
+	c.kickMsg = reason
+
+	// Unregister asynchronously: the caller may be the hub's own goroutine
+	go c.hub.UnregisterClient(c)
+}
+
+// isKicked reports whether the client is being disconnected
+func (c *Client) isKicked() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.kicked
+}
+
+// isClosed reports whether the hub has closed the client's send channel
+func (c *Client) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// closeSend closes the send channel exactly once, using the close code of
+// a pending kick if there is one. Only the hub calls this.
+func (c *Client) closeSend() {
+	c.mu.Lock()
+	code, reason := websocket.CloseNormalClosure, ""
+	if c.kicked {
+		code, reason = c.kickCode, c.kickMsg
+	}
+	c.mu.Unlock()
+
+	c.closeSendWith(code, reason)
+}
+
+// closeSendWith closes the send channel and records the close code and
+// reason writePump sends once the queue has drained.
+func (c *Client) closeSendWith(code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeMsg = websocket.FormatCloseMessage(code, reason)
+	close(c.send)
+}
+
+// closeFrame returns the payload of the close frame to send to the peer
+func (c *Client) closeFrame() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeMsg
 }
 
 // Run starts the client's read and write pumps
 func (c *Client) Run() {
+	c.hub.pumps.Add(1)
 	go c.writePump()
 	go c.readPump()
 }
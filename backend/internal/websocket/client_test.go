@@ -0,0 +1,161 @@
+package websocket
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestClient creates a client with a small send queue and the given drop policy
+func newTestClient(h *Hub, queue int, policy DropPolicy) *Client {
+	c := NewClient(h, nil, 1)
+	c.send = make(chan []byte, queue)
+	c.SetDropPolicy(policy)
+	return c
+}
+
+// queued drains and returns the frames waiting in a client's send queue
+func queued(c *Client) []string {
+	var frames []string
+	for {
+		select {
+		case frame := <-c.send:
+			frames = append(frames, string(frame))
+		default:
+			return frames
+		}
+	}
+}
+
+func TestDropPolicyDisconnect(t *testing.T) {
+	h := newTestHub(t, DefaultRateLimitConfig())
+	c := newTestClient(h, 2, DropPolicyDisconnect)
+
+	for i := 0; i < 2; i++ {
+		if err := c.SendRaw([]byte{byte('a' + i)}); err != nil {
+			t.Fatalf("SendRaw() error = %v with room in the queue", err)
+		}
+	}
+	if err := c.SendRaw([]byte("c")); err != ErrSendQueueFull {
+		t.Errorf("SendRaw() error = %v on a full queue, want %v", err, ErrSendQueueFull)
+	}
+	c.mu.Lock()
+	kicked, code := c.kicked, c.kickCode
+	c.mu.Unlock()
+	if !kicked || code != websocket.CloseTryAgainLater {
+		t.Errorf("client kicked = %v with code %d, want code %d", kicked, code, websocket.CloseTryAgainLater)
+	}
+	if got := c.DroppedFrames(); got != 1 {
+		t.Errorf("DroppedFrames() = %d, want 1", got)
+	}
+}
+
+func TestDropPolicyDropOldest(t *testing.T) {
+	h := newTestHub(t, DefaultRateLimitConfig())
+	c := newTestClient(h, 2, DropPolicyDropOldest)
+
+	for _, frame := range []string{"a", "b", "c", "d", "e"} {
+		if err := c.SendRaw([]byte(frame)); err != nil {
+			t.Fatalf("SendRaw(%q) error = %v", frame, err)
+		}
+	}
+	if got := queued(c); fmt.Sprint(got) != "[d e]" {
+		t.Errorf("queue = %v, want the newest frames [d e]", got)
+	}
+	if got := c.DroppedFrames(); got != 3 {
+		t.Errorf("DroppedFrames() = %d, want 3", got)
+	}
+	if c.isKicked() {
+		t.Error("client was kicked under the drop oldest policy")
+	}
+}
+
+func TestDropPolicyDropOldestConcurrent(t *testing.T) {
+	h := newTestHub(t, DefaultRateLimitConfig())
+	c := newTestClient(h, 8, DropPolicyDropOldest)
+
+	const senders, frames = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < frames; j++ {
+				c.SendRaw([]byte(fmt.Sprintf("%d:%d", i, j)))
+			}
+		}(i)
+	}
+	// Drain alongside the senders like writePump would
+	received := 0
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for drained := false; !drained; {
+		select {
+		case <-c.send:
+			received++
+		case <-done:
+			received += len(queued(c))
+			drained = true
+		}
+	}
+
+	if total := uint64(received) + c.DroppedFrames(); total != senders*frames {
+		t.Errorf("%d frames received and %d dropped, want %d in total", received, c.DroppedFrames(), senders*frames)
+	}
+}
+
+func TestDropPolicyCoalescePresence(t *testing.T) {
+	h := newTestHub(t, DefaultRateLimitConfig())
+	c := newTestClient(h, 2, DropPolicyCoalescePresence)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.SendPresence("online_users", []byte(fmt.Sprintf("online %d", i)))
+		}(i)
+	}
+	wg.Wait()
+	c.SendPresence("online_users", []byte("online latest"))
+	c.SendPresence("room_presence:post:1", []byte("room"))
+
+	frames := c.takePresence()
+	if len(frames) != 2 {
+		t.Fatalf("takePresence() = %d frames, want one per key", len(frames))
+	}
+	got := map[string]bool{string(frames[0]): true, string(frames[1]): true}
+	if !got["online latest"] || !got["room"] {
+		t.Errorf("takePresence() = %q, want the latest frame per key", frames)
+	}
+	if len(queued(c)) != 0 {
+		t.Error("presence updates went into the send queue")
+	}
+	if len(c.takePresence()) != 0 {
+		t.Error("takePresence() returned frames twice")
+	}
+
+	// Other frames still overflow into a disconnect
+	for i := 0; i < 3; i++ {
+		c.SendRaw([]byte("message"))
+	}
+	if !c.isKicked() {
+		t.Error("client with a full queue was not kicked")
+	}
+}
+
+func TestSendPresenceWithoutCoalescing(t *testing.T) {
+	h := newTestHub(t, DefaultRateLimitConfig())
+	c := newTestClient(h, 4, DropPolicyDropOldest)
+
+	c.SendPresence("online_users", []byte("first"))
+	c.SendPresence("online_users", []byte("second"))
+	if got := queued(c); fmt.Sprint(got) != "[first second]" {
+		t.Errorf("queue = %v, want every presence frame queued", got)
+	}
+}
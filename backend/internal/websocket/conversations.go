@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"log"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+)
+
+// sendToConversation delivers a message to the active participants of a
+// conversation, skipping exceptUserID
+func (h *Hub) sendToConversation(conversationID int, message interface{}, exceptUserID int) {
+	ids, err := database.GetConversationParticipantIDs(conversationID)
+	if err != nil {
+		log.Printf("Error getting participants of conversation %d: %v", conversationID, err)
+		return
+	}
+
+	for _, id := range ids {
+		if id != exceptUserID {
+			h.SendToUser(id, message)
+		}
+	}
+}
+
+// sendToConversationFrom delivers a message or typing event from senderID
+// to the other participants who accepted the conversation, skipping anyone
+// with a block between them and the sender. Participants who still have the
+// conversation in their message requests get request instead, if set.
+func (h *Hub) sendToConversationFrom(conversationID int, message interface{}, senderID int, request interface{}) {
+	statuses, err := database.GetParticipantStatuses(conversationID)
+	if err != nil {
+		log.Printf("Error getting participants of conversation %d: %v", conversationID, err)
+		return
+	}
+
+	for id, status := range statuses {
+		if id == senderID || h.isBlocked(senderID, id) {
+			continue
+		}
+		switch {
+		case status == models.ParticipantAccepted:
+			h.SendToUser(id, message)
+		case status == models.ParticipantPending && request != nil:
+			h.SendToUser(id, request)
+		}
+	}
+}
+
+// HandleMarkRead handles a participant reporting the last message they read
+func (h *Hub) HandleMarkRead(client *Client, msg map[string]interface{}) {
+	conversationID, ok := msg["conversationId"].(float64)
+	if !ok {
+		return
+	}
+	messageID, ok := msg["messageId"].(float64)
+	if !ok {
+		return
+	}
+
+	if err := h.MarkRead(client.userID, int(conversationID), int(messageID)); err != nil {
+		client.SendMessage(WebSocketMessage{
+			Type: EventTypeError,
+			Data: ErrorEvent{Event: EventTypeMarkRead, Message: err.Error()},
+		})
+	}
+}
+
+// MarkRead advances a participant's read position and tells the other
+// participants and the user's other connections
+func (h *Hub) MarkRead(userID, conversationID, messageID int) error {
+	lastRead, err := database.MarkConversationRead(conversationID, userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	h.sendToConversation(conversationID, WebSocketMessage{
+		Type: EventTypeConversationRead,
+		Data: ConversationReadEvent{
+			ConversationID:    conversationID,
+			UserID:            userID,
+			LastReadMessageID: lastRead,
+		},
+	}, 0)
+
+	// Reading the conversation also reads its direct message notifications
+	if n, err := database.MarkConversationNotificationsRead(userID, conversationID, lastRead); err != nil {
+		log.Printf("Error marking notifications read: %v", err)
+	} else if n > 0 {
+		h.NotifyNotificationsRead(userID)
+	}
+	return nil
+}
+
+// NotifyConversationUpdated sends the current title and members of a
+// conversation to its participants and to any former members given
+func (h *Hub) NotifyConversationUpdated(conversationID, actorID int, formerMemberIDs ...int) {
+	ids, err := database.GetConversationParticipantIDs(conversationID)
+	if err != nil {
+		log.Printf("Error getting participants of conversation %d: %v", conversationID, err)
+		return
+	}
+
+	// Load the conversation as seen by any current member
+	viewerID := actorID
+	if len(ids) > 0 {
+		viewerID = ids[0]
+	}
+	event := ConversationUpdatedEvent{ConversationID: conversationID}
+	if conversation, err := database.GetConversation(conversationID, viewerID); err == nil {
+		event.Title = conversation.Title
+		event.IsGroup = conversation.IsGroup
+		event.Participants = conversation.Participants
+	}
+
+	response := WebSocketMessage{Type: EventTypeConversationUpdated, Data: event}
+	h.SendToUsers(append(ids, formerMemberIDs...), response)
+}
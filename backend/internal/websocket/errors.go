@@ -7,4 +7,8 @@ var (
 	ErrClientDisconnected = errors.New("client disconnected")
 	ErrInvalidMessage     = errors.New("invalid message format")
 	ErrUnauthorized       = errors.New("unauthorized websocket connection")
+	ErrInvalidRoom        = errors.New("invalid or unavailable room")
+	ErrSendQueueFull      = errors.New("client send queue full")
+	ErrHubClosed          = errors.New("hub is shutting down")
+	ErrRateLimited        = errors.New("too many messages, slow down")
 )
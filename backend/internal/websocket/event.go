@@ -1,6 +1,9 @@
 package websocket
 
-import "time"
+import (
+	"real-time-forum/backend/internal/models"
+	"time"
+)
 
 // WebSocket event types and message structure definitions
 type EventType string
@@ -15,6 +18,42 @@ const (
 	EventTypeStopTyping     EventType = "stop_typing"
 	EventTypeNewPost        EventType = "new_post"
 	EventTypeNewComment     EventType = "new_comment"
+	EventTypeJoinRoom       EventType = "join_room"
+	EventTypeLeaveRoom      EventType = "leave_room"
+	EventTypeRoomPresence   EventType = "room_presence"
+	EventTypeRoomTyping     EventType = "room_typing"
+	EventTypeRoomStopTyping EventType = "room_stop_typing"
+	EventTypeError          EventType = "error"
+	EventTypeConnected      EventType = "connected"
+	EventTypeServerShutdown EventType = "server_shutdown"
+
+	EventTypeMarkRead            EventType = "mark_read"
+	EventTypeConversationRead    EventType = "conversation_read"
+	EventTypeConversationUpdated EventType = "conversation_updated"
+
+	EventTypeEditMessage    EventType = "edit_message"
+	EventTypeDeleteMessage  EventType = "delete_message"
+	EventTypeMessageUpdated EventType = "message_updated"
+	EventTypeMessageDeleted EventType = "message_deleted"
+
+	EventTypeReactionUpdated EventType = "reaction_updated"
+	EventTypeScoreUpdated    EventType = "score_updated"
+
+	EventTypeMessageRequest EventType = "message_request"
+
+	EventTypeMention EventType = "mention"
+
+	EventTypeNotification      EventType = "notification"
+	EventTypeNotificationsRead EventType = "notifications_read"
+
+	EventTypeCategoriesUpdated EventType = "categories_updated"
+
+	EventTypePostStateUpdated EventType = "post_state_updated"
+
+	EventTypeReportCreated EventType = "report_created"
+	EventTypeReportUpdated EventType = "report_updated"
+
+	EventTypeAccountBanned EventType = "account_banned"
 )
 
 // WebSocketMessage represents a generic WebSocket message
@@ -31,36 +70,72 @@ type PrivateMessageEvent struct {
 
 // NewMessageEvent represents a new message notification
 type NewMessageEvent struct {
-	ID          int       `json:"id"`
-	SenderID    int       `json:"senderId"`
-	RecipientID int       `json:"recipientId"`
-	Content     string    `json:"content"`
-	Sender      string    `json:"sender"`
-	Timestamp   time.Time `json:"timestamp"`
+	ID             int                 `json:"id"`
+	ConversationID int                 `json:"conversationId"`
+	SenderID       int                 `json:"senderId"`
+	RecipientID    int                 `json:"recipientId,omitempty"`
+	Content        string              `json:"content"`
+	Sender         string              `json:"sender"`
+	Attachments    []models.Attachment `json:"attachments,omitempty"`
+	Timestamp      time.Time           `json:"timestamp"`
 }
 
 // NewPostEvent represents a new post notification
 type NewPostEvent struct {
-	ID           int       `json:"id"`
-	UserID       int       `json:"userId"`
-	Title        string    `json:"title"`
-	Content      string    `json:"content"`
-	CategoryID   int       `json:"categoryId"`
-	CategoryName string    `json:"categoryName"`
-	Nickname     string    `json:"nickname"`
-	AvatarColor  string    `json:"avatarColor"`
-	Timestamp    time.Time `json:"timestamp"`
+	ID           int                 `json:"id"`
+	UserID       int                 `json:"userId"`
+	Title        string              `json:"title"`
+	Content      string              `json:"content"`
+	ContentHTML  string              `json:"contentHtml"`
+	CategoryID   int                 `json:"categoryId"`
+	CategoryName string              `json:"categoryName"`
+	Tags         []string            `json:"tags"`
+	Nickname     string              `json:"nickname"`
+	AvatarColor  string              `json:"avatarColor"`
+	AvatarURL    string              `json:"avatarUrl"`
+	Attachments  []models.Attachment `json:"attachments,omitempty"`
+	Timestamp    time.Time           `json:"timestamp"`
+}
+
+// CategoriesUpdatedEvent carries the category list after a change
+type CategoriesUpdatedEvent struct {
+	Categories []models.Category `json:"categories"`
+}
+
+// PostStateUpdatedEvent carries a post's moderation state after a
+// moderator pinned, locked or archived it
+type PostStateUpdatedEvent struct {
+	models.PostState
+	ModeratorID int `json:"moderatorId"`
+}
+
+// ReportEvent tells moderators about a new or changed report, along with
+// the number of open reports they may see
+type ReportEvent struct {
+	Report    models.Report `json:"report"`
+	OpenCount int           `json:"openCount"`
+}
+
+// AccountBannedEvent tells a user's connections why they are being closed
+type AccountBannedEvent struct {
+	Message   string     `json:"message"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 // NewCommentEvent represents a new comment notification
 type NewCommentEvent struct {
-	ID          int       `json:"id"`
-	PostID      int       `json:"postId"`
-	UserID      int       `json:"userId"`
-	Content     string    `json:"content"`
-	Nickname    string    `json:"nickname"`
-	AvatarColor string    `json:"avatarColor"`
-	Timestamp   time.Time `json:"timestamp"`
+	ID          int                 `json:"id"`
+	PostID      int                 `json:"postId"`
+	ParentID    int                 `json:"parentId,omitempty"`
+	UserID      int                 `json:"userId"`
+	Content     string              `json:"content"`
+	ContentHTML string              `json:"contentHtml"`
+	Nickname    string              `json:"nickname"`
+	AvatarColor string              `json:"avatarColor"`
+	AvatarURL   string              `json:"avatarUrl"`
+	Attachments []models.Attachment `json:"attachments,omitempty"`
+	Timestamp   time.Time           `json:"timestamp"`
 }
 
 // OnlineUsersEvent represents online users update
@@ -73,13 +148,111 @@ type UserStatus struct {
 	ID          int       `json:"id"`
 	Nickname    string    `json:"nickname"`
 	AvatarColor string    `json:"avatarColor"`
+	AvatarURL   string    `json:"avatarUrl"`
 	IsOnline    bool      `json:"isOnline"`
 	LastSeen    time.Time `json:"lastSeen"`
 }
 
 // TypingEvent represents typing indicator
 type TypingEvent struct {
+	UserID         int    `json:"userId"`
+	Username       string `json:"username"`
+	ChatWith       int    `json:"chatWith,omitempty"`
+	ConversationID int    `json:"conversationId,omitempty"`
+}
+
+// RoomPresenceEvent represents the users currently viewing a room
+type RoomPresenceEvent struct {
+	Room    string       `json:"room"`
+	Count   int          `json:"count"`
+	Viewers []UserStatus `json:"viewers"`
+}
+
+// RoomTypingEvent represents a typing indicator scoped to a room
+type RoomTypingEvent struct {
+	Room     string `json:"room"`
 	UserID   int    `json:"userId"`
-	Username string `json:"username"`
-	ChatWith int    `json:"chatWith"`
-}
\ No newline at end of file
+	Username string `json:"username,omitempty"`
+}
+
+// Error codes reported in ErrorEvent
+const (
+	ErrorCodeRateLimited = "rate_limited"
+)
+
+// ErrorEvent represents an error reported back to a client
+type ErrorEvent struct {
+	Code    string    `json:"code,omitempty"`
+	Event   EventType `json:"event,omitempty"`
+	Message string    `json:"message"`
+}
+
+// ServerShutdownEvent tells clients the server is going away and when to reconnect
+type ServerShutdownEvent struct {
+	ReconnectAfter int `json:"reconnectAfter"` // seconds
+}
+
+// ConversationReadEvent represents a participant's new read position
+type ConversationReadEvent struct {
+	ConversationID    int `json:"conversationId"`
+	UserID            int `json:"userId"`
+	LastReadMessageID int `json:"lastReadMessageId"`
+}
+
+// ConversationUpdatedEvent represents a change to a conversation's title or members
+type ConversationUpdatedEvent struct {
+	ConversationID int                              `json:"conversationId"`
+	Title          string                           `json:"title"`
+	IsGroup        bool                             `json:"isGroup"`
+	Participants   []models.ConversationParticipant `json:"participants"`
+}
+
+// MessageUpdatedEvent represents an edit to a private message
+type MessageUpdatedEvent struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversationId"`
+	SenderID       int       `json:"senderId"`
+	Content        string    `json:"content"`
+	EditedAt       time.Time `json:"editedAt"`
+}
+
+// MessageDeletedEvent represents a private message deleted for everyone, or
+// for the receiving user only when Scope is "me"
+type MessageDeletedEvent struct {
+	ID             int    `json:"id"`
+	ConversationID int    `json:"conversationId"`
+	Scope          string `json:"scope"`
+}
+
+// ReactionUpdatedEvent represents a reaction added to or removed from a
+// post, comment or message, with the target's new counts
+type ReactionUpdatedEvent struct {
+	TargetType     string                 `json:"targetType"`
+	TargetID       int                    `json:"targetId"`
+	PostID         int                    `json:"postId,omitempty"`
+	ConversationID int                    `json:"conversationId,omitempty"`
+	UserID         int                    `json:"userId"`
+	Emoji          string                 `json:"emoji"`
+	Added          bool                   `json:"added"`
+	Reactions      []models.ReactionCount `json:"reactions"`
+}
+
+// ScoreUpdatedEvent represents a new vote score on a post or comment
+type ScoreUpdatedEvent struct {
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	PostID     int    `json:"postId"`
+	Score      int    `json:"score"`
+}
+
+// NotificationEvent carries a new notification with the user's unread count
+type NotificationEvent struct {
+	Notification models.Notification `json:"notification"`
+	UnreadCount  int                 `json:"unreadCount"`
+}
+
+// NotificationsReadEvent tells a user's connections their unread count
+// changed because notifications were read
+type NotificationsReadEvent struct {
+	UnreadCount int `json:"unreadCount"`
+}
@@ -0,0 +1,277 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"real-time-forum/backend/internal/utils"
+	"sync"
+	"time"
+)
+
+const (
+	// Interval between SSE keep-alive comments
+	sseKeepAlive = 15 * time.Second
+
+	// Maximum time a long-poll request waits for new frames
+	longPollWait = 25 * time.Second
+
+	// Long-poll clients that have not polled for this long are dropped
+	longPollIdleTimeout = 60 * time.Second
+)
+
+// Transport names reported for each client
+const (
+	TransportWebSocket = "websocket"
+	TransportSSE       = "sse"
+	TransportLongPoll  = "longpoll"
+)
+
+// ConnectedEvent tells a fallback client the ID to use for its actions
+type ConnectedEvent struct {
+	ClientID  string `json:"clientId"`
+	Transport string `json:"transport"`
+}
+
+// LongPollResponse is returned by each long-poll request
+type LongPollResponse struct {
+	ClientID string            `json:"clientId"`
+	Messages []json.RawMessage `json:"messages"`
+}
+
+// longPollState tracks activity of a long-poll client between requests
+type longPollState struct {
+	mu       sync.Mutex // serializes polls for the same client
+	lastPoll time.Time
+	lastMu   sync.Mutex
+}
+
+// touch records that the client just polled
+func (s *longPollState) touch() {
+	s.lastMu.Lock()
+	s.lastPoll = time.Now()
+	s.lastMu.Unlock()
+}
+
+// idleFor returns how long it has been since the client last polled
+func (s *longPollState) idleFor() time.Duration {
+	s.lastMu.Lock()
+	defer s.lastMu.Unlock()
+	return time.Since(s.lastPoll)
+}
+
+// HandleSSE streams hub events to the client as Server-Sent Events
+func HandleSSE(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	client := NewClient(hub, nil, userID)
+	client.transport = TransportSSE
+	if err := hub.RegisterClient(client); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer hub.UnregisterClient(client)
+
+	hello, _ := json.Marshal(WebSocketMessage{
+		Type: EventTypeConnected,
+		Data: ConnectedEvent{ClientID: client.id, Transport: client.transport},
+	})
+	writeSSE(w, hello)
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-client.send:
+			if !ok {
+				return
+			}
+			writeSSE(w, message)
+
+		case <-client.wake:
+			for _, frame := range client.takePresence() {
+				writeSSE(w, frame)
+			}
+
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSSE writes one frame as an SSE data event
+func writeSSE(w http.ResponseWriter, data []byte) {
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// HandleLongPoll delivers hub events through repeated long-poll requests.
+// The first request omits client_id and receives one to use afterwards.
+func HandleLongPoll(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" {
+		client := NewClient(hub, nil, userID)
+		client.transport = TransportLongPoll
+		client.poll = &longPollState{}
+		client.poll.touch()
+		if err := hub.RegisterClient(client); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		go hub.reapLongPollClient(client)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LongPollResponse{ClientID: client.id, Messages: []json.RawMessage{}})
+		return
+	}
+
+	client, ok := hub.ClientByID(clientID)
+	if !ok || client.userID != userID || client.poll == nil {
+		http.Error(w, "Unknown or expired client", http.StatusGone)
+		return
+	}
+
+	client.poll.mu.Lock()
+	defer client.poll.mu.Unlock()
+	client.poll.touch()
+	defer client.poll.touch()
+
+	messages, open := client.waitForFrames(r, longPollWait)
+	if !open {
+		http.Error(w, "Unknown or expired client", http.StatusGone)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LongPollResponse{ClientID: client.id, Messages: messages})
+}
+
+// waitForFrames blocks until at least one frame is queued or the wait
+// expires, then drains everything pending. It reports false once the hub has
+// closed the client.
+func (c *Client) waitForFrames(r *http.Request, wait time.Duration) ([]json.RawMessage, bool) {
+	messages := []json.RawMessage{}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case message, ok := <-c.send:
+		if !ok {
+			return nil, false
+		}
+		messages = append(messages, message)
+	case <-c.wake:
+	case <-timer.C:
+		return messages, true
+	case <-r.Context().Done():
+		return messages, true
+	}
+
+drain:
+	for {
+		select {
+		case message, ok := <-c.send:
+			if !ok {
+				// Deliver what we have; the next poll reports the closure
+				break drain
+			}
+			messages = append(messages, message)
+		default:
+			break drain
+		}
+	}
+	for _, frame := range c.takePresence() {
+		messages = append(messages, frame)
+	}
+	return messages, true
+}
+
+// reapLongPollClient unregisters a long-poll client once it stops polling
+func (h *Hub) reapLongPollClient(client *Client) {
+	ticker := time.NewTicker(longPollIdleTimeout / 4)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if client.isClosed() {
+			return
+		}
+		if client.poll.idleFor() > longPollIdleTimeout {
+			log.Printf("Long-poll client expired: user %d", client.userID)
+			h.UnregisterClient(client)
+			return
+		}
+	}
+}
+
+// HandleClientAction accepts client-to-server events over plain HTTP for
+// SSE and long-poll clients. The body is the same JSON a WebSocket client
+// would send, plus the clientId issued by the transport.
+func HandleClientAction(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := utils.GetUserIDFromSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxMessageSize)
+	var msg map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	clientID, _ := msg["clientId"].(string)
+	client, ok := hub.ClientByID(clientID)
+	if !ok || client.userID != userID {
+		http.Error(w, "Unknown or expired client", http.StatusGone)
+		return
+	}
+	if client.poll != nil {
+		client.poll.touch()
+	}
+
+	client.handleMessage(msg)
+	w.WriteHeader(http.StatusAccepted)
+}
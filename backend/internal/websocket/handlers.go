@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	"real-time-forum/backend/internal/utils"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -32,7 +33,18 @@ func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
 
 	// Create and register client
 	client := NewClient(hub, conn, userID)
-	hub.RegisterClient(client)
+	if name := r.URL.Query().Get("drop_policy"); name != "" {
+		if policy, err := ParseDropPolicy(name); err == nil {
+			client.SetDropPolicy(policy)
+		}
+	}
+	if err := hub.RegisterClient(client); err != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()),
+			time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
 
 	// Start client pumps
 	client.Run()
@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"real-time-forum/backend/internal/database"
 	"real-time-forum/backend/internal/models"
@@ -11,29 +12,60 @@ import (
 
 // Hub manages WebSocket clients, broadcasting, and user tracking
 type Hub struct {
-	clients     map[*Client]bool
-	broadcast   chan []byte
-	register    chan *Client
-	unregister  chan *Client
-	userClients map[int]*Client
-	mu          sync.RWMutex
+	clients           map[*Client]bool
+	broadcast         chan []byte
+	register          chan *Client
+	unregister        chan *Client
+	userClients       map[int]*Client
+	clientsByID       map[string]*Client
+	rooms             map[string]*Room
+	defaultDropPolicy DropPolicy
+	metrics           hubMetrics
+	limiter           *rateLimiter
+	editWindow        time.Duration
+	filters           *filterCache
+	pumps             sync.WaitGroup
+	done              chan struct{}
+	stopped           chan struct{}
+	shutdownOnce      sync.Once
+	mu                sync.RWMutex
 }
 
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
 	return &Hub{
-		clients:     make(map[*Client]bool),
-		broadcast:   make(chan []byte),
-		register:    make(chan *Client),
-		unregister:  make(chan *Client),
-		userClients: make(map[int]*Client),
+		clients:           make(map[*Client]bool),
+		broadcast:         make(chan []byte),
+		register:          make(chan *Client),
+		unregister:        make(chan *Client),
+		userClients:       make(map[int]*Client),
+		clientsByID:       make(map[string]*Client),
+		rooms:             make(map[string]*Room),
+		defaultDropPolicy: DropPolicyCoalescePresence,
+		limiter:           newRateLimiter(DefaultRateLimitConfig()),
+		editWindow:        DefaultMessageEditWindow,
+		filters:           newFilterCache(),
+		done:              make(chan struct{}),
+		stopped:           make(chan struct{}),
 	}
 }
 
-// Run starts the hub's main loop
+// SetDefaultDropPolicy sets the drop policy given to newly connected clients
+func (h *Hub) SetDefaultDropPolicy(policy DropPolicy) {
+	h.mu.Lock()
+	h.defaultDropPolicy = policy
+	h.mu.Unlock()
+}
+
+// Run starts the hub's main loop. It returns once Shutdown is called.
 func (h *Hub) Run() {
+	defer close(h.stopped)
+
 	for {
 		select {
+		case <-h.done:
+			return
+
 		case client := <-h.register:
 			h.registerClient(client)
 
@@ -51,6 +83,7 @@ func (h *Hub) registerClient(client *Client) {
 	h.mu.Lock()
 	h.clients[client] = true
 	h.userClients[client.userID] = client
+	h.clientsByID[client.id] = client
 	h.mu.Unlock()
 
 	// Update user online status
@@ -59,41 +92,77 @@ func (h *Hub) registerClient(client *Client) {
 	// Broadcast online status update
 	h.broadcastOnlineUsers()
 
-	log.Printf("Client registered: user %d", client.userID)
+	log.Printf("Client registered: user %d via %s", client.userID, client.transport)
 }
 
-// unregisterClient unregisters a client
+// unregisterClient unregisters a client. It is the only place a client's
+// send channel is closed, and it is safe to call more than once per client.
 func (h *Hub) unregisterClient(client *Client) {
 	h.mu.Lock()
-	if _, ok := h.clients[client]; ok {
+	_, ok := h.clients[client]
+	if ok {
 		delete(h.clients, client)
-		delete(h.userClients, client.userID)
-		close(client.send)
+		delete(h.clientsByID, client.id)
+		if h.userClients[client.userID] == client {
+			delete(h.userClients, client.userID)
+			// Fall back to another open connection of the same user
+			for other := range h.clients {
+				if other.userID == client.userID {
+					h.userClients[client.userID] = other
+					break
+				}
+			}
+		}
 	}
+	_, stillOnline := h.userClients[client.userID]
 	h.mu.Unlock()
 
-	// Update user offline status
-	database.UpdateUserOnlineStatus(client.userID, false)
+	if !ok {
+		return
+	}
 
-	// Broadcast online status update
-	h.broadcastOnlineUsers()
+	// Close first so a concurrent JoinRoom cannot re-add the client to a room
+	client.closeSend()
+	h.leaveAllRooms(client)
+
+	if !stillOnline {
+		h.pruneBuckets()
+		h.forgetFilters(client.userID)
+
+		// Update user offline status
+		database.UpdateUserOnlineStatus(client.userID, false)
+
+		// Broadcast online status update
+		h.broadcastOnlineUsers()
+	}
 
 	log.Printf("Client unregistered: user %d", client.userID)
 }
 
-// broadcastMessage sends a message to all connected clients
-func (h *Hub) broadcastMessage(message []byte) {
+// clientSnapshot returns the currently registered clients
+func (h *Hub) clientSnapshot() []*Client {
 	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(h.clients))
 	for client := range h.clients {
-		select {
-		case client.send <- message:
-		default:
-			close(client.send)
-			delete(h.clients, client)
-			delete(h.userClients, client.userID)
-		}
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// broadcastMessage sends a message to all connected clients
+func (h *Hub) broadcastMessage(message []byte) {
+	for _, client := range h.clientSnapshot() {
+		client.SendRaw(message)
+	}
+}
+
+// broadcastPresence sends a coalescable presence update to all connected clients
+func (h *Hub) broadcastPresence(key string, message []byte) {
+	for _, client := range h.clientSnapshot() {
+		client.SendPresence(key, message)
 	}
-	h.mu.RUnlock()
 }
 
 // broadcastOnlineUsers broadcasts the list of online users
@@ -112,153 +181,316 @@ func (h *Hub) broadcastOnlineUsers() {
 			ID:          user.ID,
 			Nickname:    user.Nickname,
 			AvatarColor: user.AvatarColor,
+			AvatarURL:   user.AvatarURL,
 			IsOnline:    user.IsOnline,
 			LastSeen:    lastSeen,
 		})
 	}
 
-	message := WebSocketMessage{
-		Type: EventTypeOnlineUsers,
-		Data: OnlineUsersEvent{Users: userStatuses},
-	}
-
-	data, err := json.Marshal(message)
+	shared, err := marshalOnlineUsers(userStatuses)
 	if err != nil {
 		log.Printf("Error marshaling online users: %v", err)
 		return
 	}
-	h.broadcast <- data
+
+	// Delivered directly: this runs on the hub goroutine, which also reads h.broadcast.
+	// Users with blocks get a list without the users on the other side.
+	key := string(EventTypeOnlineUsers)
+	perUser := make(map[int][]byte)
+	for _, client := range h.clientSnapshot() {
+		filter := h.userFilter(client.userID)
+		if len(filter.blocked) == 0 {
+			client.SendPresence(key, shared)
+			continue
+		}
+
+		data, ok := perUser[client.userID]
+		if !ok {
+			var visible []UserStatus
+			for _, status := range userStatuses {
+				if !filter.blocked[status.ID] {
+					visible = append(visible, status)
+				}
+			}
+			if data, err = marshalOnlineUsers(visible); err != nil {
+				log.Printf("Error marshaling online users: %v", err)
+				continue
+			}
+			perUser[client.userID] = data
+		}
+		client.SendPresence(key, data)
+	}
+}
+
+// marshalOnlineUsers encodes an online users event
+func marshalOnlineUsers(users []UserStatus) ([]byte, error) {
+	return json.Marshal(WebSocketMessage{
+		Type: EventTypeOnlineUsers,
+		Data: OnlineUsersEvent{Users: users},
+	})
+}
+
+// ClientByID looks up a registered client by its ID
+func (h *Hub) ClientByID(id string) (*Client, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	client, ok := h.clientsByID[id]
+	return client, ok
 }
 
 // RegisterClient registers a new client with the hub
-func (h *Hub) RegisterClient(client *Client) {
-	h.register <- client
+func (h *Hub) RegisterClient(client *Client) error {
+	select {
+	case h.register <- client:
+		return nil
+	case <-h.done:
+		return ErrHubClosed
+	}
 }
 
 // UnregisterClient unregisters a client from the hub
 func (h *Hub) UnregisterClient(client *Client) {
-	h.unregister <- client
+	select {
+	case h.unregister <- client:
+	case <-h.done:
+	}
 }
 
 // BroadcastMessage broadcasts a message to all clients
 func (h *Hub) BroadcastMessage(message []byte) {
-	h.broadcast <- message
+	select {
+	case h.broadcast <- message:
+	case <-h.done:
+	}
 }
 
-// SendToUser sends a message to a specific user
+// SendToUser sends a message to every connection of a specific user
 func (h *Hub) SendToUser(userID int, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	var clients []*Client
 	h.mu.RLock()
-	client, ok := h.userClients[userID]
+	for client := range h.clients {
+		if client.userID == userID {
+			clients = append(clients, client)
+		}
+	}
 	h.mu.RUnlock()
 
-	if !ok {
+	if len(clients) == 0 {
 		return ErrClientDisconnected
 	}
+	for _, client := range clients {
+		client.SendRaw(data)
+	}
+	return nil
+}
 
-	return client.SendMessage(message)
+// SendToUsers sends a message to every connection of the given users
+func (h *Hub) SendToUsers(userIDs []int, message interface{}) {
+	for _, userID := range userIDs {
+		h.SendToUser(userID, message)
+	}
 }
 
-// handlePrivateMessage handles private message events
+// HandlePrivateMessage handles private message events for one-to-one
+// (recipientId) and group (conversationId) conversations
 func (h *Hub) HandlePrivateMessage(client *Client, msg map[string]interface{}) {
-	recipientID, ok := msg["recipientId"].(float64)
-	if !ok {
+	recipientID, _ := msg["recipientId"].(float64)
+	conversationID, _ := msg["conversationId"].(float64)
+
+	content, _ := msg["content"].(string)
+
+	req := models.CreateMessageRequest{
+		RecipientID:    int(recipientID),
+		ConversationID: int(conversationID),
+		Content:        content,
+		AttachmentIDs:  intList(msg["attachmentIds"]),
+	}
+	if err := req.Validate(); err != nil {
+		client.SendMessage(WebSocketMessage{
+			Type: EventTypeError,
+			Data: ErrorEvent{Event: EventTypePrivateMessage, Message: err.Error()},
+		})
 		return
 	}
 
-	content, ok := msg["content"].(string)
-	if !ok {
+	// allowEvent already charged the sender's message bucket
+	response, err := h.sendPrivateMessage(client.userID, req)
+	if err != nil {
+		log.Printf("Error sending message: %v", err)
+		client.SendMessage(WebSocketMessage{
+			Type: EventTypeError,
+			Data: ErrorEvent{Event: EventTypePrivateMessage, Message: err.Error()},
+		})
 		return
 	}
 
-	// Create and save message
-	message := &models.Message{
-		SenderID:    client.userID,
-		RecipientID: int(recipientID),
-		Content:     content,
-		CreatedAt:   time.Now(),
+	// Send confirmation to sender
+	client.SendMessage(response)
+}
+
+// intList reads a JSON array of numbers from an event, skipping anything else
+func intList(value interface{}) []int {
+	items, _ := value.([]interface{})
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		if id, ok := item.(float64); ok {
+			ids = append(ids, int(id))
+		}
 	}
+	return ids
+}
 
-	if err := database.CreateMessage(message); err != nil {
-		log.Printf("Error saving message: %v", err)
-		return
+// SendPrivateMessage sends a private message on behalf of the REST send
+// path, charging the same per-user bucket as messages sent over a socket
+func (h *Hub) SendPrivateMessage(senderID int, req models.CreateMessageRequest) (WebSocketMessage, error) {
+	if !h.allowUserEvent(senderID, EventTypePrivateMessage, time.Now()) {
+		h.metrics.rateLimited.Add(1)
+		return WebSocketMessage{}, ErrRateLimited
 	}
+	return h.sendPrivateMessage(senderID, req)
+}
 
-	// Get sender info
-	sender, err := database.GetUserByID(client.userID)
-	if err != nil {
-		log.Printf("Error getting sender info: %v", err)
-		return
+// sendPrivateMessage stores a private message and delivers it to the other
+// participants of the conversation. It is shared by the WebSocket and REST
+// send paths and returns the event so the caller can confirm to the sender.
+func (h *Hub) sendPrivateMessage(senderID int, req models.CreateMessageRequest) (WebSocketMessage, error) {
+	// Create and save message
+	message := &models.Message{
+		ConversationID: req.ConversationID,
+		SenderID:       senderID,
+		RecipientID:    req.RecipientID,
+		Content:        req.Content,
+		CreatedAt:      time.Now(),
 	}
 
-	// Use message.CreatedAt directly as it's already time.Time
-	createdAt := message.CreatedAt
+	if err := database.CheckAttachments(senderID, req.AttachmentIDs); err != nil {
+		return WebSocketMessage{}, err
+	}
+	if err := database.CreateMessage(message, req.AttachmentIDs); err != nil {
+		return WebSocketMessage{}, err
+	}
+	// The message is already stored, so a failure here only costs the mentions
+	if err := database.RecordMessageMentions(message); err != nil {
+		log.Printf("Error recording mentions: %v", err)
+	}
 
 	// Create response
 	response := WebSocketMessage{
 		Type: EventTypeNewMessage,
 		Data: NewMessageEvent{
-			ID:          message.ID,
-			SenderID:    client.userID,
-			RecipientID: int(recipientID),
-			Content:     content,
-			Sender:      sender.Nickname,
-			Timestamp:   createdAt,
+			ID:             message.ID,
+			ConversationID: message.ConversationID,
+			SenderID:       senderID,
+			RecipientID:    message.RecipientID,
+			Content:        message.Content,
+			Sender:         message.SenderName,
+			Attachments:    message.Attachments,
+			Timestamp:      message.CreatedAt,
 		},
 	}
 
-	// Send to recipient if online
-	h.SendToUser(int(recipientID), response)
+	// Send to the other participants that are online; anyone who has not
+	// accepted the conversation yet only hears about a message request
+	h.sendToConversationFrom(message.ConversationID, response, senderID, WebSocketMessage{
+		Type: EventTypeMessageRequest,
+		Data: response.Data,
+	})
+	h.notifyMentions(models.MentionTargetMessage, message.ID, nil)
+	h.notifyDirectMessage(message)
 
-	// Send confirmation to sender
-	client.SendMessage(response)
+	return response, nil
 }
 
-// handleTyping handles typing indicator events
+// HandleTyping handles typing indicator events, either for a one-to-one
+// chat (chatWith) or a conversation (conversationId)
 func (h *Hub) HandleTyping(client *Client, msg map[string]interface{}) {
-	chatWith, ok := msg["chatWith"].(float64)
+	chatWith, conversationID, target, ok := h.typingTarget(client, msg)
 	if !ok {
 		return
 	}
 
+	// Coalesce bursts so the recipient gets at most one per interval
+	if !h.allowTyping(client.userID, target) {
+		return
+	}
+
 	sender, err := database.GetUserByID(client.userID)
 	if err != nil {
 		return
 	}
 
-	response := WebSocketMessage{
+	h.sendTyping(client.userID, chatWith, conversationID, WebSocketMessage{
 		Type: EventTypeTyping,
 		Data: TypingEvent{
-			UserID:   client.userID,
-			Username: sender.Nickname,
-			ChatWith: int(chatWith),
+			UserID:         client.userID,
+			Username:       sender.Nickname,
+			ChatWith:       chatWith,
+			ConversationID: conversationID,
 		},
-	}
-
-	// Send to the user being chatted with
-	h.SendToUser(int(chatWith), response)
+	})
 }
 
-// handleStopTyping handles stop typing indicator events
+// HandleStopTyping handles stop typing indicator events
 func (h *Hub) HandleStopTyping(client *Client, msg map[string]interface{}) {
-	chatWith, ok := msg["chatWith"].(float64)
+	chatWith, conversationID, target, ok := h.typingTarget(client, msg)
 	if !ok {
 		return
 	}
+	h.stopTyping(client.userID, target)
 
-	response := WebSocketMessage{
+	h.sendTyping(client.userID, chatWith, conversationID, WebSocketMessage{
 		Type: EventTypeStopTyping,
 		Data: TypingEvent{
-			UserID:   client.userID,
-			ChatWith: int(chatWith),
+			UserID:         client.userID,
+			ChatWith:       chatWith,
+			ConversationID: conversationID,
 		},
+	})
+}
+
+// typingTarget reads the chat a typing event is for and checks the sender
+// may type there
+func (h *Hub) typingTarget(client *Client, msg map[string]interface{}) (chatWith, conversationID int, target string, ok bool) {
+	if id, isSet := msg["conversationId"].(float64); isSet && id > 0 {
+		member, err := database.IsConversationParticipant(int(id), client.userID)
+		if err != nil || !member {
+			return 0, 0, "", false
+		}
+		return 0, int(id), fmt.Sprintf("conversation:%d", int(id)), true
 	}
 
-	// Send to the user being chatted with
-	h.SendToUser(int(chatWith), response)
+	if id, isSet := msg["chatWith"].(float64); isSet && id > 0 {
+		return int(id), 0, fmt.Sprintf("user:%d", int(id)), true
+	}
+	return 0, 0, "", false
+}
+
+// sendTyping delivers a typing event to the user being chatted with or to
+// the other participants of a conversation
+func (h *Hub) sendTyping(senderID, chatWith, conversationID int, response WebSocketMessage) {
+	if conversationID > 0 {
+		h.sendToConversationFrom(conversationID, response, senderID, nil)
+		return
+	}
+
+	// Send to the user being chatted with, unless either blocked the other
+	// or the sender would only reach their message requests
+	if h.isBlocked(senderID, chatWith) {
+		return
+	}
+	if accepted, err := database.AcceptsMessagesFrom(chatWith, senderID); err != nil || !accepted {
+		return
+	}
+	h.SendToUser(chatWith, response)
 }
 
 // handleNewPost handles new post events
-func (h *Hub) HandleNewPost(post *models.Post, nickname, avatarColor string) {
+func (h *Hub) HandleNewPost(post *models.Post, author *models.User) {
 	response := WebSocketMessage{
 		Type: EventTypeNewPost,
 		Data: NewPostEvent{
@@ -266,43 +498,46 @@ func (h *Hub) HandleNewPost(post *models.Post, nickname, avatarColor string) {
 			UserID:       post.UserID,
 			Title:        post.Title,
 			Content:      post.Content,
+			ContentHTML:  post.ContentHTML,
 			CategoryID:   post.CategoryID,
 			CategoryName: post.CategoryName,
-			Nickname:     nickname,
-			AvatarColor:  avatarColor,
+			Tags:         post.Tags,
+			Nickname:     author.Nickname,
+			AvatarColor:  author.AvatarColor,
+			AvatarURL:    author.AvatarURL,
+			Attachments:  post.Attachments,
 			Timestamp:    time.Now(),
 		},
 	}
 
-	data, err := json.Marshal(response)
-	if err != nil {
-		log.Printf("Error marshaling new post event: %v", err)
-		return
-	}
-
-	h.BroadcastMessage(data)
+	// Skip users who may not view the post's category, and users who
+	// blocked or muted the author
+	hidden := h.hidesPost(post.ID)
+	h.sendFiltered(h.clientSnapshot(), response, func(client *Client) bool {
+		return hidden(client) || h.hidesAuthor(client.userID, post.UserID)
+	})
+	h.notifyMentions(models.MentionTargetPost, post.ID, nil)
 }
 
-// handleNewComment handles new comment events
-func (h *Hub) HandleNewComment(comment *models.Comment, nickname, avatarColor string) {
+// handleNewComment sends new comment events to the viewers of the post
+func (h *Hub) HandleNewComment(comment *models.Comment, author *models.User) {
 	response := WebSocketMessage{
 		Type: EventTypeNewComment,
 		Data: NewCommentEvent{
 			ID:          comment.ID,
 			PostID:      comment.PostID,
+			ParentID:    comment.ParentID,
 			UserID:      comment.UserID,
 			Content:     comment.Content,
-			Nickname:    nickname,
-			AvatarColor: avatarColor,
+			ContentHTML: comment.ContentHTML,
+			Nickname:    author.Nickname,
+			AvatarColor: author.AvatarColor,
+			AvatarURL:   author.AvatarURL,
+			Attachments: comment.Attachments,
 			Timestamp:   time.Now(),
 		},
 	}
 
-	data, err := json.Marshal(response)
-	if err != nil {
-		log.Printf("Error marshaling new comment event: %v", err)
-		return
-	}
-
-	h.BroadcastMessage(data)
-}
\ No newline at end of file
+	h.sendToPostRoom(comment.PostID, response, comment.UserID)
+	h.notifyMentions(models.MentionTargetComment, comment.ID, h.notifyReplies(comment))
+}
@@ -0,0 +1,33 @@
+package websocket
+
+import (
+	"log"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+)
+
+// notifyMentions sends a mention event to each online user mentioned in a
+// post, comment or message and stores a mention notification, unless they
+// were already notified of it another way. Users who may not view the post
+// are left out. Everyone can find their mentions later through
+// database.GetMentions.
+func (h *Hub) notifyMentions(targetType string, targetID int, notified map[int]bool) {
+	mentions, err := database.GetTargetMentions(targetType, targetID)
+	if err != nil {
+		log.Printf("Error getting mentions: %v", err)
+		return
+	}
+
+	for _, mention := range mentions {
+		if mention.PostID != 0 && !h.canViewPost(mention.UserID, mention.PostID) {
+			continue
+		}
+		h.SendToUser(mention.UserID, WebSocketMessage{
+			Type: EventTypeMention,
+			Data: mention,
+		})
+		if !notified[mention.UserID] {
+			h.notify(mention.UserID, models.NotificationMention, mention.AuthorID, targetType, targetID, "")
+		}
+	}
+}
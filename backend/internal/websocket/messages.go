@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"time"
+)
+
+// DefaultMessageEditWindow is how long senders may edit a message after sending it
+const DefaultMessageEditWindow = 15 * time.Minute
+
+// SetMessageEditWindow changes how long senders may edit their messages;
+// zero allows edits at any time
+func (h *Hub) SetMessageEditWindow(window time.Duration) {
+	h.mu.Lock()
+	h.editWindow = window
+	h.mu.Unlock()
+}
+
+// messageEditWindow returns the current edit window
+func (h *Hub) messageEditWindow() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.editWindow
+}
+
+// HandleEditMessage handles a sender editing one of their messages
+func (h *Hub) HandleEditMessage(client *Client, msg map[string]interface{}) {
+	messageID, _ := msg["messageId"].(float64)
+	content, _ := msg["content"].(string)
+
+	req := models.EditMessageRequest{MessageID: int(messageID), Content: content}
+	if err := req.Validate(); err != nil {
+		client.SendMessage(WebSocketMessage{
+			Type: EventTypeError,
+			Data: ErrorEvent{Event: EventTypeEditMessage, Message: err.Error()},
+		})
+		return
+	}
+
+	if _, err := h.EditMessage(client.userID, req); err != nil {
+		client.SendMessage(WebSocketMessage{
+			Type: EventTypeError,
+			Data: ErrorEvent{Event: EventTypeEditMessage, Message: err.Error()},
+		})
+	}
+}
+
+// HandleDeleteMessage handles a user deleting a message for themselves or,
+// as its sender, for everyone
+func (h *Hub) HandleDeleteMessage(client *Client, msg map[string]interface{}) {
+	messageID, _ := msg["messageId"].(float64)
+	scope, _ := msg["scope"].(string)
+
+	req := models.DeleteMessageRequest{MessageID: int(messageID), Scope: scope}
+	if err := req.Validate(); err != nil {
+		client.SendMessage(WebSocketMessage{
+			Type: EventTypeError,
+			Data: ErrorEvent{Event: EventTypeDeleteMessage, Message: err.Error()},
+		})
+		return
+	}
+
+	if err := h.DeleteMessage(client.userID, req); err != nil {
+		client.SendMessage(WebSocketMessage{
+			Type: EventTypeError,
+			Data: ErrorEvent{Event: EventTypeDeleteMessage, Message: err.Error()},
+		})
+	}
+}
+
+// EditMessage stores an edit and sends the new content to every participant
+// of the conversation, including the sender's other connections. It is
+// shared by the WebSocket and REST paths.
+func (h *Hub) EditMessage(userID int, req models.EditMessageRequest) (*models.Message, error) {
+	message, err := database.EditMessage(req.MessageID, userID, req.Content, h.messageEditWindow())
+	if err != nil {
+		return nil, err
+	}
+	if message.EditedAt == nil {
+		// Content was unchanged
+		return message, nil
+	}
+
+	h.sendToConversation(message.ConversationID, WebSocketMessage{
+		Type: EventTypeMessageUpdated,
+		Data: MessageUpdatedEvent{
+			ID:             message.ID,
+			ConversationID: message.ConversationID,
+			SenderID:       message.SenderID,
+			Content:        message.Content,
+			EditedAt:       *message.EditedAt,
+		},
+	}, 0)
+	return message, nil
+}
+
+// DeleteMessage deletes a message for the user only, telling just their own
+// connections, or for everyone, telling every participant
+func (h *Hub) DeleteMessage(userID int, req models.DeleteMessageRequest) error {
+	var message *models.Message
+	var err error
+	if req.Scope == models.DeleteScopeEveryone {
+		message, err = database.DeleteMessageForEveryone(req.MessageID, userID)
+	} else {
+		message, err = database.HideMessage(req.MessageID, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	response := WebSocketMessage{
+		Type: EventTypeMessageDeleted,
+		Data: MessageDeletedEvent{
+			ID:             message.ID,
+			ConversationID: message.ConversationID,
+			Scope:          req.Scope,
+		},
+	}
+	if req.Scope == models.DeleteScopeEveryone {
+		h.sendToConversation(message.ConversationID, response, 0)
+	} else {
+		h.SendToUser(userID, response)
+	}
+	return nil
+}
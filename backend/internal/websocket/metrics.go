@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// DropPolicy controls what happens when a client's send queue is full
+type DropPolicy int
+
+const (
+	// DropPolicyDisconnect disconnects a client whose queue overflows
+	DropPolicyDisconnect DropPolicy = iota
+	// DropPolicyDropOldest discards the oldest queued frame to make room
+	DropPolicyDropOldest
+	// DropPolicyCoalescePresence keeps only the latest presence update per
+	// key outside the queue and disconnects on overflow of other frames
+	DropPolicyCoalescePresence
+)
+
+// String returns the policy name used in query parameters and metrics
+func (p DropPolicy) String() string {
+	switch p {
+	case DropPolicyDisconnect:
+		return "disconnect"
+	case DropPolicyDropOldest:
+		return "drop_oldest"
+	case DropPolicyCoalescePresence:
+		return "coalesce_presence"
+	}
+	return "unknown"
+}
+
+// ParseDropPolicy parses a drop policy name
+func ParseDropPolicy(name string) (DropPolicy, error) {
+	switch strings.ToLower(name) {
+	case "disconnect":
+		return DropPolicyDisconnect, nil
+	case "drop_oldest", "oldest":
+		return DropPolicyDropOldest, nil
+	case "coalesce_presence", "coalesce":
+		return DropPolicyCoalescePresence, nil
+	}
+	return DropPolicyDisconnect, fmt.Errorf("unknown drop policy %q", name)
+}
+
+// hubMetrics holds the hub's delivery counters
+type hubMetrics struct {
+	framesDropped    atomic.Uint64
+	framesCoalesced  atomic.Uint64
+	slowDisconnects  atomic.Uint64
+	rateLimited      atomic.Uint64
+	abuseDisconnects atomic.Uint64
+}
+
+// Metrics is a snapshot of the hub's delivery counters
+type Metrics struct {
+	ConnectedClients int             `json:"connectedClients"`
+	FramesDropped    uint64          `json:"framesDropped"`
+	FramesCoalesced  uint64          `json:"framesCoalesced"`
+	SlowDisconnects  uint64          `json:"slowDisconnects"`
+	RateLimited      uint64          `json:"rateLimited"`
+	AbuseDisconnects uint64          `json:"abuseDisconnects"`
+	Clients          []ClientMetrics `json:"clients"`
+}
+
+// ClientMetrics reports the queue state of a single connected client
+type ClientMetrics struct {
+	UserID        int    `json:"userId"`
+	Transport     string `json:"transport"`
+	DropPolicy    string `json:"dropPolicy"`
+	QueueLength   int    `json:"queueLength"`
+	DroppedFrames uint64 `json:"droppedFrames"`
+}
+
+// Metrics returns a snapshot of the hub's delivery counters
+func (h *Hub) Metrics() Metrics {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	m := Metrics{
+		ConnectedClients: len(h.clients),
+		FramesDropped:    h.metrics.framesDropped.Load(),
+		FramesCoalesced:  h.metrics.framesCoalesced.Load(),
+		SlowDisconnects:  h.metrics.slowDisconnects.Load(),
+		RateLimited:      h.metrics.rateLimited.Load(),
+		AbuseDisconnects: h.metrics.abuseDisconnects.Load(),
+		Clients:          make([]ClientMetrics, 0, len(h.clients)),
+	}
+	for client := range h.clients {
+		client.mu.Lock()
+		policy := client.policy
+		client.mu.Unlock()
+
+		m.Clients = append(m.Clients, ClientMetrics{
+			UserID:        client.userID,
+			Transport:     client.transport,
+			DropPolicy:    policy.String(),
+			QueueLength:   len(client.send),
+			DroppedFrames: client.DroppedFrames(),
+		})
+	}
+	return m
+}
+
+// OnlyUser leaves only userID's own connections in the client list; the
+// counters still cover the whole hub
+func (m *Metrics) OnlyUser(userID int) {
+	clients := make([]ClientMetrics, 0)
+	for _, client := range m.Clients {
+		if client.UserID == userID {
+			clients = append(clients, client)
+		}
+	}
+	m.Clients = clients
+}
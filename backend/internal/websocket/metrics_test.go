@@ -0,0 +1,23 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMetricsOnlyUser(t *testing.T) {
+	m := Metrics{
+		ConnectedClients: 3,
+		Clients:          []ClientMetrics{{UserID: 1, Transport: "websocket"}, {UserID: 2}, {UserID: 1, Transport: "sse"}},
+	}
+	m.OnlyUser(1)
+	want := []ClientMetrics{{UserID: 1, Transport: "websocket"}, {UserID: 1, Transport: "sse"}}
+	if fmt.Sprint(m.Clients) != fmt.Sprint(want) || m.ConnectedClients != 3 {
+		t.Errorf("OnlyUser(1) = %+v, want clients %+v and 3 connected", m, want)
+	}
+
+	m.OnlyUser(3)
+	if m.Clients == nil || len(m.Clients) != 0 {
+		t.Errorf("OnlyUser(3) clients = %#v, want an empty list", m.Clients)
+	}
+}
@@ -0,0 +1,130 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+)
+
+// UpdatePostState applies a moderator's changes to a post's state and
+// sends the new state to everyone who may view the post, so listings and
+// open threads update live
+func (h *Hub) UpdatePostState(moderatorID, postID int, req models.PostStateRequest) (*models.PostState, error) {
+	if err := database.CheckPostAccess(moderatorID, postID, models.CategoryActionView); err != nil {
+		return nil, err
+	}
+	state, err := database.UpdatePostState(postID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	h.sendFiltered(h.clientSnapshot(), WebSocketMessage{
+		Type: EventTypePostStateUpdated,
+		Data: PostStateUpdatedEvent{PostState: *state, ModeratorID: moderatorID},
+	}, h.hidesPost(postID))
+	return state, nil
+}
+
+// CreateReport stores a user's report and alerts the moderators online
+func (h *Hub) CreateReport(reporterID int, req models.ReportRequest) (*models.Report, error) {
+	report, err := database.CreateReport(reporterID, req)
+	if err != nil {
+		return nil, err
+	}
+	h.sendToModerators(EventTypeReportCreated, report)
+	return report, nil
+}
+
+// UpdateReport applies a moderator's changes to a report and sends it to
+// the moderators online, so every queue stays current
+func (h *Hub) UpdateReport(moderatorID, reportID int, req models.UpdateReportRequest) (*models.Report, error) {
+	report, err := database.UpdateReport(moderatorID, reportID, req)
+	if err != nil {
+		return nil, err
+	}
+	h.sendToModerators(EventTypeReportUpdated, report)
+	return report, nil
+}
+
+// sendToModerators sends a report event to the connected moderators and
+// admins who may see the report, each with their own open count
+func (h *Hub) sendToModerators(eventType EventType, report *models.Report) {
+	messages := make(map[int][]byte)
+	for _, client := range h.clientSnapshot() {
+		data, checked := messages[client.userID]
+		if !checked {
+			data = h.reportMessage(client.userID, eventType, report)
+			messages[client.userID] = data
+		}
+		if data != nil {
+			client.SendRaw(data)
+		}
+	}
+}
+
+// reportMessage builds a report event for a user, or returns nil when they
+// are not staff or may not see the report
+func (h *Hub) reportMessage(userID int, eventType EventType, report *models.Report) []byte {
+	role, err := database.GetUserRole(userID)
+	if err != nil {
+		if err != database.ErrUserNotFound {
+			log.Printf("Error getting role of user %d: %v", userID, err)
+		}
+		return nil
+	}
+	if !models.RoleAtLeast(role, models.RoleModerator) || (report.PostID != 0 && !h.canViewPost(userID, report.PostID)) {
+		return nil
+	}
+
+	count, err := database.CountOpenReports(userID)
+	if err != nil {
+		log.Printf("Error counting open reports for user %d: %v", userID, err)
+	}
+	data, err := json.Marshal(WebSocketMessage{
+		Type: eventType,
+		Data: ReportEvent{Report: *report, OpenCount: count},
+	})
+	if err != nil {
+		log.Printf("Error marshaling report event: %v", err)
+		return nil
+	}
+	return data
+}
+
+// CloseAccountBanned is the close code sent to the connections of a user
+// who has just been banned
+const CloseAccountBanned = 4003
+
+// BanUser bans a user and disconnects them: each of their connections is
+// told why and then closed with CloseAccountBanned
+func (h *Hub) BanUser(moderatorID int, req models.BanRequest) (*models.Ban, error) {
+	ban, err := database.BanUser(moderatorID, req)
+	if err != nil {
+		return nil, err
+	}
+	h.forgetBans()
+
+	event := WebSocketMessage{
+		Type: EventTypeAccountBanned,
+		Data: AccountBannedEvent{Message: ban.Message(), Reason: ban.Reason, ExpiresAt: ban.ExpiresAt},
+	}
+	for _, client := range h.clientSnapshot() {
+		if client.userID == ban.UserID {
+			client.SendMessage(event)
+			client.kick(CloseAccountBanned, "account banned")
+		}
+	}
+	return ban, nil
+}
+
+// LiftBan ends a user's ban early, showing their content again if the ban
+// hid it
+func (h *Hub) LiftBan(moderatorID, userID int) (*models.Ban, error) {
+	ban, err := database.LiftBan(moderatorID, userID)
+	if err != nil {
+		return nil, err
+	}
+	h.forgetBans()
+	return ban, nil
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"log"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+)
+
+// notify stores a notification and, if one was stored, sends it to the
+// user's connections along with their unread count. Users are not notified
+// of posts and comments in categories they may not view. It reports whether
+// the user was notified.
+func (h *Hub) notify(userID int, notificationType string, actorID int, targetType string, targetID int, detail string) bool {
+	if !h.canViewTarget(userID, targetType, targetID) {
+		return false
+	}
+
+	notification, err := database.CreateNotification(userID, notificationType, actorID, targetType, targetID, detail)
+	if err != nil {
+		log.Printf("Error creating %s notification for user %d: %v", notificationType, userID, err)
+		return false
+	}
+	if notification == nil {
+		return false
+	}
+
+	count, err := database.GetUnreadNotificationCount(userID)
+	if err != nil {
+		log.Printf("Error counting notifications for user %d: %v", userID, err)
+	}
+	h.SendToUser(userID, WebSocketMessage{
+		Type: EventTypeNotification,
+		Data: NotificationEvent{Notification: *notification, UnreadCount: count},
+	})
+	return true
+}
+
+// NotifyNotificationsRead sends a user's connections their new unread count
+// after notifications were read, so every tab updates its badge
+func (h *Hub) NotifyNotificationsRead(userID int) {
+	count, err := database.GetUnreadNotificationCount(userID)
+	if err != nil {
+		log.Printf("Error counting notifications for user %d: %v", userID, err)
+		return
+	}
+	h.SendToUser(userID, WebSocketMessage{
+		Type: EventTypeNotificationsRead,
+		Data: NotificationsReadEvent{UnreadCount: count},
+	})
+}
+
+// notifyReplies notifies the author of the comment replied to and everyone
+// watching the post of a new comment; a watching post author gets a post
+// reply rather than a watched post notification. It returns who was
+// notified so they are not notified again of a mention in the same comment.
+func (h *Hub) notifyReplies(comment *models.Comment) map[int]bool {
+	notified := make(map[int]bool)
+
+	if comment.ParentID > 0 {
+		authorID, err := database.GetTargetAuthorID(models.ReactionTargetComment, comment.ParentID)
+		if err != nil {
+			log.Printf("Error getting author of comment %d: %v", comment.ParentID, err)
+		} else if h.notify(authorID, models.NotificationCommentReply, comment.UserID, models.MentionTargetComment, comment.ID, "") {
+			notified[authorID] = true
+		}
+	}
+
+	postAuthorID, err := database.GetTargetAuthorID(models.ReactionTargetPost, comment.PostID)
+	if err != nil {
+		log.Printf("Error getting author of post %d: %v", comment.PostID, err)
+	}
+	watchers, err := database.GetPostWatcherIDs(comment.PostID)
+	if err != nil {
+		log.Printf("Error getting watchers of post %d: %v", comment.PostID, err)
+	}
+	for _, userID := range watchers {
+		if notified[userID] {
+			continue
+		}
+		notificationType := models.NotificationWatchedPost
+		if userID == postAuthorID {
+			notificationType = models.NotificationPostReply
+		}
+		if h.notify(userID, notificationType, comment.UserID, models.MentionTargetComment, comment.ID, "") {
+			notified[userID] = true
+		}
+	}
+	return notified
+}
+
+// notifyReaction notifies the author of a post, comment or message that
+// someone reacted to it
+func (h *Hub) notifyReaction(userID int, req models.ReactionRequest) {
+	authorID, err := database.GetTargetAuthorID(req.TargetType, req.TargetID)
+	if err != nil {
+		log.Printf("Error getting author of %s %d: %v", req.TargetType, req.TargetID, err)
+		return
+	}
+	h.notify(authorID, models.NotificationReaction, userID, req.TargetType, req.TargetID, req.Emoji)
+}
+
+// notifyDirectMessage notifies the recipient of a one-to-one message
+func (h *Hub) notifyDirectMessage(message *models.Message) {
+	recipients, err := database.GetDirectMessageRecipients(message)
+	if err != nil {
+		log.Printf("Error getting recipients of message %d: %v", message.ID, err)
+		return
+	}
+	for _, userID := range recipients {
+		h.notify(userID, models.NotificationDirectMessage, message.SenderID, models.MentionTargetMessage, message.ID, "")
+	}
+}
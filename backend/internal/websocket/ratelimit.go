@@ -0,0 +1,282 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Rate describes a token bucket: up to Burst events at once, refilled at
+// PerSecond tokens per second
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// RateLimitConfig controls how fast clients may send events
+type RateLimitConfig struct {
+	// Events limits each event type per user, across all of their connections
+	Events map[EventType]Rate
+	// Connection limits all events on a single connection
+	Connection Rate
+	// Clients exceeding a limit MaxViolations times within ViolationWindow
+	// are disconnected
+	MaxViolations   int
+	ViolationWindow time.Duration
+	// TypingInterval is the minimum gap between typing indicators from one
+	// sender delivered to the same recipient or room
+	TypingInterval time.Duration
+}
+
+// ParseRate parses a rate written as "<per second>:<burst>", such as "1:5";
+// "0:0" turns the limit off
+func ParseRate(value string) (Rate, error) {
+	perSecond, burst, ok := strings.Cut(value, ":")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q: want <per second>:<burst>", value)
+	}
+	var rate Rate
+	var err error
+	if rate.PerSecond, err = strconv.ParseFloat(perSecond, 64); err != nil || rate.PerSecond < 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: bad tokens per second", value)
+	}
+	if rate.Burst, err = strconv.Atoi(burst); err != nil || rate.Burst < 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: bad burst", value)
+	}
+	return rate, nil
+}
+
+// DefaultRateLimitConfig returns the limits used by NewHub
+func DefaultRateLimitConfig() RateLimitConfig {
+	typing := Rate{PerSecond: 5, Burst: 10}
+	return RateLimitConfig{
+		Events: map[EventType]Rate{
+			EventTypePrivateMessage: {PerSecond: 1, Burst: 5},
+			EventTypeTyping:         typing,
+			EventTypeStopTyping:     typing,
+			EventTypeRoomTyping:     typing,
+			EventTypeRoomStopTyping: typing,
+			EventTypeMarkRead:       {PerSecond: 2, Burst: 10},
+			EventTypeEditMessage:    {PerSecond: 1, Burst: 5},
+			EventTypeDeleteMessage:  {PerSecond: 1, Burst: 5},
+			EventTypeJoinRoom:       {PerSecond: 2, Burst: 10},
+			EventTypeLeaveRoom:      {PerSecond: 2, Burst: 10},
+		},
+		Connection:      Rate{PerSecond: 20, Burst: 40},
+		MaxViolations:   10,
+		ViolationWindow: time.Minute,
+		TypingInterval:  3 * time.Second,
+	}
+}
+
+// tokenBucket is a single token bucket; the zero value starts full
+type tokenBucket struct {
+	tokens  float64
+	last    time.Time
+	started bool
+}
+
+// allow takes a token if one is available
+func (b *tokenBucket) allow(rate Rate, now time.Time) bool {
+	if rate.PerSecond <= 0 || rate.Burst <= 0 {
+		return true
+	}
+	if !b.started {
+		b.tokens = float64(rate.Burst)
+		b.started = true
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * rate.PerSecond
+		if b.tokens > float64(rate.Burst) {
+			b.tokens = float64(rate.Burst)
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// full reports whether the bucket has refilled, which makes it no
+// different from a new one
+func (b *tokenBucket) full(rate Rate, now time.Time) bool {
+	if !b.started || rate.PerSecond <= 0 || rate.Burst <= 0 {
+		return true
+	}
+	return b.tokens+now.Sub(b.last).Seconds()*rate.PerSecond >= float64(rate.Burst)
+}
+
+// rateLimiter holds per-user buckets and typing coalescing state
+type rateLimiter struct {
+	mu     sync.Mutex
+	config RateLimitConfig
+	users  map[int]map[EventType]*tokenBucket
+	typing map[string]time.Time
+}
+
+// newRateLimiter creates a limiter with the given configuration
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		config: config,
+		users:  make(map[int]map[EventType]*tokenBucket),
+		typing: make(map[string]time.Time),
+	}
+}
+
+// clientLimits holds the per-connection rate limiting state
+type clientLimits struct {
+	mu         sync.Mutex
+	bucket     tokenBucket
+	violations []time.Time
+}
+
+// SetRateLimits replaces the hub's rate limit configuration
+func (h *Hub) SetRateLimits(config RateLimitConfig) {
+	h.limiter.mu.Lock()
+	h.limiter.config = config
+	h.limiter.users = make(map[int]map[EventType]*tokenBucket)
+	h.limiter.mu.Unlock()
+}
+
+// allowEvent checks the connection and per-user buckets for an incoming event
+func (h *Hub) allowEvent(client *Client, eventType EventType) bool {
+	now := time.Now()
+	allowed := h.allowUserEvent(client.userID, eventType, now)
+
+	h.limiter.mu.Lock()
+	connection := h.limiter.config.Connection
+	h.limiter.mu.Unlock()
+
+	client.limits.mu.Lock()
+	defer client.limits.mu.Unlock()
+	// Always charge the connection bucket so rejected frames still count
+	connAllowed := client.limits.bucket.allow(connection, now)
+	return allowed && connAllowed
+}
+
+// allowUserEvent takes a token from a user's bucket for an event type.
+// Events the user sends over plain HTTP share the bucket with their sockets.
+func (h *Hub) allowUserEvent(userID int, eventType EventType, now time.Time) bool {
+	h.limiter.mu.Lock()
+	defer h.limiter.mu.Unlock()
+
+	rate, ok := h.limiter.config.Events[eventType]
+	if !ok {
+		return true
+	}
+	buckets, ok := h.limiter.users[userID]
+	if !ok {
+		buckets = make(map[EventType]*tokenBucket)
+		h.limiter.users[userID] = buckets
+	}
+	bucket, ok := buckets[eventType]
+	if !ok {
+		bucket = &tokenBucket{}
+		buckets[eventType] = bucket
+	}
+	return bucket.allow(rate, now)
+}
+
+// rejectEvent tells the client an event was rate limited and disconnects
+// clients that keep exceeding their limits
+func (h *Hub) rejectEvent(client *Client, eventType EventType) {
+	now := time.Now()
+	h.metrics.rateLimited.Add(1)
+
+	h.limiter.mu.Lock()
+	config := h.limiter.config
+	h.limiter.mu.Unlock()
+
+	client.limits.mu.Lock()
+	recent := client.limits.violations[:0]
+	for _, at := range client.limits.violations {
+		if now.Sub(at) < config.ViolationWindow {
+			recent = append(recent, at)
+		}
+	}
+	client.limits.violations = append(recent, now)
+	count := len(client.limits.violations)
+	client.limits.mu.Unlock()
+
+	client.SendMessage(WebSocketMessage{
+		Type: EventTypeError,
+		Data: ErrorEvent{
+			Code:    ErrorCodeRateLimited,
+			Event:   eventType,
+			Message: fmt.Sprintf("too many %s events, slow down", eventType),
+		},
+	})
+
+	if config.MaxViolations > 0 && count >= config.MaxViolations {
+		if !client.isKicked() {
+			h.metrics.abuseDisconnects.Add(1)
+			log.Printf("Disconnecting user %d for repeated rate limit violations", client.userID)
+		}
+		client.kick(websocket.ClosePolicyViolation, "rate limit exceeded")
+	}
+}
+
+// allowTyping reports whether a typing indicator from sender to target
+// should be delivered, coalescing bursts into one per TypingInterval
+func (h *Hub) allowTyping(senderID int, target string) bool {
+	now := time.Now()
+	key := fmt.Sprintf("%d:%s", senderID, target)
+
+	h.limiter.mu.Lock()
+	defer h.limiter.mu.Unlock()
+
+	interval := h.limiter.config.TypingInterval
+	if last, ok := h.limiter.typing[key]; ok && now.Sub(last) < interval {
+		return false
+	}
+	h.limiter.typing[key] = now
+
+	// Keep the map from growing without bound
+	if len(h.limiter.typing) > 10000 {
+		for k, at := range h.limiter.typing {
+			if now.Sub(at) >= interval {
+				delete(h.limiter.typing, k)
+			}
+		}
+	}
+	return true
+}
+
+// stopTyping forgets a sender's last typing indicator to target once they
+// stop, so the next one is delivered right away instead of being coalesced
+func (h *Hub) stopTyping(senderID int, target string) {
+	key := fmt.Sprintf("%d:%s", senderID, target)
+
+	h.limiter.mu.Lock()
+	defer h.limiter.mu.Unlock()
+	delete(h.limiter.typing, key)
+}
+
+// pruneBuckets drops the buckets of users whose buckets have all refilled.
+// Buckets outlive the connections that drained them, so a user cannot get
+// fresh ones by reconnecting.
+func (h *Hub) pruneBuckets() {
+	now := time.Now()
+	h.limiter.mu.Lock()
+	defer h.limiter.mu.Unlock()
+
+	for userID, buckets := range h.limiter.users {
+		full := true
+		for eventType, bucket := range buckets {
+			if !bucket.full(h.limiter.config.Events[eventType], now) {
+				full = false
+				break
+			}
+		}
+		if full {
+			delete(h.limiter.users, userID)
+		}
+	}
+}
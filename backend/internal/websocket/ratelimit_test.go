@@ -0,0 +1,265 @@
+package websocket
+
+import (
+	"real-time-forum/backend/internal/models"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestHub starts a hub with the given rate limits and stops it when the
+// test ends
+func newTestHub(t *testing.T, config RateLimitConfig) *Hub {
+	t.Helper()
+	h := NewHub()
+	h.SetRateLimits(config)
+	go h.Run()
+	t.Cleanup(func() {
+		close(h.done)
+		<-h.stopped
+	})
+	return h
+}
+
+// slowRate barely refills, so tests can count on it not refilling while they run
+var slowRate = Rate{PerSecond: 0.001, Burst: 3}
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	rate := Rate{PerSecond: 2, Burst: 3}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  bool
+	}{
+		{"starts full", 0, true},
+		{"second token", 0, true},
+		{"third token", 0, true},
+		{"empty", 0, false},
+		{"still empty", 100 * time.Millisecond, false},
+		{"refilled one token", 600 * time.Millisecond, true},
+		{"empty again", 600 * time.Millisecond, false},
+		{"refills up to burst only", time.Hour, true},
+		{"burst token 2", time.Hour, true},
+		{"burst token 3", time.Hour, true},
+		{"burst spent", time.Hour, false},
+	}
+	var b tokenBucket
+	for _, tt := range tests {
+		if got := b.allow(rate, start.Add(tt.after)); got != tt.want {
+			t.Errorf("%s: allow() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTokenBucketDisabled(t *testing.T) {
+	var b tokenBucket
+	for _, rate := range []Rate{{}, {PerSecond: 1}, {Burst: 1}} {
+		for i := 0; i < 100; i++ {
+			if !b.allow(rate, time.Now()) {
+				t.Fatalf("allow(%+v) = false, want no limit", rate)
+			}
+		}
+	}
+}
+
+func TestTokenBucketFull(t *testing.T) {
+	start := time.Now()
+	rate := Rate{PerSecond: 1, Burst: 2}
+
+	var b tokenBucket
+	if !b.full(rate, start) {
+		t.Error("new bucket is not full")
+	}
+	b.allow(rate, start)
+	if b.full(rate, start) {
+		t.Error("drained bucket is full")
+	}
+	if b.full(rate, start.Add(500*time.Millisecond)) {
+		t.Error("bucket is full before it refilled")
+	}
+	if !b.full(rate, start.Add(time.Second)) {
+		t.Error("bucket is not full after it refilled")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Rate
+		wantErr bool
+	}{
+		{"1:5", Rate{PerSecond: 1, Burst: 5}, false},
+		{"0.5:2", Rate{PerSecond: 0.5, Burst: 2}, false},
+		{"0:0", Rate{}, false},
+		{"5", Rate{}, true},
+		{"a:5", Rate{}, true},
+		{"1:b", Rate{}, true},
+		{"-1:5", Rate{}, true},
+		{"1:-5", Rate{}, true},
+		{"1:2.5", Rate{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRate(%q) = %+v, %v; want %+v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAllowEventSharesUserBucketAcrossConnections(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	config.Events = map[EventType]Rate{EventTypePrivateMessage: slowRate}
+	config.Connection = Rate{}
+	h := newTestHub(t, config)
+
+	// Several connections of one user race for the same bucket
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		client := NewClient(h, nil, 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if h.allowEvent(client, EventTypePrivateMessage) {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != int32(slowRate.Burst) {
+		t.Errorf("%d events allowed across connections, want %d", got, slowRate.Burst)
+	}
+	if !h.allowEvent(NewClient(h, nil, 2), EventTypePrivateMessage) {
+		t.Error("another user shares the drained bucket")
+	}
+	if !h.allowEvent(NewClient(h, nil, 1), EventTypeMarkRead) {
+		t.Error("an event type without a limit was rejected")
+	}
+}
+
+func TestAllowEventChargesConnectionBucket(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	config.Events = map[EventType]Rate{}
+	config.Connection = slowRate
+	h := newTestHub(t, config)
+
+	first, second := NewClient(h, nil, 1), NewClient(h, nil, 1)
+	for i := 0; i < slowRate.Burst; i++ {
+		if !h.allowEvent(first, EventTypeTyping) {
+			t.Fatalf("event %d rejected within the connection burst", i)
+		}
+	}
+	if h.allowEvent(first, EventTypeTyping) {
+		t.Error("event allowed past the connection burst")
+	}
+	if !h.allowEvent(second, EventTypeTyping) {
+		t.Error("another connection shares the drained connection bucket")
+	}
+}
+
+func TestBucketsSurviveReconnect(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	config.Events = map[EventType]Rate{EventTypePrivateMessage: slowRate, EventTypeTyping: slowRate}
+	h := newTestHub(t, config)
+
+	for h.allowUserEvent(1, EventTypePrivateMessage, time.Now()) {
+	}
+	h.allowUserEvent(2, EventTypeTyping, time.Now())
+	h.allowUserEvent(3, EventTypeTyping, time.Now().Add(-time.Hour))
+
+	h.pruneBuckets()
+
+	if h.allowUserEvent(1, EventTypePrivateMessage, time.Now()) {
+		t.Error("a drained bucket was reset by pruning")
+	}
+	h.limiter.mu.Lock()
+	_, kept := h.limiter.users[2]
+	_, pruned := h.limiter.users[3]
+	h.limiter.mu.Unlock()
+	if !kept {
+		t.Error("a partly drained bucket was pruned")
+	}
+	if pruned {
+		t.Error("a refilled bucket was kept")
+	}
+}
+
+func TestSendPrivateMessageRateLimited(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	config.Events = map[EventType]Rate{EventTypePrivateMessage: slowRate}
+	h := newTestHub(t, config)
+
+	// A socket drains the bucket; the REST path must not get around it
+	client := NewClient(h, nil, 1)
+	for h.allowEvent(client, EventTypePrivateMessage) {
+	}
+	req := models.CreateMessageRequest{RecipientID: 2, Content: "hi"}
+	if _, err := h.SendPrivateMessage(1, req); err != ErrRateLimited {
+		t.Errorf("SendPrivateMessage() error = %v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestAllowTypingCoalesces(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	config.TypingInterval = 50 * time.Millisecond
+	h := newTestHub(t, config)
+
+	// Concurrent indicators from one sender to one target collapse into one
+	var delivered atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if h.allowTyping(1, "user:2") {
+				delivered.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := delivered.Load(); got != 1 {
+		t.Errorf("%d typing indicators delivered within the interval, want 1", got)
+	}
+
+	if !h.allowTyping(1, "post:7") {
+		t.Error("typing in another room was coalesced")
+	}
+	if !h.allowTyping(3, "user:2") {
+		t.Error("typing from another sender was coalesced")
+	}
+
+	time.Sleep(config.TypingInterval)
+	if !h.allowTyping(1, "user:2") {
+		t.Error("typing was still coalesced after the interval")
+	}
+}
+
+func TestStopTypingEndsCoalescing(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	config.TypingInterval = time.Hour
+	h := newTestHub(t, config)
+
+	// type → stop → type within the interval: the second indicator must get through
+	if !h.allowTyping(1, "user:2") {
+		t.Fatal("first typing indicator was coalesced")
+	}
+	h.stopTyping(1, "user:2")
+	if !h.allowTyping(1, "user:2") {
+		t.Error("typing after stop_typing was coalesced")
+	}
+	if h.allowTyping(1, "user:2") {
+		t.Error("a burst after typing resumed was not coalesced")
+	}
+
+	h.allowTyping(1, "post:7")
+	h.stopTyping(1, "user:2")
+	if h.allowTyping(1, "post:7") {
+		t.Error("stopping in one chat reset coalescing in another")
+	}
+}
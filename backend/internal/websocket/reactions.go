@@ -0,0 +1,102 @@
+package websocket
+
+import (
+	"log"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+)
+
+// React adds or removes a user's reaction and broadcasts the target's new
+// counts to whoever can see it: everyone who may view the post for posts,
+// the post's viewers for comments and the participants for messages. It
+// returns the counts as seen by the user.
+func (h *Hub) React(userID int, req models.ReactionRequest, add bool) ([]models.ReactionCount, error) {
+	event := ReactionUpdatedEvent{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		UserID:     userID,
+		Emoji:      req.Emoji,
+		Added:      add,
+	}
+
+	switch req.TargetType {
+	case models.ReactionTargetPost:
+		if err := database.CheckPostAccess(userID, req.TargetID, models.CategoryActionView); err != nil {
+			return nil, err
+		}
+		event.PostID = req.TargetID
+	case models.ReactionTargetComment:
+		postID, err := database.GetCommentPostID(req.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		if err := database.CheckPostAccess(userID, postID, models.CategoryActionView); err == database.ErrPostNotFound {
+			return nil, database.ErrCommentNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		event.PostID = postID
+	case models.ReactionTargetMessage:
+		message, err := database.GetMessage(req.TargetID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if message.IsDeleted {
+			return nil, database.ErrMessageDeleted
+		}
+		event.ConversationID = message.ConversationID
+	}
+	if event.PostID != 0 {
+		if err := database.CheckPostWritable(event.PostID); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed bool
+	var err error
+	if add {
+		changed, err = database.AddReaction(userID, req.TargetType, req.TargetID, req.Emoji)
+	} else {
+		changed, err = database.RemoveReaction(userID, req.TargetType, req.TargetID, req.Emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		// Broadcast counts without anyone's reacted flag
+		counts, err := database.GetReactionCounts(req.TargetType, req.TargetID, 0)
+		if err != nil {
+			log.Printf("Error counting reactions on %s %d: %v", req.TargetType, req.TargetID, err)
+		} else {
+			event.Reactions = counts
+			h.broadcastReaction(event)
+		}
+		if add {
+			h.notifyReaction(userID, req)
+		}
+	}
+
+	return database.GetReactionCounts(req.TargetType, req.TargetID, userID)
+}
+
+// broadcastReaction delivers a reaction update to the audience of its target
+func (h *Hub) broadcastReaction(event ReactionUpdatedEvent) {
+	response := WebSocketMessage{Type: EventTypeReactionUpdated, Data: event}
+
+	switch event.TargetType {
+	case models.ReactionTargetPost:
+		h.sendFiltered(h.clientSnapshot(), response, h.hidesPost(event.PostID))
+	case models.ReactionTargetComment:
+		// Viewers who blocked or muted the comment's author or the reactor
+		// get neither
+		authorID, err := database.GetTargetAuthorID(event.TargetType, event.TargetID)
+		if err != nil {
+			log.Printf("Error finding the author of comment %d: %v", event.TargetID, err)
+			return
+		}
+		h.sendToPostRoom(event.PostID, response, authorID, event.UserID)
+	case models.ReactionTargetMessage:
+		h.sendToConversation(event.ConversationID, response, 0)
+	}
+}
@@ -0,0 +1,314 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"real-time-forum/backend/internal/database"
+	"strconv"
+	"strings"
+)
+
+// Room name prefixes for the kinds of rooms the hub knows about
+const (
+	roomPrefixPost = "post:"
+)
+
+// Room represents a named group of clients that receive scoped events
+type Room struct {
+	name    string
+	clients map[*Client]bool
+}
+
+// newRoom creates an empty room
+func newRoom(name string) *Room {
+	return &Room{
+		name:    name,
+		clients: make(map[*Client]bool),
+	}
+}
+
+// PostRoomName returns the room name used for viewers of a post
+func PostRoomName(postID int) string {
+	return fmt.Sprintf("%s%d", roomPrefixPost, postID)
+}
+
+// canJoinRoom reports whether a client is allowed to join the named room
+func (h *Hub) canJoinRoom(client *Client, name string) bool {
+	switch {
+	case strings.HasPrefix(name, roomPrefixPost):
+		postID, err := strconv.Atoi(strings.TrimPrefix(name, roomPrefixPost))
+		if err != nil || postID <= 0 {
+			return false
+		}
+		return h.canViewPost(client.userID, postID)
+	}
+	return false
+}
+
+// JoinRoom adds a client to a room and broadcasts the updated presence
+func (h *Hub) JoinRoom(client *Client, name string) error {
+	if !h.canJoinRoom(client, name) {
+		return ErrInvalidRoom
+	}
+
+	h.mu.Lock()
+	if client.isClosed() {
+		h.mu.Unlock()
+		return ErrClientDisconnected
+	}
+	room, ok := h.rooms[name]
+	if !ok {
+		room = newRoom(name)
+		h.rooms[name] = room
+	}
+	room.clients[client] = true
+	client.rooms[name] = true
+	h.mu.Unlock()
+
+	h.broadcastRoomPresence(name)
+	return nil
+}
+
+// LeaveRoom removes a client from a room and broadcasts the updated presence
+func (h *Hub) LeaveRoom(client *Client, name string) {
+	h.mu.Lock()
+	left := h.removeFromRoom(client, name)
+	h.mu.Unlock()
+
+	if left {
+		h.broadcastRoomPresence(name)
+	}
+}
+
+// leaveAllRooms removes a client from every room it has joined
+func (h *Hub) leaveAllRooms(client *Client) {
+	h.mu.Lock()
+	var left []string
+	for name := range client.rooms {
+		if h.removeFromRoom(client, name) {
+			left = append(left, name)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, name := range left {
+		h.broadcastRoomPresence(name)
+	}
+}
+
+// removeFromRoom drops a client from a room, deleting the room once empty.
+// The caller must hold h.mu.
+func (h *Hub) removeFromRoom(client *Client, name string) bool {
+	delete(client.rooms, name)
+
+	room, ok := h.rooms[name]
+	if !ok || !room.clients[client] {
+		return false
+	}
+	delete(room.clients, client)
+	if len(room.clients) == 0 {
+		delete(h.rooms, name)
+	}
+	return true
+}
+
+// roomClients returns a snapshot of the clients currently in a room
+func (h *Hub) roomClients(name string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	room, ok := h.rooms[name]
+	if !ok {
+		return nil
+	}
+	clients := make([]*Client, 0, len(room.clients))
+	for client := range room.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// InRoom reports whether a client has joined the named room
+func (h *Hub) InRoom(client *Client, name string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return client.rooms[name]
+}
+
+// SendToRoom sends a message to every client in a room
+func (h *Hub) SendToRoom(name string, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling room %s message: %v", name, err)
+		return
+	}
+
+	for _, client := range h.roomClients(name) {
+		client.SendRaw(data)
+	}
+}
+
+// sendToPostRoom sends a message to the viewers of a post. Room members are
+// checked again, as they may have lost access to the post's category since
+// they joined, and those who blocked or muted any of authorIDs are skipped.
+func (h *Hub) sendToPostRoom(postID int, message interface{}, authorIDs ...int) {
+	hidden := h.hidesPost(postID)
+	h.sendFiltered(h.roomClients(PostRoomName(postID)), message, func(client *Client) bool {
+		if hidden(client) {
+			return true
+		}
+		for _, authorID := range authorIDs {
+			if h.hidesAuthor(client.userID, authorID) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RoomViewers returns the distinct users currently in a room
+func (h *Hub) RoomViewers(name string) []UserStatus {
+	seen := make(map[int]bool)
+	var viewers []UserStatus
+	for _, client := range h.roomClients(name) {
+		if seen[client.userID] {
+			continue
+		}
+		seen[client.userID] = true
+
+		user, err := database.GetUserByID(client.userID)
+		if err != nil {
+			continue
+		}
+		viewers = append(viewers, UserStatus{
+			ID:          user.ID,
+			Nickname:    user.Nickname,
+			AvatarColor: user.AvatarColor,
+			AvatarURL:   user.AvatarURL,
+			IsOnline:    true,
+			LastSeen:    user.LastSeen,
+		})
+	}
+	return viewers
+}
+
+// broadcastRoomPresence sends the current viewer list to everyone in a room,
+// leaving out viewers on the other side of a block
+func (h *Hub) broadcastRoomPresence(name string) {
+	viewers := h.RoomViewers(name)
+	shared, err := marshalRoomPresence(name, viewers)
+	if err != nil {
+		log.Printf("Error marshaling room %s presence: %v", name, err)
+		return
+	}
+
+	key := string(EventTypeRoomPresence) + ":" + name
+	perUser := make(map[int][]byte)
+	for _, client := range h.roomClients(name) {
+		filter := h.userFilter(client.userID)
+		if len(filter.blocked) == 0 {
+			client.SendPresence(key, shared)
+			continue
+		}
+
+		data, ok := perUser[client.userID]
+		if !ok {
+			var visible []UserStatus
+			for _, viewer := range viewers {
+				if !filter.blocked[viewer.ID] {
+					visible = append(visible, viewer)
+				}
+			}
+			if data, err = marshalRoomPresence(name, visible); err != nil {
+				log.Printf("Error marshaling room %s presence: %v", name, err)
+				continue
+			}
+			perUser[client.userID] = data
+		}
+		client.SendPresence(key, data)
+	}
+}
+
+// marshalRoomPresence encodes a room presence event
+func marshalRoomPresence(name string, viewers []UserStatus) ([]byte, error) {
+	return json.Marshal(WebSocketMessage{
+		Type: EventTypeRoomPresence,
+		Data: RoomPresenceEvent{
+			Room:    name,
+			Count:   len(viewers),
+			Viewers: viewers,
+		},
+	})
+}
+
+// HandleJoinRoom handles a client's request to join a room
+func (h *Hub) HandleJoinRoom(client *Client, msg map[string]interface{}) {
+	name, ok := msg["room"].(string)
+	if !ok {
+		return
+	}
+
+	if err := h.JoinRoom(client, name); err != nil {
+		client.SendMessage(WebSocketMessage{
+			Type: EventTypeError,
+			Data: ErrorEvent{Event: EventTypeJoinRoom, Message: err.Error()},
+		})
+	}
+}
+
+// HandleLeaveRoom handles a client's request to leave a room
+func (h *Hub) HandleLeaveRoom(client *Client, msg map[string]interface{}) {
+	name, ok := msg["room"].(string)
+	if !ok {
+		return
+	}
+
+	h.LeaveRoom(client, name)
+}
+
+// HandleRoomTyping relays a typing indicator to the other members of a room
+func (h *Hub) HandleRoomTyping(client *Client, msg map[string]interface{}) {
+	h.relayRoomTyping(client, msg, EventTypeRoomTyping)
+}
+
+// HandleRoomStopTyping relays a stop typing indicator to the other members of a room
+func (h *Hub) HandleRoomStopTyping(client *Client, msg map[string]interface{}) {
+	h.relayRoomTyping(client, msg, EventTypeRoomStopTyping)
+}
+
+// relayRoomTyping sends a room-scoped typing event from a room member
+func (h *Hub) relayRoomTyping(client *Client, msg map[string]interface{}, eventType EventType) {
+	name, ok := msg["room"].(string)
+	if !ok || !h.InRoom(client, name) {
+		return
+	}
+
+	var username string
+	if eventType == EventTypeRoomTyping {
+		// Coalesce bursts so room members get at most one per interval
+		if !h.allowTyping(client.userID, name) {
+			return
+		}
+
+		sender, err := database.GetUserByID(client.userID)
+		if err != nil {
+			return
+		}
+		username = sender.Nickname
+	} else {
+		h.stopTyping(client.userID, name)
+	}
+
+	message := WebSocketMessage{
+		Type: eventType,
+		Data: RoomTypingEvent{
+			Room:     name,
+			UserID:   client.userID,
+			Username: username,
+		},
+	}
+	h.sendFiltered(h.roomClients(name), message, func(other *Client) bool {
+		return other == client || h.isBlocked(client.userID, other.userID)
+	})
+}
@@ -0,0 +1,232 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the tests against a fresh database in a temporary directory,
+// migrated from the repository's migrations folder
+func TestMain(m *testing.M) {
+	migrations, err := filepath.Abs("../../../migrations")
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir, err := os.MkdirTemp("", "forum-websocket-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Symlink(migrations, filepath.Join(dir, "migrations")); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatal(err)
+	}
+	if err := database.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	database.CloseDB()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+var testUserCount int
+
+// newTestUser inserts a user with the given role and returns its ID
+func newTestUser(t *testing.T, role string) int {
+	t.Helper()
+	testUserCount++
+	nickname := fmt.Sprintf("user%d", testUserCount)
+	var id int
+	err := database.DB.QueryRow(`
+		INSERT INTO users (nickname, email, password_hash, first_name, last_name, age, gender, role)
+		VALUES (?, ?, '', 'Test', 'User', 30, 'other', ?)
+		RETURNING id
+	`, nickname, nickname+"@example.com", role).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// roomFixture is a post with a comment whose room is watched by one client
+// per viewer
+type roomFixture struct {
+	hub        *Hub
+	categoryID int
+	post       *models.Post
+	comment    *models.Comment
+	clients    map[int]*Client
+}
+
+// newRoomFixture creates a post and a comment by authorID and puts a client
+// of every viewer in the post's room
+func newRoomFixture(t *testing.T, authorID int, viewerIDs ...int) *roomFixture {
+	t.Helper()
+	var categoryID int
+	err := database.DB.QueryRow("INSERT INTO categories (name) VALUES (?) RETURNING id",
+		fmt.Sprintf("room test %d", testUserCount)).Scan(&categoryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := &models.Post{UserID: authorID, CategoryID: categoryID, Title: "Room", Content: "hi"}
+	if err := database.CreatePost(post, nil); err != nil {
+		t.Fatal(err)
+	}
+	comment := &models.Comment{PostID: post.ID, UserID: authorID, Content: "a comment"}
+	if err := database.CreateComment(comment, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &roomFixture{
+		hub:        newTestHub(t, DefaultRateLimitConfig()),
+		categoryID: categoryID,
+		post:       post,
+		comment:    comment,
+		clients:    make(map[int]*Client),
+	}
+	for _, id := range viewerIDs {
+		client := NewClient(f.hub, nil, id)
+		if err := f.hub.JoinRoom(client, PostRoomName(post.ID)); err != nil {
+			t.Fatal(err)
+		}
+		f.clients[id] = client
+	}
+	for _, client := range f.clients {
+		queued(client)
+	}
+	return f
+}
+
+// received reports which viewers got an event of the given type
+func (f *roomFixture) received(eventType EventType) map[int]bool {
+	got := make(map[int]bool)
+	for id, client := range f.clients {
+		for _, frame := range queued(client) {
+			if strings.Contains(frame, `"type":"`+string(eventType)+`"`) {
+				got[id] = true
+			}
+		}
+	}
+	return got
+}
+
+// restrict limits viewing the fixture's category to moderators
+func (f *roomFixture) restrict(t *testing.T) {
+	t.Helper()
+	permissions := []models.CategoryPermission{{Action: models.CategoryActionView, Role: models.RoleModerator}}
+	if err := database.SetCategoryPermissions(f.categoryID, permissions); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleJoinRoomErrorNamesTheEvent(t *testing.T) {
+	h := newTestHub(t, DefaultRateLimitConfig())
+	c := newTestClient(h, 4, DropPolicyDisconnect)
+
+	h.HandleJoinRoom(c, map[string]interface{}{"room": "lobby"})
+
+	frames := queued(c)
+	if len(frames) != 1 {
+		t.Fatalf("got %d frames, want one error", len(frames))
+	}
+	var reply struct {
+		Type EventType  `json:"type"`
+		Data ErrorEvent `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(frames[0]), &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Type != EventTypeError || reply.Data.Event != EventTypeJoinRoom {
+		t.Errorf("reply = %s, want an error for %s", frames[0], EventTypeJoinRoom)
+	}
+	if reply.Data.Message != ErrInvalidRoom.Error() {
+		t.Errorf("error message = %q, want %q", reply.Data.Message, ErrInvalidRoom.Error())
+	}
+}
+
+func TestCommentReactionsAreFilteredInTheRoom(t *testing.T) {
+	author := newTestUser(t, models.RoleUser)
+	reactor := newTestUser(t, models.RoleUser)
+	viewer := newTestUser(t, models.RoleUser)
+	blocksReactor := newTestUser(t, models.RoleUser)
+	mutesAuthor := newTestUser(t, models.RoleUser)
+	moderator := newTestUser(t, models.RoleModerator)
+	if err := database.BlockUser(blocksReactor, reactor); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.MuteUser(mutesAuthor, author); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newRoomFixture(t, author, viewer, blocksReactor, mutesAuthor, moderator)
+	react := func() map[int]bool {
+		f.hub.broadcastReaction(ReactionUpdatedEvent{
+			TargetType: models.ReactionTargetComment,
+			TargetID:   f.comment.ID,
+			PostID:     f.post.ID,
+			UserID:     reactor,
+			Emoji:      "👍",
+			Added:      true,
+		})
+		return f.received(EventTypeReactionUpdated)
+	}
+
+	got := react()
+	want := map[int]bool{viewer: true, moderator: true}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("reaction delivered to %v, want only %v", got, want)
+	}
+
+	// Viewers who lost access to the category since joining get nothing
+	f.restrict(t)
+	got = react()
+	want = map[int]bool{moderator: true}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("reaction after restricting the category delivered to %v, want only %v", got, want)
+	}
+}
+
+func TestScoreUpdatesAreFilteredInTheRoom(t *testing.T) {
+	author := newTestUser(t, models.RoleUser)
+	viewer := newTestUser(t, models.RoleUser)
+	blocksAuthor := newTestUser(t, models.RoleUser)
+	moderator := newTestUser(t, models.RoleModerator)
+	if err := database.BlockUser(blocksAuthor, author); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newRoomFixture(t, author, viewer, blocksAuthor, moderator)
+	targets := []struct {
+		targetType string
+		targetID   int
+	}{
+		{models.VoteTargetPost, f.post.ID},
+		{models.VoteTargetComment, f.comment.ID},
+	}
+	for _, target := range targets {
+		f.hub.broadcastScore(ScoreUpdatedEvent{TargetType: target.targetType, TargetID: target.targetID, PostID: f.post.ID, Score: 1})
+		got := f.received(EventTypeScoreUpdated)
+		want := map[int]bool{viewer: true, moderator: true}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s score delivered to %v, want only %v", target.targetType, got, want)
+		}
+	}
+
+	f.restrict(t)
+	f.hub.broadcastScore(ScoreUpdatedEvent{TargetType: models.VoteTargetComment, TargetID: f.comment.ID, PostID: f.post.ID, Score: 2})
+	got := f.received(EventTypeScoreUpdated)
+	want := map[int]bool{moderator: true}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("score after restricting the category delivered to %v, want only %v", got, want)
+	}
+}
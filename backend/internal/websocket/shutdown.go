@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gorilla/websocket"
+)
+
+// Shutdown stops the hub and disconnects every client. Clients are told to
+// reconnect after the given number of seconds, through a server_shutdown
+// event and, for WebSocket clients, a going-away close frame. It waits for
+// queued frames to be flushed until ctx expires.
+func (h *Hub) Shutdown(ctx context.Context, reconnectAfter int) error {
+	h.shutdownOnce.Do(func() {
+		close(h.done)
+	})
+
+	// Wait for Run to return so nothing else touches the client maps
+	select {
+	case <-h.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	h.mu.Lock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.clients = make(map[*Client]bool)
+	h.userClients = make(map[int]*Client)
+	h.clientsByID = make(map[string]*Client)
+	h.rooms = make(map[string]*Room)
+	h.mu.Unlock()
+
+	notice := WebSocketMessage{
+		Type: EventTypeServerShutdown,
+		Data: ServerShutdownEvent{ReconnectAfter: reconnectAfter},
+	}
+	reason := fmt.Sprintf("server restarting, reconnect in %ds", reconnectAfter)
+	for _, client := range clients {
+		client.SendMessage(notice)
+		client.closeSendWith(websocket.CloseGoingAway, reason)
+	}
+	log.Printf("Hub shutting down: closing %d clients", len(clients))
+
+	drained := make(chan struct{})
+	go func() {
+		h.pumps.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"log"
+	"real-time-forum/backend/internal/database"
+	"real-time-forum/backend/internal/models"
+)
+
+// Vote records a user's vote and sends the new score to the post's viewers
+func (h *Hub) Vote(userID int, req models.VoteRequest) (models.VoteResult, error) {
+	var postID int
+	switch req.TargetType {
+	case models.VoteTargetPost:
+		if err := database.CheckPostAccess(userID, req.TargetID, models.CategoryActionView); err != nil {
+			return models.VoteResult{}, err
+		}
+		postID = req.TargetID
+	case models.VoteTargetComment:
+		id, err := database.GetCommentPostID(req.TargetID)
+		if err != nil {
+			return models.VoteResult{}, err
+		}
+		if err := database.CheckPostAccess(userID, id, models.CategoryActionView); err == database.ErrPostNotFound {
+			return models.VoteResult{}, database.ErrCommentNotFound
+		} else if err != nil {
+			return models.VoteResult{}, err
+		}
+		postID = id
+	}
+	if err := database.CheckPostWritable(postID); err != nil {
+		return models.VoteResult{}, err
+	}
+
+	score, err := database.SetVote(userID, req.TargetType, req.TargetID, req.Value)
+	if err != nil {
+		return models.VoteResult{}, err
+	}
+
+	h.broadcastScore(ScoreUpdatedEvent{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		PostID:     postID,
+		Score:      score,
+	})
+
+	return models.VoteResult{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Score:      score,
+		UserVote:   req.Value,
+	}, nil
+}
+
+// broadcastScore sends a new score to the post's viewers, leaving out those
+// who may no longer view the post or who blocked or muted the author of
+// what was voted on
+func (h *Hub) broadcastScore(event ScoreUpdatedEvent) {
+	authorID, err := database.GetTargetAuthorID(event.TargetType, event.TargetID)
+	if err != nil {
+		log.Printf("Error finding the author of %s %d: %v", event.TargetType, event.TargetID, err)
+		return
+	}
+	h.sendToPostRoom(event.PostID, WebSocketMessage{Type: EventTypeScoreUpdated, Data: event}, authorID)
+}
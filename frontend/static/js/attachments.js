@@ -0,0 +1,40 @@
+window.Attachments = {
+    // Uploads every file picked in an input and returns their IDs
+    async uploadFiles(input) {
+        const ids = [];
+        for (const file of input?.files || []) {
+            const body = new FormData();
+            body.append('file', file);
+            const response = await fetch('/api/attachments', {
+                method: 'POST',
+                body,
+                credentials: 'include'
+            });
+            if (!response.ok) {
+                throw new Error(`${file.name}: ${await response.text()}`);
+            }
+            const attachment = await response.json();
+            ids.push(attachment.id);
+        }
+        return ids;
+    },
+
+    render(attachments) {
+        if (!attachments || attachments.length === 0) return '';
+        const items = attachments.map(a => {
+            if (a.thumbnailUrl) {
+                return `<a href="${escapeHtml(a.url)}" target="_blank" rel="noopener">
+                    <img src="${escapeHtml(a.thumbnailUrl)}" alt="${escapeHtml(a.filename)}" class="max-h-32 rounded border border-gray-200">
+                </a>`;
+            }
+            return `<a href="${escapeHtml(a.url)}" class="underline text-sm" target="_blank" rel="noopener">📎 ${escapeHtml(a.filename)} (${this.formatSize(a.size)})</a>`;
+        });
+        return `<div class="flex flex-wrap gap-2 mt-2">${items.join('')}</div>`;
+    },
+
+    formatSize(bytes) {
+        if (bytes < 1024) return `${bytes} B`;
+        if (bytes < 1024 * 1024) return `${Math.round(bytes / 1024)} KB`;
+        return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+    }
+};
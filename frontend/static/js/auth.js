@@ -176,6 +176,17 @@ window.Auth = {
 
         document.getElementById('save-profile-btn')?.addEventListener('click', this.handleSaveProfile);
 
+        document.getElementById('edit-avatar-file')?.addEventListener('change', (e) => {
+            if (e.target.files.length > 0) {
+                this.updateAvatar('POST', e.target.files[0]);
+            }
+            e.target.value = '';
+        });
+
+        document.getElementById('remove-avatar-btn')?.addEventListener('click', () => {
+            this.updateAvatar('DELETE');
+        });
+
         document.querySelectorAll('[data-edit-color]').forEach(button => {
             button.addEventListener('click', (e) => {
                 document.querySelectorAll('[data-edit-color]').forEach(b => b.classList.remove('ring-2', 'ring-offset-2', 'ring-blue-500'));
@@ -300,6 +311,10 @@ window.Auth = {
         document.getElementById('edit-nickname').value = user.nickname || '';
         document.getElementById('edit-age').value = user.age || '';
         document.getElementById('edit-gender').value = user.gender || '';
+        document.getElementById('edit-dm-privacy').value = user.dmPrivacy || 'everyone';
+
+        const preview = document.getElementById('edit-avatar-preview');
+        if (preview) preview.innerHTML = avatarImage(user.avatarUrl, 64, 'w-12 h-12');
 
         document.querySelectorAll('[data-edit-color]').forEach(button => {
             button.classList.remove('ring-2', 'ring-offset-2', 'ring-blue-500');
@@ -316,6 +331,7 @@ window.Auth = {
             nickname: document.getElementById('edit-nickname').value.trim(),
             age: parseInt(document.getElementById('edit-age').value) || 0,
             gender: document.getElementById('edit-gender').value,
+            dmPrivacy: document.getElementById('edit-dm-privacy').value,
             avatarColor: document.querySelector('[data-edit-color].ring-2')?.dataset.editColor || ForumApp.currentUser.avatarColor
         };
 
@@ -357,6 +373,31 @@ window.Auth = {
         }
     },
 
+    async updateAvatar(method, file) {
+        const options = { method, credentials: 'include' };
+        if (file) {
+            const formData = new FormData();
+            formData.append('file', file);
+            options.body = formData;
+        }
+
+        try {
+            const response = await fetch('/api/profile/avatar', options);
+            if (response.ok) {
+                ForumApp.currentUser = { ...ForumApp.currentUser, ...await response.json() };
+                showLoggedInUI();
+                this.loadProfileData();
+                showNotification(file ? 'Avatar updated' : 'Avatar removed');
+            } else {
+                const error = await response.text();
+                showNotification(error || 'Failed to update avatar', 'error');
+            }
+        } catch (error) {
+            console.error('Avatar update error:', error);
+            showNotification('Error updating avatar', 'error');
+        }
+    },
+
     handleLogout() {
         Auth.logout();
     }
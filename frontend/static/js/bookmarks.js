@@ -0,0 +1,185 @@
+window.Bookmarks = {
+    items: [],
+    folders: [],
+    folderId: '',
+    offset: 0,
+
+    init() {
+        document.getElementById('bookmark-folder')?.addEventListener('change', (e) => {
+            this.folderId = e.target.value;
+            this.load();
+        });
+        document.getElementById('bookmark-folder-new')?.addEventListener('click', () => this.createFolder());
+        document.getElementById('bookmark-folder-rename')?.addEventListener('click', () => this.renameFolder());
+        document.getElementById('bookmark-folder-delete')?.addEventListener('click', () => this.deleteFolder());
+        document.getElementById('bookmarks-more')?.addEventListener('click', () => this.load(this.offset));
+        document.getElementById('bookmarks-list')?.addEventListener('click', (e) => {
+            const li = e.target.closest('li[data-id]');
+            if (!li) return;
+            const bookmark = this.items.find(b => b.id === parseInt(li.dataset.id));
+            if (!bookmark) return;
+            if (e.target.closest('.bookmark-remove')) {
+                this.remove(bookmark.targetType, bookmark.targetId);
+            } else {
+                Posts.loadPostDetails(bookmark.postId);
+            }
+        });
+    },
+
+    async load(offset = 0) {
+        try {
+            const params = new URLSearchParams({ offset });
+            if (this.folderId) params.set('folder_id', this.folderId);
+            const response = await fetch(`/api/bookmarks?${params}`, { credentials: 'include' });
+            if (!response.ok) return;
+            const bookmarks = await response.json();
+            this.items = offset === 0 ? bookmarks : this.items.concat(bookmarks);
+            this.offset = this.items.length;
+            document.getElementById('bookmarks-more')?.classList.toggle('hidden', bookmarks.length < 20);
+            this.render();
+            if (offset === 0) this.loadFolders();
+        } catch (error) {
+            console.error('Error loading bookmarks:', error);
+        }
+    },
+
+    render() {
+        const list = document.getElementById('bookmarks-list');
+        if (!list) return;
+        if (this.items.length === 0) {
+            list.innerHTML = '<li class="text-sm text-gray-500">Nothing saved yet</li>';
+            return;
+        }
+        list.innerHTML = this.items.map(b => `
+            <li data-id="${b.id}" class="text-sm cursor-pointer hover:bg-gray-50 rounded p-1">
+                <div class="flex justify-between items-start">
+                    <p class="font-medium text-gray-700 truncate">${escapeHtml(b.postTitle)}</p>
+                    <button class="bookmark-remove text-gray-400 hover:text-red-600 ml-2" title="Remove bookmark">&times;</button>
+                </div>
+                ${b.targetType === 'comment' ? `<p class="text-gray-500 truncate">${escapeHtml(b.authorName)}: ${escapeHtml(b.excerpt)}</p>` : ''}
+                ${b.note ? `<p class="text-xs text-gray-400 truncate">${escapeHtml(b.note)}</p>` : ''}
+            </li>
+        `).join('');
+    },
+
+    async loadFolders() {
+        const select = document.getElementById('bookmark-folder');
+        if (!select) return;
+        try {
+            const response = await fetch('/api/bookmarks/folders', { credentials: 'include' });
+            if (!response.ok) return;
+            this.folders = await response.json();
+            if (!this.folders.some(f => String(f.id) === this.folderId)) this.folderId = '';
+            select.innerHTML = '<option value="">All bookmarks</option>' + this.folders.map(f =>
+                `<option value="${f.id}">${escapeHtml(f.name)} (${f.count})</option>`
+            ).join('');
+            select.value = this.folderId;
+            document.getElementById('bookmark-folder-rename')?.classList.toggle('hidden', !this.folderId);
+            document.getElementById('bookmark-folder-delete')?.classList.toggle('hidden', !this.folderId);
+        } catch (error) {
+            console.error('Error loading bookmark folders:', error);
+        }
+    },
+
+    // Saves a post or comment into the folder being shown, or outside any
+    // folder when all bookmarks are shown
+    async save(targetType, targetId) {
+        try {
+            const response = await fetch('/api/bookmarks', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ targetType, targetId, folderId: parseInt(this.folderId) || 0 }),
+                credentials: 'include'
+            });
+            if (!response.ok) {
+                showNotification('Failed to save bookmark', 'error');
+                return false;
+            }
+            showNotification('Saved to bookmarks');
+            Posts.showBookmarked(targetType, targetId, true);
+            this.load();
+            return true;
+        } catch (error) {
+            console.error('Error saving bookmark:', error);
+            return false;
+        }
+    },
+
+    async remove(targetType, targetId) {
+        try {
+            const response = await fetch(`/api/bookmarks?target_type=${targetType}&target_id=${targetId}`, {
+                method: 'DELETE',
+                credentials: 'include'
+            });
+            if (!response.ok) {
+                showNotification('Failed to remove bookmark', 'error');
+                return false;
+            }
+            Posts.showBookmarked(targetType, targetId, false);
+            this.load();
+            return true;
+        } catch (error) {
+            console.error('Error removing bookmark:', error);
+            return false;
+        }
+    },
+
+    async toggle(targetType, targetId, bookmarked) {
+        return bookmarked ? this.remove(targetType, targetId) : this.save(targetType, targetId);
+    },
+
+    async createFolder() {
+        const name = prompt('Folder name');
+        if (!name) return;
+        const folder = await this.sendFolder('/api/bookmarks/folders', 'POST', name);
+        if (folder) {
+            this.folderId = String(folder.id);
+            this.load();
+        }
+    },
+
+    async renameFolder() {
+        const folder = this.folders.find(f => String(f.id) === this.folderId);
+        if (!folder) return;
+        const name = prompt('Rename folder', folder.name);
+        if (!name || name === folder.name) return;
+        if (await this.sendFolder(`/api/bookmarks/folders?id=${folder.id}`, 'PUT', name)) this.loadFolders();
+    },
+
+    async deleteFolder() {
+        const folder = this.folders.find(f => String(f.id) === this.folderId);
+        if (!folder || !confirm(`Delete the folder "${folder.name}"? Its bookmarks are kept.`)) return;
+        try {
+            const response = await fetch(`/api/bookmarks/folders?id=${folder.id}`, { method: 'DELETE', credentials: 'include' });
+            if (!response.ok) {
+                showNotification('Failed to delete folder', 'error');
+                return;
+            }
+            this.folderId = '';
+            this.load();
+        } catch (error) {
+            console.error('Error deleting bookmark folder:', error);
+        }
+    },
+
+    async sendFolder(url, method, name) {
+        try {
+            const response = await fetch(url, {
+                method,
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ name }),
+                credentials: 'include'
+            });
+            if (!response.ok) {
+                showNotification(await response.text(), 'error');
+                return null;
+            }
+            return await response.json();
+        } catch (error) {
+            console.error('Error saving bookmark folder:', error);
+            return null;
+        }
+    }
+};
+
+document.addEventListener('DOMContentLoaded', () => Bookmarks.init());
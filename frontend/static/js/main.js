@@ -21,7 +21,13 @@ const DOM = {
 const ForumApp = {
     currentUser: null,
     currentCategory: 'all',
+    currentTags: [],
+    categories: [],
+    tagMatch: 'any',
+    currentSort: 'new',
+    currentRange: 'week',
     currentThreadId: null,
+    threadTypers: {},
     conversations: [],
     onlineUsers: [],
     currentChatUser: null
@@ -36,6 +42,14 @@ function escapeHtml(unsafe) {
         .replace(/'/g, "&#039;");
 }
 
+// avatarImage renders a user's avatar at the given pixel size, or an empty
+// string when the user has no avatar URL
+function avatarImage(url, size, classes) {
+    if (!url) return '';
+    const src = `${url}&size=${size}`;
+    return `<img src="${escapeHtml(src)}" alt="" class="${classes} rounded-full object-cover">`;
+}
+
 function formatDate(timestamp) {
     const date = new Date(timestamp);
     return date.toLocaleString('en-US', { 
@@ -84,6 +98,11 @@ function showLoggedInUI() {
     DOM.forumContent.classList.remove('hidden');
     DOM.authButtons.classList.add('hidden');
     DOM.userMenuContainer.classList.remove('hidden');
+    document.getElementById('notifications-container')?.classList.remove('hidden');
+    Notifications.load();
+    Bookmarks.load();
+    Reports.load();
+    Tags.loadDirectory();
     // Safely update username displays if they exist
     const usernameDisplay = document.getElementById('username-display');
     if (usernameDisplay) usernameDisplay.textContent = ForumApp.currentUser?.nickname || 'User';
@@ -93,6 +112,9 @@ function showLoggedInUI() {
     if (avatar) {
         avatar.className = `w-8 h-8 rounded-full bg-${ForumApp.currentUser?.avatarColor || 'blue-500'} flex items-center justify-center text-white`;
         avatar.textContent = ForumApp.currentUser?.nickname?.substring(0, 2).toUpperCase() || 'U';
+        if (ForumApp.currentUser?.avatarUrl) {
+            avatar.innerHTML = avatarImage(ForumApp.currentUser.avatarUrl, 64, 'w-8 h-8');
+        }
     }
 }
 
@@ -104,6 +126,7 @@ function showLoggedOutUI() {
     DOM.forumContent.classList.add('hidden');
     DOM.authButtons.classList.remove('hidden');
     DOM.userMenuContainer.classList.add('hidden');
+    document.getElementById('notifications-container')?.classList.add('hidden');
     document.getElementById('online-count').classList.add('hidden');
     // Safely update username displays if they exist
     const usernameDisplay = document.getElementById('username-display');
@@ -150,9 +173,9 @@ function updateOnlineUsersList() {
         const li = document.createElement('li');
         li.className = 'flex items-center space-x-2 p-2 hover:bg-gray-50 rounded-md cursor-pointer';
         li.innerHTML = `
-            <div class="w-6 h-6 rounded-full bg-${user.avatarColor || 'blue-500'} flex items-center justify-center text-xs text-white">
-                ${user.nickname.substring(0, 2).toUpperCase()}
-            </div>
+            ${avatarImage(user.avatarUrl, 32, 'w-6 h-6') || `<div class="w-6 h-6 rounded-full bg-${user.avatarColor || 'blue-500'} flex items-center justify-center text-xs text-white">
+                ${escapeHtml(user.nickname.substring(0, 2).toUpperCase())}
+            </div>`}
             <span class="text-sm text-gray-700">${escapeHtml(user.nickname)}</span>
             <div class="w-2 h-2 bg-green-500 rounded-full ml-auto" title="Online"></div>
         `;
@@ -214,6 +237,7 @@ function startPrivateChat(conversation) {
 
     const mobileChatWith = document.getElementById('mobile-chat-with');
     if (mobileChatWith) mobileChatWith.textContent = conversation.with;
+    document.getElementById('chat-ban-btn')?.classList.toggle('hidden', !Reports.isModerator());
     const avatar = document.getElementById('chat-avatar');
     const mobileAvatar = document.getElementById('mobile-chat-avatar');
     if (avatar && mobileAvatar) {
@@ -0,0 +1,106 @@
+window.Mentions = {
+    inputs: ['thread-content', 'reply-content', 'message-content', 'chat-input', 'mobile-chat-input'],
+    list: null,
+    activeInput: null,
+    timer: null,
+
+    init() {
+        this.list = document.createElement('ul');
+        this.list.className = 'absolute z-50 bg-white border border-gray-200 rounded-md shadow-lg text-sm hidden';
+        document.body.appendChild(this.list);
+
+        this.inputs.forEach(id => {
+            const input = document.getElementById(id);
+            if (!input) return;
+            input.addEventListener('input', () => this.onInput(input));
+            input.addEventListener('blur', () => setTimeout(() => this.hide(), 150));
+        });
+
+        // Mention links in rendered posts and comments open a chat
+        document.addEventListener('click', (e) => {
+            const link = e.target.closest('a.mention');
+            if (!link) return;
+            e.preventDefault();
+            const userId = parseInt(link.dataset.userId);
+            if (ForumApp.currentUser && userId !== ForumApp.currentUser.id) {
+                Messages.startChat(userId, link.textContent.replace(/^@/, ''));
+            }
+        });
+    },
+
+    // The @word being typed just before the caret, if any
+    currentQuery(input) {
+        const before = input.value.slice(0, input.selectionStart);
+        const match = before.match(/(^|[^\p{L}\p{N}_.-])@([\p{L}\p{N}_.-]+)$/u);
+        return match ? match[2] : null;
+    },
+
+    onInput(input) {
+        clearTimeout(this.timer);
+        const query = this.currentQuery(input);
+        if (!query) {
+            this.hide();
+            return;
+        }
+        this.timer = setTimeout(() => this.suggest(input, query), 200);
+    },
+
+    async suggest(input, query) {
+        try {
+            const response = await fetch(`/api/users/search?prefix=${encodeURIComponent(query)}&limit=5`, { credentials: 'include' });
+            if (!response.ok) return;
+            const users = await response.json();
+            if (users.length === 0 || this.currentQuery(input) !== query) {
+                this.hide();
+                return;
+            }
+            this.show(input, users);
+        } catch (error) {
+            console.error('Error searching users:', error);
+        }
+    },
+
+    show(input, users) {
+        this.activeInput = input;
+        this.list.innerHTML = '';
+        users.forEach(user => {
+            const li = document.createElement('li');
+            li.className = 'flex items-center space-x-2 px-3 py-1 hover:bg-gray-100 cursor-pointer';
+            li.innerHTML = `${avatarImage(user.avatarUrl, 32, 'w-5 h-5')}<span>${escapeHtml(user.nickname)}</span>`;
+            li.addEventListener('mousedown', (e) => {
+                e.preventDefault();
+                this.insert(user.nickname);
+            });
+            this.list.appendChild(li);
+        });
+
+        const rect = input.getBoundingClientRect();
+        this.list.style.left = `${rect.left + window.scrollX}px`;
+        this.list.style.top = `${rect.bottom + window.scrollY}px`;
+        this.list.classList.remove('hidden');
+    },
+
+    hide() {
+        this.list?.classList.add('hidden');
+        this.activeInput = null;
+    },
+
+    // Replaces the @word before the caret with the chosen nickname
+    insert(nickname) {
+        const input = this.activeInput;
+        if (!input) return;
+        const caret = input.selectionStart;
+        const before = input.value.slice(0, caret).replace(/@[\p{L}\p{N}_.-]*$/u, `@${nickname} `);
+        input.value = before + input.value.slice(caret);
+        input.selectionStart = input.selectionEnd = before.length;
+        input.focus();
+        this.hide();
+    },
+
+    handleMention(data) {
+        const where = data.targetType === 'message' ? 'a message' : data.targetType === 'post' ? 'a post' : 'a comment';
+        showNotification(`${data.authorName} mentioned you in ${where}: ${data.excerpt}`);
+    }
+};
+
+document.addEventListener('DOMContentLoaded', () => Mentions.init());
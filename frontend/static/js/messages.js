@@ -74,10 +74,25 @@ window.Messages = {
             const isSent = msg.senderId === ForumApp.currentUser.id;
             const div = document.createElement('div');
             div.className = `flex ${isSent ? 'justify-end' : 'justify-start'} mb-2`;
+            const content = msg.isDeleted
+                ? '<p class="text-sm italic opacity-75">This message was deleted</p>'
+                : `<p class="text-sm">${escapeHtml(msg.content)}</p>${Attachments.render(msg.attachments)}`;
+            const actions = isSent && msg.id && !msg.isDeleted
+                ? `<span class="ml-2">
+                        <button class="underline" data-action="edit" data-message-id="${msg.id}">Edit</button>
+                        <button class="underline ml-1" data-action="delete" data-message-id="${msg.id}">Delete</button>
+                   </span>`
+                : (msg.id && !msg.isDeleted
+                    ? `<span class="ml-2">
+                        <button class="underline" data-action="delete" data-message-id="${msg.id}">Delete</button>
+                        <button class="underline ml-1" data-action="report" data-message-id="${msg.id}">Report</button>
+                   </span>`
+                    : '');
             div.innerHTML = `
                 <div class="${isSent ? 'bg-blue-500 text-white' : 'bg-gray-200 text-gray-800'} p-3 rounded-lg max-w-xs">
-                    <p class="text-sm">${escapeHtml(msg.content)}</p>
-                    <p class="text-xs mt-1 opacity-75">${formatTime(msg.timestamp)}</p>
+                    ${content}
+                    <p class="text-xs mt-1 opacity-75">${formatTime(msg.timestamp || msg.created_at)}${msg.isEdited && !msg.isDeleted ? ' (edited)' : ''}${actions}</p>
+                    ${msg.id && !msg.isDeleted ? Reactions.renderBar('message', msg.id, msg.reactions) : ''}
                 </div>
             `;
             chatMessages.appendChild(div);
@@ -108,7 +123,14 @@ window.Messages = {
     handleNewMessage(data) {
         const conversation = ForumApp.conversations.find(c => c.userId === data.senderId || c.userId === data.recipientId);
         if (conversation) {
-            conversation.messages.push(data);
+            // Our own echo confirms the message shown optimistically; give it its id
+            const pending = data.senderId === ForumApp.currentUser.id
+                && conversation.messages.find(m => !m.id && m.content === data.content);
+            if (pending) {
+                pending.id = data.id;
+            } else {
+                conversation.messages.push(data);
+            }
             conversation.lastMessage = data.content;
             conversation.time = formatDate(data.timestamp);
             conversation.unread = data.senderId !== ForumApp.currentUser.id;
@@ -124,6 +146,69 @@ window.Messages = {
         }
     },
 
+    handleMessageRequest(data) {
+        showNotification(`New message request from ${data.sender}`);
+    },
+
+    findMessage(messageId) {
+        for (const conversation of ForumApp.conversations) {
+            const message = conversation.messages.find(m => m.id === messageId);
+            if (message) return { conversation, message };
+        }
+        return null;
+    },
+
+    refreshOpenChat(conversation) {
+        if (ForumApp.currentChatUser?.userId === conversation.userId) {
+            this.displayMessages(conversation.messages);
+        }
+    },
+
+    handleMessageUpdated(data) {
+        const found = this.findMessage(data.id);
+        if (!found) return;
+        found.message.content = data.content;
+        found.message.isEdited = true;
+        found.message.editedAt = data.editedAt;
+        this.refreshOpenChat(found.conversation);
+    },
+
+    handleMessageDeleted(data) {
+        const found = this.findMessage(data.id);
+        if (!found) return;
+        if (data.scope === 'everyone') {
+            found.message.content = '';
+            found.message.isDeleted = true;
+        } else {
+            found.conversation.messages = found.conversation.messages.filter(m => m.id !== data.id);
+        }
+        this.refreshOpenChat(found.conversation);
+    },
+
+    handleMessageAction(e) {
+        const button = e.target.closest('button[data-action]');
+        if (!button) return;
+        const messageId = parseInt(button.dataset.messageId);
+        const found = this.findMessage(messageId);
+        if (!found) return;
+
+        if (button.dataset.action === 'edit') {
+            const content = prompt('Edit message', found.message.content);
+            if (content && content.trim() && content.trim() !== found.message.content) {
+                WebSocketClient.editMessage(messageId, content.trim());
+            }
+        } else if (button.dataset.action === 'delete') {
+            const isSent = found.message.senderId === ForumApp.currentUser.id;
+            if (isSent && confirm('Delete for everyone? Cancel deletes it only for you.')) {
+                WebSocketClient.deleteMessage(messageId, 'everyone');
+            } else if (confirm('Delete this message for you?')) {
+                WebSocketClient.deleteMessage(messageId, 'me');
+            }
+        } else if (button.dataset.action === 'report') {
+            Reports.report('message', messageId);
+        }
+    },
+
     handleTyping(data) {
         if (ForumApp.currentChatUser?.userId === data.userId) {
             const typingIndicator = document.getElementById('typing-indicator');
@@ -175,6 +260,9 @@ window.Messages = {
             this.startChat(parseInt(recipientId), document.getElementById('message-recipient').selectedOptions[0].text);
         });
 
+        document.getElementById('chat-messages')?.addEventListener('click', (e) => this.handleMessageAction(e));
+        document.getElementById('mobile-chat-messages')?.addEventListener('click', (e) => this.handleMessageAction(e));
+
         document.getElementById('mobile-messages-btn')?.addEventListener('click', () => {
             DOM.mobileMessagesPanel.classList.remove('hidden');
             DOM.forumContent.classList.add('hidden');
@@ -186,6 +274,26 @@ window.Messages = {
             DOM.forumContent.classList.remove('hidden');
         });
 
+        document.getElementById('chat-block-btn')?.addEventListener('click', () => {
+            if (ForumApp.currentChatUser) this.restrictUser('blocks', ForumApp.currentChatUser.userId, 'Block');
+        });
+
+        document.getElementById('chat-report-btn')?.addEventListener('click', () => {
+            if (ForumApp.currentChatUser) Reports.report('user', ForumApp.currentChatUser.userId);
+        });
+
+        document.getElementById('chat-ban-btn')?.addEventListener('click', () => {
+            if (ForumApp.currentChatUser) Reports.banUser(ForumApp.currentChatUser.userId, ForumApp.currentChatUser.with);
+        });
+
+        document.getElementById('chat-follow-btn')?.addEventListener('click', () => {
+            if (ForumApp.currentChatUser) this.followUser(ForumApp.currentChatUser.userId);
+        });
+
+        document.getElementById('chat-mute-btn')?.addEventListener('click', () => {
+            if (ForumApp.currentChatUser) this.restrictUser('mutes', ForumApp.currentChatUser.userId, 'Mute');
+        });
+
         document.getElementById('close-chat')?.addEventListener('click', () => {
             DOM.chatWindow.classList.add('hidden');
             ForumApp.currentChatUser = null;
@@ -252,6 +360,49 @@ window.Messages = {
         });
     },
 
+    async restrictUser(list, userId, label) {
+        if (!confirm(`${label} this user?`)) return;
+        try {
+            const response = await fetch(`/api/${list}`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ userId }),
+                credentials: 'include'
+            });
+            if (response.ok) {
+                showNotification(`User ${list === 'blocks' ? 'blocked' : 'muted'}`);
+                if (list === 'blocks') {
+                    DOM.chatWindow.classList.add('hidden');
+                    ForumApp.currentChatUser = null;
+                }
+            } else {
+                showNotification(await response.text() || `Failed to ${label.toLowerCase()} user`, 'error');
+            }
+        } catch (error) {
+            console.error(`Error updating ${list}:`, error);
+            showNotification(`Error updating ${list}`, 'error');
+        }
+    },
+
+    async followUser(userId) {
+        try {
+            const response = await fetch('/api/follows', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ userId }),
+                credentials: 'include'
+            });
+            if (response.ok) {
+                showNotification('User followed');
+            } else {
+                showNotification(await response.text() || 'Failed to follow user', 'error');
+            }
+        } catch (error) {
+            console.error('Error following user:', error);
+            showNotification('Error following user', 'error');
+        }
+    },
+
     async populateRecipientList() {
         try {
             const response = await fetch('/api/users', { credentials: 'include' });
@@ -0,0 +1,220 @@
+window.Notifications = {
+    labels: {
+        post_reply: 'Replies to my posts',
+        comment_reply: 'Replies to my comments',
+        mention: 'Mentions',
+        reaction: 'Reactions',
+        direct_message: 'Direct messages',
+        watched_post: 'Comments on watched posts'
+    },
+    items: [],
+    offset: 0,
+
+    init() {
+        const button = document.getElementById('notifications-button');
+        const dropdown = document.getElementById('notifications-dropdown');
+        if (!button || !dropdown) return;
+
+        button.addEventListener('click', (e) => {
+            e.stopPropagation();
+            dropdown.classList.toggle('hidden');
+            if (!dropdown.classList.contains('hidden')) this.loadPreferences();
+        });
+        document.addEventListener('click', (e) => {
+            if (!dropdown.contains(e.target)) dropdown.classList.add('hidden');
+        });
+        document.getElementById('notifications-read-all')?.addEventListener('click', () => this.markAllRead());
+        document.getElementById('notifications-more')?.addEventListener('click', () => this.load(this.offset));
+        document.getElementById('notifications-list')?.addEventListener('click', (e) => {
+            const li = e.target.closest('li[data-id]');
+            if (li) this.open(this.items.find(n => n.id === parseInt(li.dataset.id)));
+        });
+        document.getElementById('notification-preferences')?.addEventListener('change', (e) => {
+            if (e.target.dataset.type) this.savePreference(e.target.dataset.type, e.target.checked);
+        });
+        document.getElementById('digest-frequency')?.addEventListener('change', (e) => this.saveDigestFrequency(e.target.value));
+        document.getElementById('watch-settings')?.addEventListener('change', () => this.saveWatchSettings());
+    },
+
+    async load(offset = 0) {
+        try {
+            const response = await fetch(`/api/notifications?offset=${offset}`, { credentials: 'include' });
+            if (!response.ok) return;
+            const data = await response.json();
+            this.items = offset === 0 ? data.notifications : this.items.concat(data.notifications);
+            this.offset = this.items.length;
+            document.getElementById('notifications-more')?.classList.toggle('hidden', data.notifications.length < 20);
+            this.render();
+            this.setUnreadCount(data.unreadCount);
+        } catch (error) {
+            console.error('Error loading notifications:', error);
+        }
+    },
+
+    describe(n) {
+        const name = n.actorName;
+        switch (n.type) {
+            case 'post_reply': return `${name} replied to your post`;
+            case 'comment_reply': return `${name} replied to your comment`;
+            case 'mention': return `${name} mentioned you`;
+            case 'reaction': return `${name} reacted ${n.detail} to your ${n.targetType}`;
+            case 'direct_message': return `${name} sent you a message`;
+            case 'watched_post': return `${name} commented on a post you watch`;
+            default: return name;
+        }
+    },
+
+    render() {
+        const list = document.getElementById('notifications-list');
+        if (!list) return;
+        if (this.items.length === 0) {
+            list.innerHTML = '<li class="px-4 py-3 text-gray-500">No notifications yet</li>';
+            return;
+        }
+        list.innerHTML = this.items.map(n => `
+            <li data-id="${n.id}" class="flex items-start space-x-2 px-4 py-2 cursor-pointer hover:bg-gray-50 ${n.read ? '' : 'bg-blue-50'}">
+                ${avatarImage(n.actorAvatar, 32, 'w-6 h-6 flex-shrink-0')}
+                <div class="min-w-0">
+                    <p>${escapeHtml(this.describe(n))}</p>
+                    <p class="text-gray-500 truncate">${escapeHtml(n.excerpt)}</p>
+                    <p class="text-xs text-gray-400">${formatDate(n.createdAt)}</p>
+                </div>
+            </li>
+        `).join('');
+    },
+
+    setUnreadCount(count) {
+        const badge = document.getElementById('notifications-badge');
+        if (!badge) return;
+        badge.textContent = count > 99 ? '99+' : count;
+        badge.classList.toggle('hidden', !count);
+    },
+
+    handleNotification(data) {
+        const n = data.notification;
+        this.items = [n, ...this.items.filter(item => item.id !== n.id)];
+        this.render();
+        this.setUnreadCount(data.unreadCount);
+        // Mentions and messages already show up on their own
+        if (n.type !== 'mention' && n.type !== 'direct_message') {
+            showNotification(this.describe(n));
+        }
+    },
+
+    async markRead(ids) {
+        try {
+            const response = await fetch('/api/notifications/read', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ notificationIds: ids }),
+                credentials: 'include'
+            });
+            if (response.ok) this.setUnreadCount((await response.json()).unreadCount);
+        } catch (error) {
+            console.error('Error marking notifications read:', error);
+        }
+    },
+
+    async markAllRead() {
+        try {
+            const response = await fetch('/api/notifications/read-all', { method: 'POST', credentials: 'include' });
+            if (!response.ok) return;
+            this.items.forEach(n => n.read = true);
+            this.render();
+            this.setUnreadCount((await response.json()).unreadCount);
+        } catch (error) {
+            console.error('Error marking notifications read:', error);
+        }
+    },
+
+    // Marks a notification read and opens the post or chat it is about
+    open(n) {
+        if (!n) return;
+        if (!n.read) {
+            n.read = true;
+            this.render();
+            this.markRead([n.id]);
+        }
+        document.getElementById('notifications-dropdown')?.classList.add('hidden');
+        if (n.postId) {
+            Posts.loadPostDetails(n.postId);
+        } else if (n.type === 'direct_message') {
+            Messages.startChat(n.actorId, n.actorName);
+        }
+    },
+
+    async loadPreferences() {
+        const container = document.getElementById('notification-preferences');
+        if (!container) return;
+        try {
+            const response = await fetch('/api/notifications/preferences', { credentials: 'include' });
+            if (!response.ok) return;
+            const prefs = await response.json();
+            container.innerHTML = Object.entries(this.labels).map(([type, label]) => `
+                <label class="flex items-center space-x-2">
+                    <input type="checkbox" data-type="${type}" ${prefs[type] ? 'checked' : ''}>
+                    <span>${label}</span>
+                </label>
+            `).join('');
+
+            const digest = await fetch('/api/digest/settings', { credentials: 'include' });
+            if (digest.ok) document.getElementById('digest-frequency').value = (await digest.json()).frequency;
+
+            const watch = await fetch('/api/watches/settings', { credentials: 'include' });
+            if (watch.ok) {
+                const settings = await watch.json();
+                document.getElementById('auto-watch-posts').checked = settings.autoWatchPosts;
+                document.getElementById('auto-watch-comments').checked = settings.autoWatchComments;
+            }
+        } catch (error) {
+            console.error('Error loading notification settings:', error);
+        }
+    },
+
+    async savePreference(type, enabled) {
+        try {
+            const response = await fetch('/api/notifications/preferences', {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ [type]: enabled }),
+                credentials: 'include'
+            });
+            if (!response.ok) showNotification('Failed to save notification settings', 'error');
+        } catch (error) {
+            console.error('Error saving notification settings:', error);
+        }
+    },
+
+    async saveWatchSettings() {
+        try {
+            const response = await fetch('/api/watches/settings', {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({
+                    autoWatchPosts: document.getElementById('auto-watch-posts').checked,
+                    autoWatchComments: document.getElementById('auto-watch-comments').checked
+                }),
+                credentials: 'include'
+            });
+            if (!response.ok) showNotification('Failed to save watch settings', 'error');
+        } catch (error) {
+            console.error('Error saving watch settings:', error);
+        }
+    },
+
+    async saveDigestFrequency(frequency) {
+        try {
+            const response = await fetch('/api/digest/settings', {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ frequency }),
+                credentials: 'include'
+            });
+            if (!response.ok) showNotification('Failed to save digest settings', 'error');
+        } catch (error) {
+            console.error('Error saving digest settings:', error);
+        }
+    }
+};
+
+document.addEventListener('DOMContentLoaded', () => Notifications.init());
@@ -1,16 +1,30 @@
 window.Posts = {
-    async loadPosts() {
+    async loadPosts(offset = 0) {
         if (!ForumApp.currentUser) {
             DOM.loginModal.classList.remove('hidden');
             return;
         }
         try {
-            const url = ForumApp.currentCategory === 'all' ? '/api/posts' : `/api/posts?category_id=${ForumApp.currentCategory}`;
+            const params = new URLSearchParams({ sort: ForumApp.currentSort, offset });
+            if (ForumApp.currentSort === 'top') params.set('range', ForumApp.currentRange);
+            if (ForumApp.currentCategory !== 'all') params.set('category_id', ForumApp.currentCategory);
+            if (ForumApp.currentTags.length > 0) {
+                params.set('tag', ForumApp.currentTags.join(','));
+                params.set('tag_match', ForumApp.tagMatch);
+            }
+            const url = `/api/posts?${params}`;
             const response = await fetch(url, { credentials: 'include' });
             if (response.ok) {
                 const posts = await response.json();
-                showNotification('Posts loaded successfully!');
-                this.displayPosts(posts);
+                if (offset === 0) {
+                    showNotification('Posts loaded successfully!');
+                    this.displayPosts(posts);
+                } else {
+                    posts.forEach(post => DOM.threadsContainer?.appendChild(this.createPostElement(post)));
+                }
+                this.postsOffset = offset + posts.length;
+                this.morePosts = posts.length === 20;
+                document.getElementById('posts-more')?.classList.toggle('hidden', !this.morePosts);
             } else {
                 showNotification('Failed to load posts', 'error');
             }
@@ -47,18 +61,22 @@ window.Posts = {
         div.className = 'bg-white p-4 rounded-md shadow-sm border border-gray-200 hover:shadow-md transition';
         div.innerHTML = `
             <div class="flex items-center space-x-2 mb-2">
-                <div class="w-6 h-6 rounded-full bg-${post.avatar_color || 'blue-500'} flex items-center justify-center text-xs text-white">
+                ${avatarImage(post.authorAvatar, 32, 'w-6 h-6') || `<div class="w-6 h-6 rounded-full bg-${post.avatar_color || 'blue-500'} flex items-center justify-center text-xs text-white">
                     ${post.nickname ? post.nickname.substring(0, 2).toUpperCase() : 'U'}
-                </div>
+                </div>`}
                 <span class="text-sm font-medium text-gray-700">${escapeHtml(post.nickname)}</span>
                 <span class="text-sm text-gray-500">• ${formatDate(post.created_at)}</span>
             </div>
-            <h3 class="text-lg font-semibold text-gray-800 mb-2">${escapeHtml(post.title)}</h3>
-            <p class="text-gray-600 mb-3 line-clamp-3">${escapeHtml(post.content)}</p>
+            <h3 class="text-lg font-semibold text-gray-800 mb-2">${this.renderStateBadges(post)}${escapeHtml(post.title)}</h3>
+            ${Tags.renderChips(post.tags)}
+            <div class="markdown text-gray-600 mb-3 line-clamp-3">${post.contentHtml || escapeHtml(post.content)}</div>
+            ${Attachments.render(post.attachments)}
             <div class="flex justify-between items-center text-sm text-gray-500">
                 <span>${post.comment_count || 0} comments</span>
                 <button data-post-id="${post.id}" class="view-post-btn text-blue-600 hover:text-blue-800">View post</button>
             </div>
+            ${this.renderVotes('post', post.id, post.score, post.userVote)}
+            ${Reactions.renderBar('post', post.id, post.reactions)}
         `;
         div.querySelector('.view-post-btn').addEventListener('click', () => {
             if (!ForumApp.currentUser) {
@@ -72,13 +90,17 @@ window.Posts = {
 
     async loadPostDetails(postId) {
         try {
+            this.leaveThreadRoom();
+            this.setReplyTo(null);
             ForumApp.currentThreadId = postId;
+            WebSocketClient.joinRoom(this.roomName(postId));
             const response = await fetch(`/api/posts?post_id=${postId}`, { credentials: 'include' });
             if (response.ok) {
                 const post = await response.json();
                 this.displayPostDetails(post);
                 await this.loadComments(postId);
                 DOM.threadsContainer.classList.add('hidden');
+                document.getElementById('posts-more')?.classList.add('hidden');
                 DOM.threadDetail.classList.remove('hidden');
             } else {
                 showNotification('Failed to load post details', 'error');
@@ -97,12 +119,157 @@ window.Posts = {
         const content = document.getElementById('thread-detail-content');
 
         if (title && avatar && author && time && content) {
-            title.textContent = post.title;
+            title.dataset.title = post.title;
+            title.innerHTML = this.renderStateBadges(post) + escapeHtml(post.title);
+            document.getElementById('thread-detail-tags').innerHTML = Tags.renderChips(post.tags);
             avatar.className = `w-6 h-6 rounded-full bg-${post.avatar_color || 'blue-500'} flex items-center justify-center text-xs text-white`;
             avatar.textContent = post.nickname ? post.nickname.substring(0, 2).toUpperCase() : 'U';
+            if (post.authorAvatar) {
+                avatar.innerHTML = avatarImage(post.authorAvatar, 32, 'w-6 h-6');
+            }
             author.textContent = post.nickname;
             time.textContent = formatDate(post.created_at);
-            content.textContent = post.content;
+            this.setWatching(post.watching);
+            DOM.threadDetail.dataset.categoryId = post.categoryId;
+            this.showPostState(post);
+            this.showBookmarked('post', post.id, post.bookmarked);
+            // contentHtml is sanitized by the server
+            content.classList.add('markdown');
+            if (post.contentHtml) {
+                content.innerHTML = post.contentHtml;
+            } else {
+                content.textContent = post.content;
+            }
+        }
+    },
+
+    // Hides the reply form in categories where the user may not comment and
+    // on locked or archived posts
+    showReplyForm(categoryId) {
+        const category = ForumApp.categories.find(c => c.id === categoryId);
+        const { locked, archived } = DOM.threadDetail.dataset;
+        let reason = '';
+        if (archived === 'true') {
+            reason = 'This post is archived and read-only.';
+        } else if (locked === 'true') {
+            reason = 'This post is locked: no new replies can be added.';
+        } else if (category && category.canComment === false) {
+            reason = 'Only some members can reply in this category.';
+        }
+        document.getElementById('reply-form')?.classList.toggle('hidden', reason !== '');
+        const disabled = document.getElementById('reply-disabled');
+        if (disabled) {
+            disabled.textContent = reason;
+            disabled.classList.toggle('hidden', reason === '');
+        }
+    },
+
+    renderStateBadges(post) {
+        const badges = [];
+        if (post.pinned) badges.push(['bg-yellow-100 text-yellow-800', post.pinned === 'global' ? 'Pinned globally' : 'Pinned']);
+        if (post.locked) badges.push(['bg-gray-200 text-gray-700', 'Locked']);
+        if (post.archived) badges.push(['bg-red-100 text-red-700', 'Archived']);
+        return badges.map(([classes, label]) =>
+            `<span class="text-xs font-medium px-2 py-0.5 rounded mr-2 align-middle ${classes}">${label}</span>`
+        ).join('');
+    },
+
+    // Shows the moderation state of the open post, and the buttons to change
+    // it to moderators
+    showPostState(state) {
+        DOM.threadDetail.dataset.pinned = state.pinned || '';
+        DOM.threadDetail.dataset.locked = state.locked ? 'true' : 'false';
+        DOM.threadDetail.dataset.archived = state.archived ? 'true' : 'false';
+        this.showReplyForm(state.categoryId);
+
+        const actions = document.getElementById('thread-mod-actions');
+        if (!actions) return;
+        const role = ForumApp.currentUser?.role;
+        actions.classList.toggle('hidden', role !== 'moderator' && role !== 'admin');
+        const buttons = {
+            'thread-pin-btn': state.pinned ? 'Unpin' : 'Pin',
+            'thread-pin-global-btn': state.pinned === 'global' ? 'Pin to category' : 'Pin globally',
+            'thread-lock-btn': state.locked ? 'Unlock' : 'Lock',
+            'thread-archive-btn': state.archived ? 'Restore' : 'Archive'
+        };
+        Object.entries(buttons).forEach(([id, label]) => {
+            const button = document.getElementById(id);
+            if (button) button.textContent = label;
+        });
+    },
+
+    // Applies moderator changes to the open post
+    async updatePostState(changes) {
+        try {
+            const response = await fetch(`/api/posts/state?post_id=${ForumApp.currentThreadId}`, {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(changes),
+                credentials: 'include'
+            });
+            if (response.ok) {
+                this.handlePostState(await response.json());
+                showNotification('Post updated');
+            } else {
+                showNotification(await response.text() || 'Failed to update post', 'error');
+            }
+        } catch (error) {
+            console.error('Error updating post:', error);
+        }
+    },
+
+    // Handles a post being pinned, locked or archived: the open post shows
+    // its new state, and the listing is reloaded to reorder it
+    handlePostState(state) {
+        if (state.postId === ForumApp.currentThreadId) {
+            const title = document.getElementById('thread-detail-title');
+            if (title) {
+                title.innerHTML = this.renderStateBadges(state) + escapeHtml(title.dataset.title || '');
+            }
+            this.showPostState(state);
+        }
+        if (ForumApp.currentUser) {
+            this.loadPosts();
+        }
+    },
+
+    setWatching(watching) {
+        const button = document.getElementById('thread-watch-btn');
+        if (!button) return;
+        button.dataset.watching = watching ? 'true' : 'false';
+        button.textContent = watching ? 'Unwatch' : 'Watch';
+    },
+
+    // Updates the bookmark button of the open post or one of its comments
+    showBookmarked(targetType, targetId, bookmarked) {
+        const button = targetType === 'post'
+            ? (targetId === ForumApp.currentThreadId ? document.getElementById('thread-bookmark-btn') : null)
+            : document.querySelector(`.comment-bookmark-btn[data-id="${targetId}"]`);
+        if (!button) return;
+        button.dataset.bookmarked = bookmarked ? 'true' : 'false';
+        button.textContent = bookmarked ? 'Saved' : 'Save';
+    },
+
+    async toggleWatch() {
+        const postId = ForumApp.currentThreadId;
+        const watching = document.getElementById('thread-watch-btn')?.dataset.watching === 'true';
+        try {
+            const response = watching
+                ? await fetch(`/api/watches?post_id=${postId}`, { method: 'DELETE', credentials: 'include' })
+                : await fetch('/api/watches', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ postId }),
+                    credentials: 'include'
+                });
+            if (response.ok) {
+                this.setWatching(!watching);
+                showNotification(watching ? 'You will no longer be notified of new comments' : 'You will be notified of new comments');
+            } else {
+                showNotification('Failed to update watch', 'error');
+            }
+        } catch (error) {
+            console.error('Error updating watch:', error);
         }
     },
 
@@ -126,41 +293,71 @@ window.Posts = {
         if (!repliesContainer) return;
 
         repliesContainer.innerHTML = '';
+        const names = Object.fromEntries(comments.map(c => [c.id, c.nickname]));
 
         comments.forEach(comment => {
             const div = document.createElement('div');
             div.className = 'p-3 bg-gray-50 rounded-md';
+            const replyTo = comment.parentId && names[comment.parentId]
+                ? `<p class="text-xs text-gray-500 mb-1">↳ in reply to ${escapeHtml(names[comment.parentId])}</p>`
+                : '';
             div.innerHTML = `
+                ${replyTo}
                 <div class="flex items-center space-x-2 mb-2">
-                    <div class="w-6 h-6 rounded-full bg-${comment.avatar_color || 'blue-500'} flex items-center justify-center text-xs text-white">
+                    ${avatarImage(comment.authorAvatar, 32, 'w-6 h-6') || `<div class="w-6 h-6 rounded-full bg-${comment.avatar_color || 'blue-500'} flex items-center justify-center text-xs text-white">
                         ${comment.nickname ? comment.nickname.substring(0, 2).toUpperCase() : 'U'}
-                    </div>
+                    </div>`}
                     <span class="text-sm font-medium text-gray-700">${escapeHtml(comment.nickname)}</span>
                     <span class="text-sm text-gray-500">• ${formatDate(comment.created_at)}</span>
                 </div>
-                <p class="text-gray-600">${escapeHtml(comment.content)}</p>
+                <div class="markdown text-gray-600">${comment.contentHtml || escapeHtml(comment.content)}</div>
+                ${Attachments.render(comment.attachments)}
+                ${this.renderVotes('comment', comment.id, comment.score, comment.userVote)}
+                ${Reactions.renderBar('comment', comment.id, comment.reactions)}
+                <button class="reply-to-btn text-xs text-blue-600 hover:text-blue-800 mt-1">Reply</button>
+                <button class="comment-bookmark-btn text-xs text-blue-600 hover:text-blue-800 mt-1 ml-2" data-id="${comment.id}" data-bookmarked="${comment.bookmarked}">${comment.bookmarked ? 'Saved' : 'Save'}</button>
+                <button class="comment-report-btn text-xs text-gray-500 hover:text-red-600 mt-1 ml-2">Report</button>
             `;
+            div.querySelector('.reply-to-btn').addEventListener('click', () => this.setReplyTo(comment.id, comment.nickname));
+            div.querySelector('.comment-bookmark-btn').addEventListener('click', (e) => {
+                Bookmarks.toggle('comment', comment.id, e.target.dataset.bookmarked === 'true');
+            });
+            div.querySelector('.comment-report-btn').addEventListener('click', () => Reports.report('comment', comment.id));
             repliesContainer.appendChild(div);
         });
     },
 
-    async createPost(title, content, categoryId) {
+    // Makes the next comment a reply to another comment, or a plain reply
+    // to the post when commentId is null
+    setReplyTo(commentId, nickname) {
+        ForumApp.replyToCommentId = commentId;
+        document.getElementById('reply-to-name').textContent = nickname || '';
+        document.getElementById('reply-to-indicator').classList.toggle('hidden', !commentId);
+        if (commentId) document.getElementById('reply-content').focus();
+    },
+
+    async createPost(title, content, categoryId, tags) {
         if (!ForumApp.currentUser) {
             DOM.loginModal.classList.remove('hidden');
             return;
         }
         try {
+            const filesInput = document.getElementById('thread-files');
+            const attachmentIds = await Attachments.uploadFiles(filesInput);
             const response = await fetch('/api/posts', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ title, content, categoryId: parseInt(categoryId) }),
+                body: JSON.stringify({ title, content, categoryId: parseInt(categoryId), tags, attachmentIds }),
                 credentials: 'include'
             });
 
             if (response.ok) {
                 const post = await response.json();
                 DOM.threadForm.classList.add('hidden');
+                if (filesInput) filesInput.value = '';
+                document.getElementById('thread-tags').value = '';
                 this.loadPosts();
+                Tags.loadDirectory();
                 showNotification('Post created successfully!');
             } else {
                 const error = await response.text();
@@ -168,7 +365,7 @@ window.Posts = {
             }
         } catch (error) {
             console.error('Error creating post:', error);
-            showNotification('Error creating post', 'error');
+            showNotification(error.message || 'Error creating post', 'error');
         }
     },
 
@@ -178,10 +375,12 @@ window.Posts = {
             return;
         }
         try {
+            const filesInput = document.getElementById('reply-files');
+            const attachmentIds = await Attachments.uploadFiles(filesInput);
             const response = await fetch('/api/comments', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ postId: postId, content }),
+                body: JSON.stringify({ postId: postId, parentId: ForumApp.replyToCommentId || 0, content, attachmentIds }),
                 credentials: 'include'
             });
 
@@ -189,6 +388,8 @@ window.Posts = {
                 const comment = await response.json();
                 this.loadComments(postId);
                 document.getElementById('reply-content').value = '';
+                if (filesInput) filesInput.value = '';
+                this.setReplyTo(null);
                 showNotification('Comment posted successfully!');
             } else {
                 const error = await response.text();
@@ -196,143 +397,215 @@ window.Posts = {
             }
         } catch (error) {
             console.error('Error creating comment:', error);
-            showNotification('Error posting comment', 'error');
+            showNotification(error.message || 'Error posting comment', 'error');
         }
     },
 
-    async populateCategories() {
-        const categorySelect = document.getElementById('thread-category');
-        const categoryList = document.getElementById('category-list');
-        const mobileCategoryList = document.getElementById('mobile-category-list');
-        if (!categorySelect || !categoryList || !mobileCategoryList) return;
+    renderVotes(targetType, targetId, score, userVote) {
+        return `
+            <div class="flex items-center space-x-1 mt-2 text-sm text-gray-500" data-vote-target="${targetType}:${targetId}" data-user-vote="${userVote || 0}">
+                <button class="px-1 ${userVote === 1 ? 'text-orange-500' : ''}" data-vote="1" title="Upvote">▲</button>
+                <span class="vote-score font-medium">${score || 0}</span>
+                <button class="px-1 ${userVote === -1 ? 'text-blue-500' : ''}" data-vote="-1" title="Downvote">▼</button>
+            </div>
+        `;
+    },
 
+    async vote(container, value) {
+        const [targetType, targetId] = container.dataset.voteTarget.split(':');
+        // Clicking the current vote again clears it
+        const current = parseInt(container.dataset.userVote);
+        if (current === value) value = 0;
         try {
-            const response = await fetch('/api/categories', { credentials: 'include' });
+            const response = await fetch('/api/votes', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ targetType, targetId: parseInt(targetId), value }),
+                credentials: 'include'
+            });
             if (response.ok) {
-                const categories = await response.json();
-                categorySelect.innerHTML = '<option value="">Select a category</option>';
-                categoryList.innerHTML = '';
-                mobileCategoryList.innerHTML = '';
-
-                // Add 'All' option for category list
-                const allButton = document.createElement('button');
-                allButton.className = `px-3 py-1 rounded-md ${ForumApp.currentCategory === 'all' ? 'bg-blue-100 text-blue-700' : 'text-gray-700'}`;
-                allButton.dataset.category = 'all';
-                allButton.textContent = 'All';
-                allButton.addEventListener('click', () => {
-                    if (!ForumApp.currentUser) {
-                        DOM.loginModal.classList.remove('hidden');
-                        return;
-                    }
-                    ForumApp.currentCategory = 'all';
-                    document.querySelectorAll('#category-list button, #mobile-category-list button').forEach(btn => {
-                        btn.classList.remove('bg-blue-100', 'text-blue-700');
-                        btn.classList.add('text-gray-700');
-                    });
-                    allButton.classList.add('bg-blue-100', 'text-blue-700');
-                    document.getElementById('current-category').textContent = 'All';
-                    this.loadPosts();
-                });
-                categoryList.appendChild(allButton);
-                mobileCategoryList.appendChild(allButton.cloneNode(true));
-
-                categories.forEach(category => {
-                    // Populate select dropdown
-                    const option = document.createElement('option');
-                    option.value = category.id;
-                    option.textContent = category.name;
-                    categorySelect.appendChild(option);
-
-                    // Populate category list
-                    const button = document.createElement('button');
-                    button.className = `px-3 py-1 rounded-md ${ForumApp.currentCategory == category.id ? 'bg-blue-100 text-blue-700' : 'text-gray-700'}`;
-                    button.dataset.category = category.id;
-                    button.textContent = category.name;
-                    button.addEventListener('click', () => {
-                        if (!ForumApp.currentUser) {
-                            DOM.loginModal.classList.remove('hidden');
-                            return;
-                        }
-                        ForumApp.currentCategory = category.id;
-                        document.querySelectorAll('#category-list button, #mobile-category-list button').forEach(btn => {
-                            btn.classList.remove('bg-blue-100', 'text-blue-700');
-                            btn.classList.add('text-gray-700');
-                        });
-                        button.classList.add('bg-blue-100', 'text-blue-700');
-                        document.getElementById('current-category').textContent = category.name;
-                        this.loadPosts();
-                    });
-                    categoryList.appendChild(button);
-                    mobileCategoryList.appendChild(button.cloneNode(true));
-                });
+                const result = await response.json();
+                container.outerHTML = this.renderVotes(targetType, result.targetId, result.score, result.userVote);
             } else {
-                throw new Error('Failed to load categories');
+                showNotification(await response.text() || 'Failed to vote', 'error');
             }
+        } catch (error) {
+            console.error('Error voting:', error);
+            showNotification('Error voting', 'error');
+        }
+    },
+
+    handleScoreUpdated(data) {
+        document.querySelectorAll(`[data-vote-target="${data.targetType}:${data.targetId}"] .vote-score`).forEach(el => {
+            el.textContent = data.score;
+        });
+    },
+
+    roomName(postId) {
+        return `post:${postId}`;
+    },
+
+    leaveThreadRoom() {
+        if (ForumApp.currentThreadId) {
+            WebSocketClient.leaveRoom(this.roomName(ForumApp.currentThreadId));
+        }
+        ForumApp.threadTypers = {};
+        this.updateReplyTypingIndicator();
+    },
+
+    isCurrentRoom(room) {
+        return ForumApp.currentThreadId && room === this.roomName(ForumApp.currentThreadId);
+    },
+
+    handleRoomPresence(data) {
+        if (!this.isCurrentRoom(data.room)) return;
+        const viewers = document.getElementById('thread-viewers');
+        if (!viewers) return;
+        const others = (data.viewers || []).filter(v => v.id !== ForumApp.currentUser?.id);
+        viewers.textContent = `${data.count} viewing`;
+        viewers.title = others.map(v => v.nickname).join(', ');
+    },
+
+    handleRoomTyping(data) {
+        if (!this.isCurrentRoom(data.room)) return;
+        ForumApp.threadTypers = ForumApp.threadTypers || {};
+        ForumApp.threadTypers[data.userId] = data.username;
+        this.updateReplyTypingIndicator();
+    },
+
+    handleRoomStopTyping(data) {
+        if (!this.isCurrentRoom(data.room) || !ForumApp.threadTypers) return;
+        delete ForumApp.threadTypers[data.userId];
+        this.updateReplyTypingIndicator();
+    },
+
+    updateReplyTypingIndicator() {
+        const indicator = document.getElementById('reply-typing-indicator');
+        if (!indicator) return;
+        const names = Object.values(ForumApp.threadTypers || {});
+        if (names.length === 0) {
+            indicator.classList.add('hidden');
+            return;
+        }
+        indicator.textContent = names.length === 1 ? `${names[0]} is replying...` : `${names.length} people are replying...`;
+        indicator.classList.remove('hidden');
+    },
+
+    handleNewComment(data) {
+        if (ForumApp.currentThreadId !== data.postId) return;
+        this.handleRoomStopTyping({ room: this.roomName(data.postId), userId: data.userId });
+        this.loadComments(data.postId);
+    },
+
+    async populateCategories() {
+        try {
+            const response = await fetch('/api/categories', { credentials: 'include' });
+            if (!response.ok) throw new Error('Failed to load categories');
+            this.renderCategories(await response.json());
         } catch (error) {
             console.error('Error loading categories:', error);
             // Fallback to static categories
-            const categories = [
+            this.renderCategories([
                 { id: 1, name: 'General Discussion' },
                 { id: 2, name: 'Technology' },
                 { id: 3, name: 'Random' },
                 { id: 4, name: 'Help' }
-            ];
-            categorySelect.innerHTML = '<option value="">Select a category</option>';
-            categoryList.innerHTML = '';
-            mobileCategoryList.innerHTML = '';
-
-            const allButton = document.createElement('button');
-            allButton.className = `px-3 py-1 rounded-md ${ForumApp.currentCategory === 'all' ? 'bg-blue-100 text-blue-700' : 'text-gray-700'}`;
-            allButton.dataset.category = 'all';
-            allButton.textContent = 'All';
-            allButton.addEventListener('click', () => {
-                if (!ForumApp.currentUser) {
-                    DOM.loginModal.classList.remove('hidden');
-                    return;
-                }
-                ForumApp.currentCategory = 'all';
-                document.querySelectorAll('#category-list button, #mobile-category-list button').forEach(btn => {
-                    btn.classList.remove('bg-blue-100', 'text-blue-700');
-                    btn.classList.add('text-gray-700');
-                });
-                allButton.classList.add('bg-blue-100', 'text-blue-700');
-                document.getElementById('current-category').textContent = 'All';
-                this.loadPosts();
+            ]);
+        }
+    },
+
+    // Fills the category select and lists; subcategories follow their
+    // parent, indented
+    renderCategories(categories) {
+        const categorySelect = document.getElementById('thread-category');
+        const categoryList = document.getElementById('category-list');
+        const mobileCategoryList = document.getElementById('mobile-category-list');
+        if (!categorySelect || !categoryList || !mobileCategoryList) return;
+
+        ForumApp.categories = categories;
+        const selected = categorySelect.value;
+        categorySelect.innerHTML = '<option value="">Select a category</option>';
+        categoryList.innerHTML = '';
+        mobileCategoryList.innerHTML = '';
+
+        const selectCategory = (id, name) => {
+            if (!ForumApp.currentUser) {
+                DOM.loginModal.classList.remove('hidden');
+                return;
+            }
+            ForumApp.currentCategory = id;
+            document.querySelectorAll('#category-list button, #mobile-category-list button').forEach(btn => {
+                btn.classList.remove('bg-blue-100', 'text-blue-700');
+                btn.classList.add('text-gray-700');
             });
-            categoryList.appendChild(allButton);
-            mobileCategoryList.appendChild(allButton.cloneNode(true));
+            document.querySelectorAll(`#category-list button[data-category="${id}"], #mobile-category-list button[data-category="${id}"]`)
+                .forEach(btn => btn.classList.add('bg-blue-100', 'text-blue-700'));
+            document.getElementById('current-category').textContent = name;
+            this.loadPosts();
+        };
 
-            categories.forEach(category => {
+        const addButton = (id, name, title, isChild) => {
+            [categoryList, mobileCategoryList].forEach(list => {
+                const button = document.createElement('button');
+                button.className = `px-3 py-1 rounded-md ${isChild ? 'ml-4 text-sm ' : ''}${ForumApp.currentCategory == id ? 'bg-blue-100 text-blue-700' : 'text-gray-700'}`;
+                button.dataset.category = id;
+                button.textContent = name;
+                if (title) button.title = title;
+                button.addEventListener('click', () => selectCategory(id, name));
+                list.appendChild(button);
+            });
+        };
+
+        // Add 'All' option for category list
+        addButton('all', 'All', '', false);
+
+        categories.forEach(category => {
+            // Populate select dropdown with the categories the user may post in
+            if (category.canPost !== false) {
                 const option = document.createElement('option');
                 option.value = category.id;
-                option.textContent = category.name;
+                option.textContent = category.parentId ? `\u00a0\u00a0${category.name}` : category.name;
                 categorySelect.appendChild(option);
+            }
 
-                const button = document.createElement('button');
-                button.className = `px-3 py-1 rounded-md ${ForumApp.currentCategory == category.id ? 'bg-blue-100 text-blue-700' : 'text-gray-700'}`;
-                button.dataset.category = category.id;
-                button.textContent = category.name;
-                button.addEventListener('click', () => {
-                    if (!ForumApp.currentUser) {
-                        DOM.loginModal.classList.remove('hidden');
-                        return;
-                    }
-                    ForumApp.currentCategory = category.id;
-                    document.querySelectorAll('#category-list button, #mobile-category-list button').forEach(btn => {
-                        btn.classList.remove('bg-blue-100', 'text-blue-700');
-                        btn.classList.add('text-gray-700');
-                    });
-                    button.classList.add('bg-blue-100', 'text-blue-700');
-                    document.getElementById('current-category').textContent = category.name;
-                    this.loadPosts();
-                });
-                categoryList.appendChild(button);
-                mobileCategoryList.appendChild(button.cloneNode(true));
-            });
+            // Populate category list
+            const details = [category.description, category.postCount !== undefined ? `${category.postCount} posts` : '']
+                .filter(Boolean).join(' · ');
+            addButton(category.id, category.name, details, Boolean(category.parentId));
+        });
+
+        if (ForumApp.currentThreadId && DOM.threadDetail.dataset.categoryId) {
+            this.showReplyForm(parseInt(DOM.threadDetail.dataset.categoryId));
+        }
+
+        // Keep the selection if the category is still there, otherwise fall
+        // back to all posts
+        categorySelect.value = categories.some(c => String(c.id) === selected && c.canPost !== false) ? selected : '';
+        if (ForumApp.currentUser && ForumApp.currentCategory !== 'all' && !categories.some(c => c.id == ForumApp.currentCategory)) {
+            selectCategory('all', 'All');
         }
     },
 
     setupEventListeners() {
+        document.addEventListener('click', (e) => {
+            const button = e.target.closest('[data-vote-target] button[data-vote]');
+            if (!button) return;
+            this.vote(button.parentElement, parseInt(button.dataset.vote));
+        });
+
+        document.getElementById('post-sort')?.addEventListener('change', (e) => {
+            ForumApp.currentSort = e.target.value;
+            document.getElementById('post-range')?.classList.toggle('hidden', ForumApp.currentSort !== 'top');
+            this.loadPosts();
+        });
+
+        document.getElementById('posts-more')?.addEventListener('click', () => this.loadPosts(this.postsOffset));
+
+        document.getElementById('post-range')?.addEventListener('change', (e) => {
+            ForumApp.currentRange = e.target.value;
+            this.loadPosts();
+        });
+
         document.getElementById('new-thread-btn')?.addEventListener('click', () => {
             if (!ForumApp.currentUser) {
                 DOM.loginModal.classList.remove('hidden');
@@ -355,7 +628,7 @@ window.Posts = {
                 return;
             }
 
-            this.createPost(title, content, categoryId);
+            this.createPost(title, content, categoryId, Tags.parse(document.getElementById('thread-tags').value));
         });
 
         document.getElementById('cancel-thread-btn')?.addEventListener('click', () => {
@@ -363,6 +636,7 @@ window.Posts = {
             document.getElementById('thread-title').value = '';
             document.getElementById('thread-content').value = '';
             document.getElementById('thread-category').value = '';
+            document.getElementById('thread-tags').value = '';
         });
 
         document.getElementById('post-reply-btn')?.addEventListener('click', () => {
@@ -378,12 +652,49 @@ window.Posts = {
             this.createComment(ForumApp.currentThreadId, content);
         });
 
+        document.getElementById('cancel-reply-to')?.addEventListener('click', () => this.setReplyTo(null));
+        document.getElementById('thread-watch-btn')?.addEventListener('click', () => this.toggleWatch());
+        document.getElementById('thread-bookmark-btn')?.addEventListener('click', (e) => {
+            Bookmarks.toggle('post', ForumApp.currentThreadId, e.target.dataset.bookmarked === 'true');
+        });
+        document.getElementById('thread-report-btn')?.addEventListener('click', () => {
+            Reports.report('post', ForumApp.currentThreadId);
+        });
+        document.getElementById('thread-pin-btn')?.addEventListener('click', () => {
+            this.updatePostState({ pinned: DOM.threadDetail.dataset.pinned ? '' : 'category' });
+        });
+        document.getElementById('thread-pin-global-btn')?.addEventListener('click', () => {
+            this.updatePostState({ pinned: DOM.threadDetail.dataset.pinned === 'global' ? 'category' : 'global' });
+        });
+        document.getElementById('thread-lock-btn')?.addEventListener('click', () => {
+            this.updatePostState({ locked: DOM.threadDetail.dataset.locked !== 'true' });
+        });
+        document.getElementById('thread-archive-btn')?.addEventListener('click', () => {
+            this.updatePostState({ archived: DOM.threadDetail.dataset.archived !== 'true' });
+        });
+
         document.getElementById('back-to-threads')?.addEventListener('click', () => {
             DOM.threadDetail.classList.add('hidden');
             DOM.threadsContainer.classList.remove('hidden');
+            document.getElementById('posts-more')?.classList.toggle('hidden', !this.morePosts);
+            this.leaveThreadRoom();
             ForumApp.currentThreadId = null;
         });
 
+        let replyTypingTimeout = null;
+        document.getElementById('reply-content')?.addEventListener('input', () => {
+            if (!ForumApp.currentThreadId) return;
+            const room = this.roomName(ForumApp.currentThreadId);
+            if (!replyTypingTimeout) {
+                WebSocketClient.sendRoomTyping(room);
+            }
+            clearTimeout(replyTypingTimeout);
+            replyTypingTimeout = setTimeout(() => {
+                WebSocketClient.sendRoomStopTyping(room);
+                replyTypingTimeout = null;
+            }, 2000);
+        });
+
         document.getElementById('thread-category')?.addEventListener('change', (e) => {
             if (!ForumApp.currentUser) {
                 DOM.loginModal.classList.remove('hidden');
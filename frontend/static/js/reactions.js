@@ -0,0 +1,92 @@
+window.Reactions = {
+    emojis: ['👍', '❤️', '😂', '😮', '😢', '🎉'],
+
+    // Latest counts per "<targetType>:<targetId>"
+    counts: {},
+
+    key(targetType, targetId) {
+        return `${targetType}:${targetId}`;
+    },
+
+    renderBar(targetType, targetId, reactions) {
+        const key = this.key(targetType, targetId);
+        this.counts[key] = reactions || [];
+        return `<div class="reaction-bar flex flex-wrap gap-1 mt-2 text-sm" data-reaction-target="${key}">${this.renderButtons(key)}</div>`;
+    },
+
+    renderButtons(key) {
+        const counts = this.counts[key] || [];
+        const shown = counts.map(c => c.emoji);
+        const buttons = counts.map(c => `
+            <button class="px-2 rounded-full border ${c.reacted ? 'bg-blue-100 border-blue-400' : 'bg-white border-gray-200'}" data-emoji="${escapeHtml(c.emoji)}">
+                ${escapeHtml(c.emoji)} ${c.count}
+            </button>`);
+        this.emojis.filter(e => !shown.includes(e)).forEach(e => {
+            buttons.push(`<button class="px-2 rounded-full border border-transparent opacity-50 hover:opacity-100" data-emoji="${e}">${e}</button>`);
+        });
+        return buttons.join('');
+    },
+
+    store(key, counts) {
+        this.counts[key] = counts;
+        // Chats re-render from memory, so keep the cached message in step
+        const [targetType, targetId] = key.split(':');
+        if (targetType === 'message') {
+            const found = Messages.findMessage(parseInt(targetId));
+            if (found) found.message.reactions = counts;
+        }
+        this.refresh(key);
+    },
+
+    refresh(key) {
+        document.querySelectorAll(`[data-reaction-target="${key}"]`).forEach(bar => {
+            bar.innerHTML = this.renderButtons(key);
+        });
+    },
+
+    async toggle(key, emoji) {
+        const [targetType, targetId] = key.split(':');
+        const reacted = (this.counts[key] || []).some(c => c.emoji === emoji && c.reacted);
+        const url = reacted
+            ? `/api/reactions?target_type=${targetType}&target_id=${targetId}&emoji=${encodeURIComponent(emoji)}`
+            : '/api/reactions';
+        try {
+            const response = await fetch(url, {
+                method: reacted ? 'DELETE' : 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: reacted ? undefined : JSON.stringify({ targetType, targetId: parseInt(targetId), emoji }),
+                credentials: 'include'
+            });
+            if (response.ok) {
+                this.store(key, await response.json());
+            } else {
+                showNotification(await response.text() || 'Failed to update reaction', 'error');
+            }
+        } catch (error) {
+            console.error('Error updating reaction:', error);
+            showNotification('Error updating reaction', 'error');
+        }
+    },
+
+    handleReactionUpdated(data) {
+        const key = this.key(data.targetType, data.targetId);
+        if (!(key in this.counts)) return;
+
+        // Broadcast counts carry no reacted flag; keep ours
+        const previous = this.counts[key];
+        const isMine = data.userId === ForumApp.currentUser?.id;
+        this.store(key, (data.reactions || []).map(c => ({
+            ...c,
+            reacted: isMine && c.emoji === data.emoji
+                ? data.added
+                : previous.some(p => p.emoji === c.emoji && p.reacted)
+        })));
+    }
+};
+
+document.addEventListener('click', (e) => {
+    const button = e.target.closest('[data-reaction-target] button[data-emoji]');
+    if (!button) return;
+    e.stopPropagation();
+    Reactions.toggle(button.parentElement.dataset.reactionTarget, button.dataset.emoji);
+});
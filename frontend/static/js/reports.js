@@ -0,0 +1,182 @@
+window.Reports = {
+    reasons: ['spam', 'harassment', 'hate', 'inappropriate', 'other'],
+    items: [],
+    status: 'open',
+    offset: 0,
+
+    init() {
+        document.getElementById('report-status')?.addEventListener('change', (e) => {
+            this.status = e.target.value;
+            this.load();
+        });
+        document.getElementById('reports-more')?.addEventListener('click', () => this.load(this.offset));
+        document.getElementById('reports-list')?.addEventListener('click', (e) => {
+            const li = e.target.closest('li[data-id]');
+            if (!li) return;
+            const report = this.items.find(r => r.id === parseInt(li.dataset.id));
+            if (!report) return;
+            const button = e.target.closest('button[data-action]');
+            if (button) {
+                this.act(report, button.dataset.action);
+            } else if (report.postId) {
+                Posts.loadPostDetails(report.postId);
+            }
+        });
+    },
+
+    isModerator() {
+        const role = ForumApp.currentUser?.role;
+        return role === 'moderator' || role === 'admin';
+    },
+
+    // Asks why a post, comment, message or user is reported and sends the
+    // report to the moderators
+    async report(targetType, targetId) {
+        const reason = prompt(`Why are you reporting this ${targetType}? (${this.reasons.join(', ')})`, 'spam');
+        if (reason === null) return;
+        if (!this.reasons.includes(reason.trim().toLowerCase())) {
+            showNotification(`Please choose one of: ${this.reasons.join(', ')}`, 'error');
+            return;
+        }
+        const note = prompt('Anything the moderators should know? (optional)', '');
+        if (note === null) return;
+        try {
+            const response = await fetch('/api/reports', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ targetType, targetId, reason: reason.trim().toLowerCase(), note }),
+                credentials: 'include'
+            });
+            if (response.ok) {
+                showNotification('Thanks, the moderators will take a look');
+            } else {
+                showNotification(await response.text() || 'Failed to send report', 'error');
+            }
+        } catch (error) {
+            console.error('Error sending report:', error);
+        }
+    },
+
+    // Loads the moderation queue for moderators; others never see it
+    async load(offset = 0) {
+        const panel = document.getElementById('reports-panel');
+        panel?.classList.toggle('hidden', !this.isModerator());
+        if (!this.isModerator()) return;
+        try {
+            const params = new URLSearchParams({ status: this.status, offset });
+            const response = await fetch(`/api/reports?${params}`, { credentials: 'include' });
+            if (!response.ok) return;
+            const list = await response.json();
+            this.items = offset === 0 ? list.reports : this.items.concat(list.reports);
+            this.offset = this.items.length;
+            document.getElementById('reports-more')?.classList.toggle('hidden', list.reports.length < 50);
+            this.showOpenCount(list.openCount);
+            this.render();
+        } catch (error) {
+            console.error('Error loading reports:', error);
+        }
+    },
+
+    showOpenCount(count) {
+        const badge = document.getElementById('reports-count');
+        if (!badge) return;
+        badge.textContent = count;
+        badge.classList.toggle('hidden', !count);
+    },
+
+    render() {
+        const list = document.getElementById('reports-list');
+        if (!list) return;
+        if (this.items.length === 0) {
+            list.innerHTML = '<li class="text-sm text-gray-500">No reports here</li>';
+            return;
+        }
+        list.innerHTML = this.items.map(r => `
+            <li data-id="${r.id}" class="text-sm rounded p-1 ${r.postId ? 'cursor-pointer hover:bg-gray-50' : ''}">
+                <p class="font-medium text-gray-700">${escapeHtml(r.reason)}: ${escapeHtml(r.targetType)} by ${escapeHtml(r.reportedUserName)}</p>
+                <p class="text-gray-500 truncate">${escapeHtml(r.excerpt)}</p>
+                ${r.note ? `<p class="text-xs text-gray-400 truncate">${escapeHtml(r.reporterName)}: ${escapeHtml(r.note)}</p>` : ''}
+                ${r.assigneeName ? `<p class="text-xs text-gray-400">Assigned to ${escapeHtml(r.assigneeName)}</p>` : ''}
+                ${r.resolutionNote ? `<p class="text-xs text-gray-400 truncate">${escapeHtml(r.resolutionNote)}</p>` : ''}
+                <div class="text-xs space-x-2 mt-1">
+                    ${r.status === 'open' ? `
+                        ${r.assigneeId !== ForumApp.currentUser.id ? '<button data-action="assign" class="text-blue-600 hover:text-blue-800">Take</button>' : ''}
+                        <button data-action="actioned" class="text-blue-600 hover:text-blue-800">Actioned</button>
+                        <button data-action="dismissed" class="text-blue-600 hover:text-blue-800">Dismiss</button>
+                    ` : '<button data-action="open" class="text-blue-600 hover:text-blue-800">Reopen</button>'}
+                    <button data-action="ban" class="text-red-600 hover:text-red-800">Ban user</button>
+                </div>
+            </li>
+        `).join('');
+    },
+
+    // Takes, closes or reopens a report; closing asks for a resolution note
+    async act(report, action) {
+        let changes;
+        if (action === 'ban') {
+            this.banUser(report.reportedUserId, report.reportedUserName);
+            return;
+        } else if (action === 'assign') {
+            changes = { assigneeId: ForumApp.currentUser.id };
+        } else if (action === 'open') {
+            changes = { status: 'open' };
+        } else {
+            const note = prompt('Resolution note (optional)', report.resolutionNote || '');
+            if (note === null) return;
+            changes = { status: action, resolutionNote: note };
+        }
+        try {
+            const response = await fetch(`/api/reports?id=${report.id}`, {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(changes),
+                credentials: 'include'
+            });
+            if (!response.ok) {
+                showNotification(await response.text() || 'Failed to update report', 'error');
+            }
+        } catch (error) {
+            console.error('Error updating report:', error);
+        }
+    },
+
+    // Bans a user for a number of hours, or permanently when none are given
+    async banUser(userId, name) {
+        const hours = prompt(`Ban ${name} for how many hours? Leave empty for a permanent ban.`, '24');
+        if (hours === null) return;
+        if (hours.trim() && !(parseInt(hours) > 0)) {
+            showNotification('Please enter a number of hours', 'error');
+            return;
+        }
+        const reason = prompt('Reason for the ban (shown to the user)', '');
+        if (!reason || !reason.trim()) return;
+        const hideContent = confirm(`Also hide ${name}'s posts and comments while the ban lasts?`);
+        try {
+            const response = await fetch('/api/bans', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ userId, reason, hours: parseInt(hours) || 0, hideContent }),
+                credentials: 'include'
+            });
+            if (response.ok) {
+                showNotification(`${name} has been banned`);
+            } else {
+                showNotification(await response.text() || 'Failed to ban user', 'error');
+            }
+        } catch (error) {
+            console.error('Error banning user:', error);
+        }
+    },
+
+    // Handles a report_created or report_updated event; new reports raise
+    // an alert
+    handleReport(type, data) {
+        if (type === 'report_created') {
+            showNotification(`New report: ${data.report.reason} (${data.report.targetType} by ${data.report.reportedUserName})`);
+        }
+        this.showOpenCount(data.openCount);
+        this.load();
+    }
+};
+
+document.addEventListener('DOMContentLoaded', () => Reports.init());
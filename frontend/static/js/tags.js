@@ -0,0 +1,81 @@
+window.Tags = {
+    searchTimer: null,
+
+    init() {
+        // Tag chips on posts, in the filter bar and in the directory
+        document.addEventListener('click', (e) => {
+            const chip = e.target.closest('[data-tag]');
+            if (!chip) return;
+            e.preventDefault();
+            this.toggleFilter(chip.dataset.tag);
+        });
+        document.getElementById('tag-match-all')?.addEventListener('change', (e) => {
+            ForumApp.tagMatch = e.target.checked ? 'all' : 'any';
+            Posts.loadPosts();
+        });
+        document.getElementById('tag-filter-clear')?.addEventListener('click', () => {
+            ForumApp.currentTags = [];
+            this.renderFilter();
+            Posts.loadPosts();
+        });
+        document.getElementById('tag-search')?.addEventListener('input', (e) => {
+            clearTimeout(this.searchTimer);
+            this.searchTimer = setTimeout(() => this.loadDirectory(e.target.value), 200);
+        });
+    },
+
+    renderChips(tags) {
+        if (!tags || tags.length === 0) return '';
+        return `<div class="flex flex-wrap gap-1 mb-2">${tags.map(tag => `
+            <a href="#" data-tag="${escapeHtml(tag)}" class="text-xs px-2 py-0.5 rounded-full bg-gray-100 text-gray-700 hover:bg-blue-100">#${escapeHtml(tag)}</a>
+        `).join('')}</div>`;
+    },
+
+    // Adds a tag to the post filter, or removes it if already there
+    toggleFilter(tag) {
+        const tags = ForumApp.currentTags;
+        ForumApp.currentTags = tags.includes(tag) ? tags.filter(t => t !== tag) : [...tags, tag];
+        this.renderFilter();
+        if (ForumApp.currentThreadId) document.getElementById('back-to-threads')?.click();
+        Posts.loadPosts();
+    },
+
+    renderFilter() {
+        const bar = document.getElementById('tag-filter');
+        if (!bar) return;
+        bar.classList.toggle('hidden', ForumApp.currentTags.length === 0);
+        document.getElementById('tag-filter-tags').innerHTML = this.renderChips(ForumApp.currentTags);
+        document.getElementById('tag-match-all').checked = ForumApp.tagMatch === 'all';
+        document.getElementById('tag-match-label')?.classList.toggle('hidden', ForumApp.currentTags.length < 2);
+    },
+
+    async loadDirectory(query = '') {
+        const list = document.getElementById('tag-directory');
+        if (!list) return;
+        try {
+            const response = await fetch(`/api/tags?${new URLSearchParams({ q: query })}`, { credentials: 'include' });
+            if (!response.ok) return;
+            const tags = await response.json();
+            if (tags.length === 0) {
+                list.innerHTML = '<li class="text-sm text-gray-500">No tags yet</li>';
+                return;
+            }
+            list.innerHTML = tags.slice(0, 20).map(tag => `
+                <li class="flex justify-between text-sm">
+                    <a href="#" data-tag="${escapeHtml(tag.name)}" class="text-blue-600 hover:text-blue-800"
+                        title="${escapeHtml(tag.synonyms.join(', '))}">#${escapeHtml(tag.name)}</a>
+                    <span class="text-gray-400">${tag.postCount}</span>
+                </li>
+            `).join('');
+        } catch (error) {
+            console.error('Error loading tags:', error);
+        }
+    },
+
+    // Splits the comma-separated tags typed into an input
+    parse(text) {
+        return text.split(',').map(tag => tag.trim()).filter(Boolean);
+    }
+};
+
+document.addEventListener('DOMContentLoaded', () => Tags.init());
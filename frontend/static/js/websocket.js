@@ -1,5 +1,9 @@
 window.WebSocketClient = {
     socket: null,
+    eventSource: null,
+    clientId: null,
+    everConnected: false,
+    nextReconnectDelay: null,
     reconnectAttempts: 0,
     maxReconnectAttempts: 5,
     reconnectInterval: 5000,
@@ -13,8 +17,12 @@ window.WebSocketClient = {
 
         this.socket.onopen = () => {
             console.log('WebSocket connected');
+            this.everConnected = true;
             this.reconnectAttempts = 0;
             showNotification('Connected to real-time updates');
+            if (ForumApp.currentThreadId) {
+                this.joinRoom(Posts.roomName(ForumApp.currentThreadId));
+            }
         };
 
         this.socket.onmessage = (event) => {
@@ -28,12 +36,24 @@ window.WebSocketClient = {
 
         this.socket.onclose = (event) => {
             console.log('WebSocket closed:', event);
+            this.socket = null;
+            if (event.code === 4003) {
+                this.handleBanned();
+                return;
+            }
+            if (!this.everConnected) {
+                // The upgrade never succeeded, likely blocked by a proxy
+                this.connectEventSource();
+                return;
+            }
             if (this.reconnectAttempts < this.maxReconnectAttempts) {
+                const delay = this.nextReconnectDelay || this.reconnectInterval;
+                this.nextReconnectDelay = null;
                 setTimeout(() => {
                     this.reconnectAttempts++;
                     console.log(`Reconnecting attempt ${this.reconnectAttempts}...`);
                     this.connect();
-                }, this.reconnectInterval);
+                }, delay);
             } else {
                 showNotification('Lost connection to server', 'error');
             }
@@ -44,16 +64,57 @@ window.WebSocketClient = {
         };
     },
 
+    connectEventSource() {
+        if (!ForumApp.currentUser || this.eventSource) return;
+
+        console.log('Falling back to Server-Sent Events');
+        this.eventSource = new EventSource('/api/events', { withCredentials: true });
+
+        this.eventSource.onmessage = (event) => {
+            try {
+                const message = JSON.parse(event.data);
+                if (message.type === 'connected') {
+                    this.clientId = message.data.clientId;
+                    showNotification('Connected to real-time updates');
+                    if (ForumApp.currentThreadId) {
+                        this.joinRoom(Posts.roomName(ForumApp.currentThreadId));
+                    }
+                    return;
+                }
+                this.handleMessage(message);
+            } catch (error) {
+                console.error('Error parsing event stream message:', error);
+            }
+        };
+
+        this.eventSource.onerror = (error) => {
+            console.error('Event stream error:', error);
+            this.clientId = null;
+        };
+    },
+
     disconnect() {
         if (this.socket) {
             this.socket.close();
             this.socket = null;
         }
+        if (this.eventSource) {
+            this.eventSource.close();
+            this.eventSource = null;
+            this.clientId = null;
+        }
     },
 
     sendMessage(type, data) {
         if (this.socket && this.socket.readyState === WebSocket.OPEN) {
             this.socket.send(JSON.stringify({ type, data }));
+        } else if (this.eventSource && this.clientId) {
+            fetch('/api/realtime/actions', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ type, data, clientId: this.clientId }),
+                credentials: 'include'
+            }).catch(error => console.error('Error sending event:', error));
         } else {
             console.error('WebSocket is not connected');
             showNotification('Cannot send message: Not connected', 'error');
@@ -68,17 +129,85 @@ window.WebSocketClient = {
             case 'new_message':
                 Messages.handleNewMessage(message.data);
                 break;
+            case 'message_request':
+                Messages.handleMessageRequest(message.data);
+                break;
+            case 'mention':
+                Mentions.handleMention(message.data);
+                break;
+            case 'notification':
+                Notifications.handleNotification(message.data);
+                break;
+            case 'notifications_read':
+                Notifications.setUnreadCount(message.data.unreadCount);
+                break;
+            case 'message_updated':
+                Messages.handleMessageUpdated(message.data);
+                break;
+            case 'message_deleted':
+                Messages.handleMessageDeleted(message.data);
+                break;
             case 'typing':
                 Messages.handleTyping(message.data);
                 break;
             case 'stop_typing':
                 Messages.handleStopTyping(message.data);
                 break;
+            case 'room_presence':
+                Posts.handleRoomPresence(message.data);
+                break;
+            case 'room_typing':
+                Posts.handleRoomTyping(message.data);
+                break;
+            case 'room_stop_typing':
+                Posts.handleRoomStopTyping(message.data);
+                break;
+            case 'new_comment':
+                Posts.handleNewComment(message.data);
+                break;
+            case 'score_updated':
+                Posts.handleScoreUpdated(message.data);
+                break;
+            case 'reaction_updated':
+                Reactions.handleReactionUpdated(message.data);
+                break;
+            case 'post_state_updated':
+                Posts.handlePostState(message.data);
+                break;
+            case 'report_created':
+            case 'report_updated':
+                Reports.handleReport(message.type, message.data);
+                break;
+            case 'categories_updated':
+                Posts.renderCategories(message.data.categories || []);
+                break;
+            case 'account_banned':
+                this.handleBanned(message.data?.message);
+                break;
+            case 'server_shutdown':
+                // Reconnect once the server is back instead of using up retries
+                this.reconnectAttempts = 0;
+                this.nextReconnectDelay = (message.data?.reconnectAfter || 5) * 1000;
+                showNotification('Server restarting, reconnecting shortly...');
+                break;
+            case 'error':
+                showNotification(message.data?.message || 'Server error', 'error');
+                break;
             default:
                 console.warn('Unknown WebSocket message type:', message.type);
         }
     },
 
+    // Signs out a user who was just banned; the server closes their
+    // connections right after telling them why
+    handleBanned(reason) {
+        if (!ForumApp.currentUser) return;
+        ForumApp.currentUser = null;
+        this.disconnect();
+        showLoggedOutUI();
+        showNotification(reason || 'Your account has been banned', 'error');
+    },
+
     handleOnlineUsers(data) {
         ForumApp.onlineUsers = data.users || [];
         updateOnlineCount();
@@ -92,6 +221,14 @@ window.WebSocketClient = {
         });
     },
 
+    editMessage(messageId, content) {
+        this.sendMessage('edit_message', { messageId, content });
+    },
+
+    deleteMessage(messageId, scope) {
+        this.sendMessage('delete_message', { messageId, scope });
+    },
+
     sendTyping(recipientId) {
         this.sendMessage('typing', {
             chatWith: parseInt(recipientId)
@@ -102,5 +239,21 @@ window.WebSocketClient = {
         this.sendMessage('stop_typing', {
             chatWith: parseInt(recipientId)
         });
+    },
+
+    joinRoom(room) {
+        this.sendMessage('join_room', { room });
+    },
+
+    leaveRoom(room) {
+        this.sendMessage('leave_room', { room });
+    },
+
+    sendRoomTyping(room) {
+        this.sendMessage('room_typing', { room });
+    },
+
+    sendRoomStopTyping(room) {
+        this.sendMessage('room_stop_typing', { room });
     }
 };
\ No newline at end of file
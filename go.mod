@@ -8,3 +8,15 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.29
 	golang.org/x/crypto v0.40.0
 )
+
+require (
+	github.com/microcosm-cc/bluemonday v1.0.27
+	github.com/yuin/goldmark v1.8.6
+	golang.org/x/image v0.29.0
+)
+
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
+	golang.org/x/net v0.41.0 // indirect
+)
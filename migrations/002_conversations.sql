@@ -0,0 +1,86 @@
+-- Conversations for one-to-one and group private messaging
+
+-- Conversations table; direct_key is "<lower user id>:<higher user id>" for one-to-one chats
+CREATE TABLE IF NOT EXISTS conversations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL DEFAULT '',
+    is_group BOOLEAN NOT NULL DEFAULT FALSE,
+    direct_key TEXT UNIQUE,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+-- Conversation participants with per-participant read state
+CREATE TABLE IF NOT EXISTS conversation_participants (
+    conversation_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    left_at DATETIME,
+    last_read_message_id INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (conversation_id, user_id),
+    FOREIGN KEY (conversation_id) REFERENCES conversations (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Move existing one-to-one messages into two-person conversations
+INSERT INTO conversations (is_group, direct_key, created_at, updated_at)
+SELECT 0,
+       MIN(sender_id, recipient_id) || ':' || MAX(sender_id, recipient_id),
+       MIN(created_at),
+       MAX(created_at)
+FROM messages
+GROUP BY MIN(sender_id, recipient_id), MAX(sender_id, recipient_id);
+
+INSERT OR IGNORE INTO conversation_participants (conversation_id, user_id, joined_at)
+SELECT id, CAST(substr(direct_key, 1, instr(direct_key, ':') - 1) AS INTEGER), created_at
+FROM conversations WHERE direct_key IS NOT NULL
+UNION ALL
+SELECT id, CAST(substr(direct_key, instr(direct_key, ':') + 1) AS INTEGER), created_at
+FROM conversations WHERE direct_key IS NOT NULL;
+
+-- Rebuild messages around conversations; recipient_id is kept for one-to-one chats
+CREATE TABLE messages_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    conversation_id INTEGER NOT NULL,
+    sender_id INTEGER NOT NULL,
+    recipient_id INTEGER,
+    content TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (conversation_id) REFERENCES conversations (id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (recipient_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+INSERT INTO messages_new (id, conversation_id, sender_id, recipient_id, content, created_at)
+SELECT m.id, c.id, m.sender_id, m.recipient_id, m.content, m.created_at
+FROM messages m
+JOIN conversations c
+  ON c.direct_key = MIN(m.sender_id, m.recipient_id) || ':' || MAX(m.sender_id, m.recipient_id);
+
+-- Carry over read state: everything a participant sent or had read
+UPDATE conversation_participants
+SET last_read_message_id = COALESCE((
+    SELECT MAX(m.id) FROM messages m
+    JOIN conversations c ON c.id = conversation_participants.conversation_id
+    WHERE c.direct_key = MIN(m.sender_id, m.recipient_id) || ':' || MAX(m.sender_id, m.recipient_id)
+      AND (m.sender_id = conversation_participants.user_id OR m.is_read = 1)
+), 0);
+
+DROP TABLE messages;
+ALTER TABLE messages_new RENAME TO messages;
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_sender_id ON messages(sender_id);
+CREATE INDEX IF NOT EXISTS idx_messages_recipient_id ON messages(recipient_id);
+CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_participants_user_id ON conversation_participants(user_id);
+
+CREATE TRIGGER IF NOT EXISTS update_conversations_updated_at
+    AFTER UPDATE ON conversations
+    FOR EACH ROW
+BEGIN
+    UPDATE conversations SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
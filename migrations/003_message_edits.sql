@@ -0,0 +1,27 @@
+-- Editing and deleting private messages
+
+-- Edited marker and delete-for-everyone tombstone
+ALTER TABLE messages ADD COLUMN edited_at DATETIME;
+ALTER TABLE messages ADD COLUMN deleted_at DATETIME;
+
+-- Previous versions of edited messages, oldest first
+CREATE TABLE IF NOT EXISTS message_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE
+);
+
+-- Messages a participant deleted for themselves only
+CREATE TABLE IF NOT EXISTS message_hidden (
+    message_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    hidden_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, user_id),
+    FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_revisions_message_id ON message_revisions(message_id);
+CREATE INDEX IF NOT EXISTS idx_message_hidden_user_id ON message_hidden(user_id);
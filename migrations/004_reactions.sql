@@ -0,0 +1,37 @@
+-- Emoji reactions on posts, comments and private messages
+
+-- One row per user per emoji per target; target_type is post, comment or message
+CREATE TABLE IF NOT EXISTS reactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment', 'message')),
+    target_id INTEGER NOT NULL,
+    emoji TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, target_type, target_id, emoji),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_target ON reactions(target_type, target_id);
+
+-- Targets are polymorphic, so clean up reactions when a target goes away
+CREATE TRIGGER IF NOT EXISTS delete_post_reactions
+    AFTER DELETE ON posts
+    FOR EACH ROW
+BEGIN
+    DELETE FROM reactions WHERE target_type = 'post' AND target_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS delete_comment_reactions
+    AFTER DELETE ON comments
+    FOR EACH ROW
+BEGIN
+    DELETE FROM reactions WHERE target_type = 'comment' AND target_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS delete_message_reactions
+    AFTER DELETE ON messages
+    FOR EACH ROW
+BEGIN
+    DELETE FROM reactions WHERE target_type = 'message' AND target_id = OLD.id;
+END;
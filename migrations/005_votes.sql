@@ -0,0 +1,75 @@
+-- Up and down votes on posts and comments
+
+-- Denormalized vote totals, kept in step by the triggers below
+ALTER TABLE posts ADD COLUMN score INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN score INTEGER NOT NULL DEFAULT 0;
+
+-- One vote per user per target; value is 1 or -1
+CREATE TABLE IF NOT EXISTS votes (
+    user_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')),
+    target_id INTEGER NOT NULL,
+    value INTEGER NOT NULL CHECK (value IN (1, -1)),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, target_type, target_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_votes_target ON votes(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_posts_score ON posts(score DESC);
+
+CREATE TRIGGER IF NOT EXISTS add_vote_score
+    AFTER INSERT ON votes
+    FOR EACH ROW
+BEGIN
+    UPDATE posts SET score = score + NEW.value WHERE NEW.target_type = 'post' AND id = NEW.target_id;
+    UPDATE comments SET score = score + NEW.value WHERE NEW.target_type = 'comment' AND id = NEW.target_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS change_vote_score
+    AFTER UPDATE OF value ON votes
+    FOR EACH ROW
+BEGIN
+    UPDATE posts SET score = score - OLD.value + NEW.value WHERE NEW.target_type = 'post' AND id = NEW.target_id;
+    UPDATE comments SET score = score - OLD.value + NEW.value WHERE NEW.target_type = 'comment' AND id = NEW.target_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS remove_vote_score
+    AFTER DELETE ON votes
+    FOR EACH ROW
+BEGIN
+    UPDATE posts SET score = score - OLD.value WHERE OLD.target_type = 'post' AND id = OLD.target_id;
+    UPDATE comments SET score = score - OLD.value WHERE OLD.target_type = 'comment' AND id = OLD.target_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS delete_post_votes
+    AFTER DELETE ON posts
+    FOR EACH ROW
+BEGIN
+    DELETE FROM votes WHERE target_type = 'post' AND target_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS delete_comment_votes
+    AFTER DELETE ON comments
+    FOR EACH ROW
+BEGIN
+    DELETE FROM votes WHERE target_type = 'comment' AND target_id = OLD.id;
+END;
+
+-- Score changes are not edits; only bump updated_at when content changes
+DROP TRIGGER IF EXISTS update_posts_updated_at;
+CREATE TRIGGER update_posts_updated_at
+    AFTER UPDATE OF title, content, category_id ON posts
+    FOR EACH ROW
+BEGIN
+    UPDATE posts SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+DROP TRIGGER IF EXISTS update_comments_updated_at;
+CREATE TRIGGER update_comments_updated_at
+    AFTER UPDATE OF content ON comments
+    FOR EACH ROW
+BEGIN
+    UPDATE comments SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
@@ -0,0 +1,22 @@
+-- Direct message privacy, follows and message requests
+
+-- Who may start a conversation with a user: everyone, people they follow, or nobody
+ALTER TABLE users ADD COLUMN dm_privacy TEXT NOT NULL DEFAULT 'everyone'
+    CHECK (dm_privacy IN ('everyone', 'following', 'nobody'));
+
+-- One-way follows between users
+CREATE TABLE IF NOT EXISTS user_follows (
+    follower_id INTEGER NOT NULL,
+    followed_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followed_id),
+    FOREIGN KEY (follower_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (followed_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_follows_followed_id ON user_follows(followed_id);
+
+-- A participant's status is 'pending' while a conversation sits in their
+-- message requests, and 'declined' once they turn it down
+ALTER TABLE conversation_participants ADD COLUMN status TEXT NOT NULL DEFAULT 'accepted'
+    CHECK (status IN ('accepted', 'pending', 'declined'));
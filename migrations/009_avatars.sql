@@ -0,0 +1,5 @@
+-- Uploaded avatar images
+
+-- avatar_key names the stored set of resized avatars; users without one get
+-- a generated avatar
+ALTER TABLE users ADD COLUMN avatar_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- Rendered Markdown stored next to the raw source of posts and comments
+
+-- Rows written before this migration are rendered when the server starts
+ALTER TABLE posts ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+-- Email digests of unread activity: how often each user gets one, when the
+-- last one was due and the token that unsubscribes them
+
+ALTER TABLE users ADD COLUMN digest_frequency TEXT NOT NULL DEFAULT 'weekly' CHECK (digest_frequency IN ('off', 'daily', 'weekly'));
+ALTER TABLE users ADD COLUMN last_digest_at DATETIME;
+ALTER TABLE users ADD COLUMN unsubscribe_token TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_unsubscribe_token ON users (unsubscribe_token);
@@ -0,0 +1,50 @@
+-- Watched posts: watchers are notified of every new comment. A row with
+-- watching = 0 records an explicit unwatch, so commenting again does not
+-- start watching the post again.
+
+CREATE TABLE IF NOT EXISTS post_watches (
+    user_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    watching BOOLEAN NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_watches_post ON post_watches (post_id) WHERE watching = 1;
+
+-- Whether writing a post or comment watches the post
+ALTER TABLE users ADD COLUMN auto_watch_posts BOOLEAN NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN auto_watch_comments BOOLEAN NOT NULL DEFAULT 1;
+
+-- Existing authors and commenters watch their posts
+INSERT OR IGNORE INTO post_watches (user_id, post_id, watching)
+SELECT user_id, id, 1 FROM posts;
+
+INSERT OR IGNORE INTO post_watches (user_id, post_id, watching)
+SELECT DISTINCT user_id, post_id, 1 FROM comments;
+
+-- Rebuild notifications to allow the watched_post type
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('post_reply', 'comment_reply', 'mention', 'reaction', 'direct_message', 'watched_post')),
+    actor_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment', 'message')),
+    target_id INTEGER NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    read_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+INSERT INTO notifications_new (id, user_id, type, actor_id, target_type, target_id, detail, read_at, created_at)
+SELECT id, user_id, type, actor_id, target_type, target_id, detail, read_at, created_at FROM notifications;
+
+DROP TABLE notifications;
+ALTER TABLE notifications_new RENAME TO notifications;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
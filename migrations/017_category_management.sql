@@ -0,0 +1,15 @@
+-- Category management: one level of subcategories, a display order among
+-- siblings, and archiving, which stops new posts but keeps old ones readable.
+-- Admins manage categories; moderators curate tag synonyms, handle reports
+-- and ban users.
+
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
+
+ALTER TABLE categories ADD COLUMN parent_id INTEGER REFERENCES categories (id);
+ALTER TABLE categories ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE categories ADD COLUMN archived_at DATETIME;
+
+-- Keep the seeded categories in the order they were created
+UPDATE categories SET position = id;
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories (parent_id, position);
@@ -0,0 +1,37 @@
+-- Per-category permissions. A category with no rules for an action lets
+-- everyone take it; otherwise a user needs a rule granting it to their role
+-- (or a lower one) or to a group they belong to. Subcategories without
+-- rules of their own for an action follow their parent's.
+
+CREATE TABLE IF NOT EXISTS user_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_group_members (
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id),
+    FOREIGN KEY (group_id) REFERENCES user_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_group_members_user ON user_group_members (user_id);
+
+-- Each rule grants one action to either a role or a group
+CREATE TABLE IF NOT EXISTS category_permissions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    category_id INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('view', 'post', 'comment')),
+    role TEXT CHECK (role IN ('user', 'moderator', 'admin')),
+    group_id INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((role IS NULL) != (group_id IS NULL)),
+    FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES user_groups (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_category_permissions_rule
+    ON category_permissions (category_id, action, COALESCE(role, ''), COALESCE(group_id, 0));
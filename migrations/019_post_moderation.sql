@@ -0,0 +1,11 @@
+-- Moderator post states. A pinned post stays at the top of its category,
+-- and a globally pinned one at the top of the listing of all posts too; a
+-- locked post takes no new comments; an archived post is read-only and left
+-- out of default listings.
+
+ALTER TABLE posts ADD COLUMN pin_scope TEXT CHECK (pin_scope IN ('category', 'global'));
+ALTER TABLE posts ADD COLUMN pinned_at DATETIME;
+ALTER TABLE posts ADD COLUMN locked_at DATETIME;
+ALTER TABLE posts ADD COLUMN archived_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_posts_pin_scope ON posts (pin_scope) WHERE pin_scope IS NOT NULL;
@@ -0,0 +1,35 @@
+-- Reports of posts, comments, messages and users, which moderators work
+-- through as a queue. The reported user, the post or conversation the
+-- content belongs to and an excerpt of it are kept from the time of the
+-- report, so edits and deletions do not hide what was reported.
+
+CREATE TABLE IF NOT EXISTS reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment', 'message', 'user')),
+    target_id INTEGER NOT NULL,
+    reported_user_id INTEGER NOT NULL,
+    post_id INTEGER,
+    conversation_id INTEGER,
+    excerpt TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'inappropriate', 'other')),
+    note TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'actioned', 'dismissed')),
+    assignee_id INTEGER,
+    resolution_note TEXT NOT NULL DEFAULT '',
+    resolved_by INTEGER,
+    resolved_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (reported_user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (assignee_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_reports_assignee ON reports (assignee_id, status);
+
+-- A user can have only one open report of the same thing
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open
+    ON reports (reporter_id, target_type, target_id) WHERE status = 'open';
@@ -0,0 +1,22 @@
+-- Temporary and permanent bans. A ban is active until it expires or is
+-- lifted; rows are never deleted, so they double as the ban history.
+-- hide_content hides the user's posts and comments from everyone while
+-- the ban is active.
+
+CREATE TABLE IF NOT EXISTS user_bans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    banned_by INTEGER,
+    reason TEXT NOT NULL,
+    expires_at DATETIME,
+    hide_content BOOLEAN NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    lifted_at DATETIME,
+    lifted_by INTEGER,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (banned_by) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (lifted_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_bans_user ON user_bans (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_user_bans_active ON user_bans (user_id) WHERE lifted_at IS NULL;
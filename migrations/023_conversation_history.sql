@@ -0,0 +1,6 @@
+-- Group members only see messages sent since they joined. A participant
+-- sees messages with an id above history_from_message_id, which is set to the
+-- conversation's latest message whenever someone is added or re-admitted.
+-- Existing participants keep the history they could already see.
+
+ALTER TABLE conversation_participants ADD COLUMN history_from_message_id INTEGER NOT NULL DEFAULT 0;